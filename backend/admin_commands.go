@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/counters"
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/Semkufu95/confessions/Backend/redis"
+	"github.com/Semkufu95/confessions/Backend/reputation"
+	"github.com/Semkufu95/confessions/Backend/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// adminCommands are maintenance tasks run against the configured database.
+var adminCommands = map[string]func(ctx context.Context, args []string) error{
+	"promote-admin":   promoteAdmin,
+	"revoke-sessions": revokeSessions,
+	"verify-email":    verifyEmail,
+	"purge-stats":     purgeStats,
+	"reindex-counters": func(ctx context.Context, args []string) error {
+		return reindexCounters(ctx)
+	},
+	"reindex-reputation": func(ctx context.Context, args []string) error {
+		return reindexReputation(ctx)
+	},
+	"seed": seed,
+}
+
+var errUsage = errors.New("invalid arguments")
+
+// runAdminCommand connects to PostgreSQL and Redis the same way the server
+// does, without applying migrations, then runs the named command.
+func runAdminCommand(name string, args []string) int {
+	command := adminCommands[name]
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	config.App = cfg
+	config.InitDB(cfg.DatabaseURL, false)
+	redis.ConnectRedis(cfg.RedisAddr)
+	defer redis.Client.Close()
+
+	ctx := context.Background()
+	if err := config.CheckMigrations(ctx); err != nil {
+		log.Printf("⚠️  %v; run `migrate up` first if this command fails", err)
+	}
+
+	if err := command(ctx, args); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		if errors.Is(err, errUsage) {
+			fmt.Fprint(os.Stderr, usage)
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+func promoteAdmin(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: promote-admin <email>", errUsage)
+	}
+	user, err := findUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if err := config.DB.WithContext(ctx).Model(&user).Update("is_admin", true).Error; err != nil {
+		return err
+	}
+	fmt.Printf("%s (%s) is now an admin\n", user.Username, user.Email)
+	return nil
+}
+
+func revokeSessions(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: revoke-sessions <email|username|id>", errUsage)
+	}
+	user, err := findUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	result := config.DB.WithContext(ctx).Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", user.ID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	fmt.Printf("revoked %d session(s) for %s\n", result.RowsAffected, user.Username)
+	return nil
+}
+
+func verifyEmail(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: verify-email <email>", errUsage)
+	}
+	user, err := findUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if err := config.DB.WithContext(ctx).Model(&user).Updates(map[string]interface{}{
+		"email_verified":                true,
+		"email_verification_token_hash": "",
+		"email_verification_expires_at": nil,
+	}).Error; err != nil {
+		return err
+	}
+	fmt.Printf("marked %s as verified\n", user.Email)
+	return nil
+}
+
+func purgeStats(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("purge-stats", flag.ContinueOnError)
+	before := flags.String("before", "", "delete observations older than a date (2006-01-02 or RFC 3339) or a duration ago (e.g. 720h)")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	cutoff, err := parseCutoff(*before, time.Now())
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	result := config.DB.WithContext(ctx).Where("observed_at < ?", cutoff).Delete(&models.StatsObservation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	fmt.Printf("deleted %d stats observation(s) before %s\n", result.RowsAffected, cutoff.Format(time.RFC3339))
+	return nil
+}
+
+// parseCutoff accepts an absolute date or a duration measured back from now.
+func parseCutoff(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("--before is required")
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --before %q", value)
+}
+
+// reindexCounters runs the counter reconciliation once and reports how many
+// rows had drifted.
+func reindexCounters(ctx context.Context) error {
+	report, err := counters.Reconcile(ctx, config.DB)
+	if err != nil {
+		return err
+	}
+	if report.Skipped {
+		return errors.New("another replica is reconciling counters; try again shortly")
+	}
+	fmt.Printf("repaired %d confession(s) and %d comment(s)\n", report.Confessions, report.Comments)
+	if report.Total() > 0 {
+		flushCaches(ctx)
+	}
+	return nil
+}
+
+// reindexReputation recounts every user's reputation signals, e.g. after
+// the first deploy or when events were missed while Redis was down.
+func reindexReputation(ctx context.Context) error {
+	users, err := reputation.RefreshAll(ctx, config.DB)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("recounted reputation signals of %d user(s)\n", users)
+	return nil
+}
+
+type fixtures struct {
+	Users []struct {
+		Username string `json:"username"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
+		IsAdmin  bool   `json:"is_admin"`
+	} `json:"users"`
+	Confessions []struct {
+		Author   string   `json:"author"`
+		Content  string   `json:"content"`
+		Category string   `json:"category"`
+		Comments []string `json:"comments"`
+	} `json:"confessions"`
+}
+
+// seed loads users and confessions from a JSON fixtures file. Existing users
+// and confessions with identical content are skipped, so the command can run
+// more than once.
+func seed(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	path := flags.String("fixtures", "fixtures/dev.json", "path to the JSON fixtures file")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if config.App.Environment == "production" {
+		return errors.New("refusing to seed fixtures when GO_ENV=production")
+	}
+
+	raw, err := os.ReadFile(*path)
+	if err != nil {
+		return err
+	}
+	var data fixtures
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("parse %s: %w", *path, err)
+	}
+
+	err = config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		users := map[string]uuid.UUID{}
+		for _, fixture := range data.Users {
+			var user models.User
+			err := tx.Where("email = ?", fixture.Email).First(&user).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				hash, err := utils.HashPassword(fixture.Password)
+				if err != nil {
+					return err
+				}
+				user = models.User{
+					Username:      fixture.Username,
+					Email:         fixture.Email,
+					PasswordHash:  hash,
+					IsAdmin:       fixture.IsAdmin,
+					EmailVerified: true,
+				}
+				if err := tx.Create(&user).Error; err != nil {
+					return fmt.Errorf("create user %s: %w", fixture.Email, err)
+				}
+				fmt.Printf("created user %s\n", user.Username)
+			} else if err != nil {
+				return err
+			}
+			users[fixture.Username] = user.ID
+		}
+
+		created := 0
+		for _, fixture := range data.Confessions {
+			authorID, ok := users[fixture.Author]
+			if !ok {
+				return fmt.Errorf("confession author %q is not a fixture user", fixture.Author)
+			}
+			var existing int64
+			if err := tx.Model(&models.Confession{}).
+				Where("user_id = ? AND content = ?", authorID, fixture.Content).
+				Count(&existing).Error; err != nil {
+				return err
+			}
+			if existing > 0 {
+				continue
+			}
+			confession := models.Confession{
+				UserID:   authorID,
+				Content:  fixture.Content,
+				Category: fixture.Category,
+				Comments: len(fixture.Comments),
+			}
+			if err := tx.Create(&confession).Error; err != nil {
+				return err
+			}
+			for _, content := range fixture.Comments {
+				comment := models.Comment{ConfessionID: confession.ID, UserID: authorID, Content: content}
+				if err := tx.Create(&comment).Error; err != nil {
+					return err
+				}
+			}
+			created++
+		}
+		fmt.Printf("created %d confession(s)\n", created)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	flushCaches(ctx)
+	return nil
+}
+
+// findUser resolves an email, username or user id.
+func findUser(ctx context.Context, identifier string) (models.User, error) {
+	identifier = strings.TrimSpace(identifier)
+	query := config.DB.WithContext(ctx)
+	if id, err := uuid.Parse(identifier); err == nil {
+		query = query.Where("id = ?", id)
+	} else {
+		query = query.Where("LOWER(email) = LOWER(?) OR username = ?", identifier, identifier)
+	}
+
+	var user models.User
+	if err := query.First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return user, fmt.Errorf("no user matches %q", identifier)
+		}
+		return user, err
+	}
+	return user, nil
+}
+
+// flushCaches drops cached reads after writes that bypass the outbox.
+func flushCaches(ctx context.Context) {
+	removed, err := redis.FlushReadCaches(ctx)
+	if err != nil {
+		log.Printf("⚠️  could not flush read caches, entries expire within their TTL: %v", err)
+		return
+	}
+	fmt.Printf("flushed %d cached read(s)\n", removed)
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCutoff(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	cutoff, err := parseCutoff("48h", now)
+	if err != nil || !cutoff.Equal(now.Add(-48*time.Hour)) {
+		t.Fatalf("expected a relative cutoff, got %s (%v)", cutoff, err)
+	}
+
+	cutoff, err = parseCutoff("2026-01-01", now)
+	if err != nil || !cutoff.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected a date cutoff, got %s (%v)", cutoff, err)
+	}
+
+	for _, value := range []string{"", "yesterday", "-1h"} {
+		if _, err := parseCutoff(value, now); err == nil {
+			t.Fatalf("expected %q to be rejected", value)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+// Package cards renders confessions into branded PNG images for link
+// previews and for sharing as pictures. Drawing is pure Go and uses the Go
+// fonts embedded in the binary.
+package cards
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Format is the size of a card.
+type Format struct {
+	Name   string
+	Width  int
+	Height int
+}
+
+// Supported formats. OpenGraph is the size chat apps and social networks
+// expect for og:image; Square and Story fit Instagram posts and stories.
+var (
+	OpenGraph = Format{Name: "og", Width: 1200, Height: 630}
+	Square    = Format{Name: "square", Width: 1080, Height: 1080}
+	Story     = Format{Name: "story", Width: 1080, Height: 1920}
+)
+
+// FormatByName returns the shareable format called name.
+func FormatByName(name string) (Format, bool) {
+	for _, format := range []Format{Square, Story, OpenGraph} {
+		if format.Name == name {
+			return format, true
+		}
+	}
+	return Format{}, false
+}
+
+// Card is what gets drawn.
+type Card struct {
+	Content  string
+	Category string
+	// Stats are shown in a row under the content, at most maxStats of them.
+	Stats []Stat
+}
+
+// Stat is a labelled count such as 12 likes.
+type Stat struct {
+	Label string
+	Count int
+}
+
+// version changes whenever the drawing does, so cached cards are redrawn.
+const version = "1"
+
+const maxStats = 5
+
+// Hash identifies the image Render draws for card in format: equal hashes
+// mean identical images.
+func Hash(card Card, format Format) string {
+	sum := sha256.New()
+	for _, part := range []string{version, format.Name, strconv.Itoa(format.Width), strconv.Itoa(format.Height), card.Category, card.Content} {
+		sum.Write([]byte(part))
+		sum.Write([]byte{0})
+	}
+	for _, stat := range card.Stats {
+		sum.Write([]byte(stat.Label + "=" + strconv.Itoa(stat.Count)))
+		sum.Write([]byte{0})
+	}
+	return hex.EncodeToString(sum.Sum(nil)[:16])
+}
+
+// Brand colours, matching the frontend's blue-900 to blue-600.
+var (
+	backgroundTop    = color.RGBA{R: 0x1e, G: 0x3a, B: 0x8a, A: 0xff}
+	backgroundBottom = color.RGBA{R: 0x25, G: 0x63, B: 0xeb, A: 0xff}
+	accent           = color.RGBA{R: 0xbf, G: 0xdb, B: 0xfe, A: 0xff}
+)
+
+// brand is printed at the bottom of every card.
+const brand = "confessions.africa"
+
+var (
+	fontsOnce     sync.Once
+	regular, bold *opentype.Font
+	fontsErr      error
+)
+
+func loadFonts() error {
+	fontsOnce.Do(func() {
+		if regular, fontsErr = opentype.Parse(goregular.TTF); fontsErr != nil {
+			return
+		}
+		bold, fontsErr = opentype.Parse(gobold.TTF)
+	})
+	return fontsErr
+}
+
+// newFace returns a face of f at size pixels. Faces are not safe for
+// concurrent use, so every render makes its own.
+func newFace(f *opentype.Font, size float64) (font.Face, error) {
+	return opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+}
+
+// Render draws card in format and writes it as PNG.
+func Render(w io.Writer, card Card, format Format) error {
+	img, err := Draw(card, format)
+	if err != nil {
+		return err
+	}
+	return (&png.Encoder{CompressionLevel: png.BestSpeed}).Encode(w, img)
+}
+
+// Draw draws card in format.
+func Draw(card Card, format Format) (*image.RGBA, error) {
+	if err := loadFonts(); err != nil {
+		return nil, err
+	}
+	img := image.NewRGBA(image.Rect(0, 0, format.Width, format.Height))
+	fillGradient(img)
+
+	side := format.Width
+	if format.Height < side {
+		side = format.Height
+	}
+	margin := format.Width / 16
+	small := float64(side) / 28
+	label, err := newFace(bold, small)
+	if err != nil {
+		return nil, err
+	}
+	defer label.Close()
+
+	top := margin + int(small)
+	if category := strings.TrimSpace(card.Category); category != "" {
+		drawText(img, label, accent, margin, top, "#"+strings.ToUpper(category))
+	}
+	footer := format.Height - margin
+	drawText(img, label, accent, margin, footer, brand)
+
+	bottom := footer - int(2*small)
+	if len(card.Stats) > 0 {
+		height, err := drawStats(img, card.Stats, margin, bottom, format.Width-2*margin, small)
+		if err != nil {
+			return nil, err
+		}
+		bottom -= height + int(small)
+	}
+
+	// The content gets the space between the header and the stats.
+	box := image.Rect(margin, top+int(small), format.Width-margin, bottom)
+	body, lines, err := fit(card.Content, box, float64(side)/12, float64(side)/24)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	lineHeight := lineHeightOf(body)
+	y := box.Min.Y + (box.Dy()-lineHeight*len(lines))/2 + body.Metrics().Ascent.Ceil()
+	for _, line := range lines {
+		drawText(img, body, color.White, box.Min.X, y, line)
+		y += lineHeight
+	}
+	return img, nil
+}
+
+// fit picks the largest text size between max and min at which content fits
+// box, and truncates it with an ellipsis at min if it still does not.
+func fit(content string, box image.Rectangle, max, min float64) (font.Face, []string, error) {
+	for size := max; ; size *= 0.85 {
+		if size < min {
+			size = min
+		}
+		face, err := newFace(regular, size)
+		if err != nil {
+			return nil, nil, err
+		}
+		lines := wrap(face, content, fixed.I(box.Dx()))
+		fits := box.Dy() / lineHeightOf(face)
+		if len(lines) <= fits {
+			return face, lines, nil
+		}
+		if size == min {
+			return face, truncate(face, lines[:fits], fixed.I(box.Dx())), nil
+		}
+		face.Close()
+	}
+}
+
+// drawStats draws stats in equal columns whose baselines end at bottom and
+// returns the height used.
+func drawStats(img draw.Image, stats []Stat, x, bottom, width int, size float64) (int, error) {
+	number, err := newFace(bold, size*1.6)
+	if err != nil {
+		return 0, err
+	}
+	defer number.Close()
+	label, err := newFace(regular, size*0.9)
+	if err != nil {
+		return 0, err
+	}
+	defer label.Close()
+
+	if len(stats) > maxStats {
+		stats = stats[:maxStats]
+	}
+	column := width / maxStats
+	numberLine := bottom - lineHeightOf(label)
+	for i, stat := range stats {
+		left := x + i*column
+		drawText(img, number, color.White, left, numberLine, compact(stat.Count))
+		drawText(img, label, accent, left, bottom, stat.Label)
+	}
+	return lineHeightOf(label) + number.Metrics().Ascent.Ceil(), nil
+}
+
+// compact writes n the way counters are usually shown: 999, 1.2K, 3.4M.
+func compact(n int) string {
+	switch {
+	case n < 1000:
+		return strconv.Itoa(n)
+	case n < 1000000:
+		return trimTenths(float64(n)/1000) + "K"
+	default:
+		return trimTenths(float64(n)/1000000) + "M"
+	}
+}
+
+func trimTenths(f float64) string {
+	return strings.TrimSuffix(strconv.FormatFloat(float64(int(f*10))/10, 'f', 1, 64), ".0")
+}
+
+func lineHeightOf(face font.Face) int {
+	return face.Metrics().Height.Ceil() * 5 / 4
+}
+
+// wrap breaks text into lines no wider than width, keeping the author's
+// line breaks and splitting words that are too long on their own.
+func wrap(face font.Face, text string, width fixed.Int26_6) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(strings.TrimSpace(text), "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			for font.MeasureString(face, word) > width {
+				head, tail := splitWord(face, word, width)
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				lines = append(lines, head)
+				word = tail
+			}
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if font.MeasureString(face, candidate) <= width {
+				line = candidate
+				continue
+			}
+			lines = append(lines, line)
+			line = word
+		}
+		lines = append(lines, line)
+	}
+	// Collapse runs of blank lines left by empty paragraphs.
+	compact := lines[:0]
+	for i, line := range lines {
+		if line == "" && (i == 0 || lines[i-1] == "") {
+			continue
+		}
+		compact = append(compact, line)
+	}
+	for len(compact) > 0 && compact[len(compact)-1] == "" {
+		compact = compact[:len(compact)-1]
+	}
+	return compact
+}
+
+// splitWord returns the longest prefix of word that fits width (at least one
+// rune) and the rest.
+func splitWord(face font.Face, word string, width fixed.Int26_6) (string, string) {
+	runes := []rune(word)
+	n := 1
+	for n < len(runes) && font.MeasureString(face, string(runes[:n+1])) <= width {
+		n++
+	}
+	return string(runes[:n]), string(runes[n:])
+}
+
+// truncate ends the last line with an ellipsis that fits width.
+func truncate(face font.Face, lines []string, width fixed.Int26_6) []string {
+	if len(lines) == 0 {
+		return lines
+	}
+	last := []rune(strings.TrimSpace(lines[len(lines)-1]))
+	for len(last) > 0 && font.MeasureString(face, string(last)+"…") > width {
+		last = last[:len(last)-1]
+	}
+	lines[len(lines)-1] = strings.TrimRight(string(last), " ") + "…"
+	return lines
+}
+
+func drawText(img draw.Image, face font.Face, c color.Color, x, y int, text string) {
+	drawer := font.Drawer{Dst: img, Src: image.NewUniform(c), Face: face, Dot: fixed.P(x, y)}
+	drawer.DrawString(text)
+}
+
+// fillGradient paints the brand gradient from top to bottom.
+func fillGradient(img *image.RGBA) {
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		t := float64(y-bounds.Min.Y) / float64(bounds.Dy())
+		row := color.RGBA{
+			R: mix(backgroundTop.R, backgroundBottom.R, t),
+			G: mix(backgroundTop.G, backgroundBottom.G, t),
+			B: mix(backgroundTop.B, backgroundBottom.B, t),
+			A: 0xff,
+		}
+		draw.Draw(img, image.Rect(bounds.Min.X, y, bounds.Max.X, y+1), image.NewUniform(row), image.Point{}, draw.Src)
+	}
+}
+
+func mix(a, b uint8, t float64) uint8 {
+	return uint8(float64(a) + (float64(b)-float64(a))*t)
+}
@@ -0,0 +1,98 @@
+package cards
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestRenderProducesPNGOfFormatSize(t *testing.T) {
+	var out bytes.Buffer
+	if err := Render(&out, Card{Content: "I never told anyone.", Category: "Love"}, OpenGraph); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	img, err := png.Decode(&out)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got := img.Bounds().Size(); got.X != OpenGraph.Width || got.Y != OpenGraph.Height {
+		t.Fatalf("size = %v, want %dx%d", got, OpenGraph.Width, OpenGraph.Height)
+	}
+}
+
+func TestWrapKeepsLinesWithinWidth(t *testing.T) {
+	if err := loadFonts(); err != nil {
+		t.Fatal(err)
+	}
+	face, err := newFace(regular, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer face.Close()
+
+	width := fixed.I(400)
+	text := "the quick brown fox jumps over the lazy dog\n\n\n" + strings.Repeat("#", 60)
+	lines := wrap(face, text, width)
+	for _, line := range lines {
+		if font.MeasureString(face, line) > width {
+			t.Fatalf("line %q is wider than %v", line, width)
+		}
+	}
+	if got := strings.Join(lines, ""); strings.Count(got, "#") != 60 || !strings.Contains(got, "lazy") {
+		t.Fatalf("wrap lost text: %q", lines)
+	}
+	for i := 1; i < len(lines); i++ {
+		if lines[i] == "" && lines[i-1] == "" {
+			t.Fatalf("blank lines were not collapsed: %q", lines)
+		}
+	}
+}
+
+func TestDrawTruncatesLongContent(t *testing.T) {
+	if err := loadFonts(); err != nil {
+		t.Fatal(err)
+	}
+	face, lines, err := fit(strings.Repeat("word ", 2000), image.Rect(0, 0, 1000, 400), 60, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer face.Close()
+	if last := lines[len(lines)-1]; !strings.HasSuffix(last, "…") {
+		t.Fatalf("last line %q has no ellipsis", last)
+	}
+}
+
+func TestHashChangesWithWhatIsDrawn(t *testing.T) {
+	card := Card{Content: "text", Category: "love", Stats: []Stat{{Label: "like", Count: 1}}}
+	base := Hash(card, Square)
+	if Hash(card, Square) != base {
+		t.Fatal("hash is not stable")
+	}
+	changed := []struct {
+		card   Card
+		format Format
+	}{
+		{Card{Content: "text!", Category: "love", Stats: card.Stats}, Square},
+		{Card{Content: "text", Category: "work", Stats: card.Stats}, Square},
+		{Card{Content: "text", Category: "love", Stats: []Stat{{Label: "like", Count: 2}}}, Square},
+		{card, Story},
+	}
+	for _, c := range changed {
+		if Hash(c.card, c.format) == base {
+			t.Fatalf("hash of %+v in %s did not change", c.card, c.format.Name)
+		}
+	}
+}
+
+func TestCompact(t *testing.T) {
+	for n, want := range map[int]string{0: "0", 999: "999", 1000: "1K", 1250: "1.2K", 999999: "999.9K", 3400000: "3.4M"} {
+		if got := compact(n); got != want {
+			t.Errorf("compact(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
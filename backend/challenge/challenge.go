@@ -0,0 +1,71 @@
+// Package challenge makes public endpoints that are easy to script, like
+// registration, prove a client is willing to spend something before they are
+// served. Verifiers are pluggable; the built-in one is a self-hosted proof of
+// work.
+package challenge
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// Scopes name the endpoints a challenge can be solved for; a solution only
+// unlocks the scope it was issued for.
+const (
+	ScopeRegister = "register"
+	ScopeContact  = "contact"
+	ScopeShare    = "share"
+)
+
+// ValidScope reports whether scope is one of the known scopes.
+func ValidScope(scope string) bool {
+	switch scope {
+	case ScopeRegister, ScopeContact, ScopeShare:
+		return true
+	}
+	return false
+}
+
+// Challenge is what a client has to solve. Type tells clients how; the other
+// fields depend on it.
+type Challenge struct {
+	Type       string    `json:"type"`
+	Scope      string    `json:"scope"`
+	Token      string    `json:"token"`
+	Difficulty int       `json:"difficulty,omitempty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Verifier issues challenges and checks solutions. A solution is accepted
+// at most once.
+type Verifier interface {
+	Issue(ctx context.Context, scope string) (Challenge, error)
+	Verify(ctx context.Context, scope, token, solution string) error
+}
+
+var (
+	ErrInvalid = errors.New("invalid challenge solution")
+	ErrExpired = errors.New("challenge expired")
+	ErrUsed    = errors.New("challenge already used")
+)
+
+var current atomic.Pointer[Verifier]
+
+// Use installs the verifier endpoints are protected with.
+func Use(verifier Verifier) {
+	if verifier == nil {
+		current.Store(nil)
+		return
+	}
+	current.Store(&verifier)
+}
+
+// Current returns the installed verifier, or nil when challenges are off.
+func Current() Verifier {
+	if verifier := current.Load(); verifier != nil {
+		return *verifier
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package challenge
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TypeProofOfWork challenges are solved by finding a decimal nonce such that
+// SHA-256(token + ":" + nonce) starts with Difficulty zero bits.
+const TypeProofOfWork = "pow"
+
+// maxSolutionLength bounds the nonce so verification stays cheap.
+const maxSolutionLength = 20
+
+// ProofOfWork issues stateless, HMAC-signed proof-of-work challenges; only
+// used solutions are remembered, until their challenge expires.
+type ProofOfWork struct {
+	key        []byte
+	difficulty int
+	ttl        time.Duration
+	now        func() time.Time
+}
+
+// NewProofOfWork signs challenges with a key derived from secret.
+func NewProofOfWork(secret string, difficulty int, ttl time.Duration) *ProofOfWork {
+	key := sha256.Sum256([]byte("challenge:" + secret))
+	return &ProofOfWork{key: key[:], difficulty: difficulty, ttl: ttl, now: time.Now}
+}
+
+// Issue returns a challenge for scope. Its token carries the scope,
+// difficulty and expiry, so changing the difficulty does not invalidate
+// challenges already handed out.
+func (p *ProofOfWork) Issue(ctx context.Context, scope string) (Challenge, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return Challenge{}, err
+	}
+	expires := p.now().Add(p.ttl).Truncate(time.Second)
+	payload := strings.Join([]string{scope, hex.EncodeToString(nonce), strconv.Itoa(p.difficulty), strconv.FormatInt(expires.Unix(), 10)}, "|")
+	token := base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(p.sign(payload))
+	return Challenge{Type: TypeProofOfWork, Scope: scope, Token: token, Difficulty: p.difficulty, ExpiresAt: expires}, nil
+}
+
+// Verify checks that token was issued by p for scope and is unexpired, that
+// solution solves it, and that it was not used before.
+func (p *ProofOfWork) Verify(ctx context.Context, scope, token, solution string) error {
+	encodedPayload, encodedMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return ErrInvalid
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil || !hmac.Equal(mac, p.sign(string(payload))) {
+		return ErrInvalid
+	}
+
+	fields := strings.Split(string(payload), "|")
+	if len(fields) != 4 || fields[0] != scope {
+		return ErrInvalid
+	}
+	difficulty, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return ErrInvalid
+	}
+	expiresUnix, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return ErrInvalid
+	}
+	expires := time.Unix(expiresUnix, 0)
+	if !p.now().Before(expires) {
+		return ErrExpired
+	}
+	if !validNonce(solution) || LeadingZeroBits(token, solution) < difficulty {
+		return ErrInvalid
+	}
+
+	first, err := markUsed(ctx, token, expires.Sub(p.now()))
+	if err != nil {
+		return err
+	}
+	if !first {
+		return ErrUsed
+	}
+	return nil
+}
+
+func (p *ProofOfWork) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+func validNonce(solution string) bool {
+	if solution == "" || len(solution) > maxSolutionLength {
+		return false
+	}
+	for _, r := range solution {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// LeadingZeroBits counts the leading zero bits of SHA-256(token:nonce).
+func LeadingZeroBits(token, nonce string) int {
+	sum := sha256.Sum256([]byte(token + ":" + nonce))
+	zeros := 0
+	for _, b := range sum {
+		if b != 0 {
+			return zeros + bits.LeadingZeros8(b)
+		}
+		zeros += 8
+	}
+	return zeros
+}
+
+// Solve finds a nonce for a proof-of-work challenge. The web app does the
+// same in front/src/api/challenge.ts; this is for Go clients and tests.
+func Solve(challenge Challenge) string {
+	for nonce := 0; ; nonce++ {
+		candidate := strconv.Itoa(nonce)
+		if LeadingZeroBits(challenge.Token, candidate) >= challenge.Difficulty {
+			return candidate
+		}
+	}
+}
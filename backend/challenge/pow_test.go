@@ -0,0 +1,80 @@
+package challenge
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProofOfWork_IssueAndVerify(t *testing.T) {
+	ctx := context.Background()
+	pow := NewProofOfWork("secret", 8, time.Minute)
+	issued, err := pow.Issue(ctx, ScopeRegister)
+	if err != nil {
+		t.Fatalf("failed to issue: %v", err)
+	}
+	if issued.Type != TypeProofOfWork || issued.Difficulty != 8 || issued.Scope != ScopeRegister {
+		t.Fatalf("unexpected challenge %+v", issued)
+	}
+	solution := Solve(issued)
+
+	unsolved := "0"
+	for LeadingZeroBits(issued.Token, unsolved) >= 8 {
+		unsolved += "0"
+	}
+	for name, tc := range map[string]struct {
+		scope, token, solution string
+	}{
+		"wrong solution":   {ScopeRegister, issued.Token, unsolved},
+		"not a number":     {ScopeRegister, issued.Token, "abc"},
+		"other scope":      {ScopeContact, issued.Token, solution},
+		"tampered token":   {ScopeRegister, strings.Replace(issued.Token, ".", "x.", 1), solution},
+		"other key":        {ScopeRegister, mustIssue(t, NewProofOfWork("other", 8, time.Minute)).Token, solution},
+		"not a token":      {ScopeRegister, "token", solution},
+		"missing solution": {ScopeRegister, issued.Token, ""},
+	} {
+		if err := pow.Verify(ctx, tc.scope, tc.token, tc.solution); !errors.Is(err, ErrInvalid) {
+			t.Fatalf("%s: expected ErrInvalid, got %v", name, err)
+		}
+	}
+
+	if err := pow.Verify(ctx, ScopeRegister, issued.Token, solution); err != nil {
+		t.Fatalf("expected the solution to be accepted, got %v", err)
+	}
+	if err := pow.Verify(ctx, ScopeRegister, issued.Token, solution); !errors.Is(err, ErrUsed) {
+		t.Fatalf("expected a replay to be refused, got %v", err)
+	}
+}
+
+func TestProofOfWork_Expiry(t *testing.T) {
+	pow := NewProofOfWork("secret", 4, time.Minute)
+	start := time.Now()
+	pow.now = func() time.Time { return start }
+	issued := mustIssue(t, pow)
+
+	pow.now = func() time.Time { return start.Add(2 * time.Minute) }
+	if err := pow.Verify(context.Background(), ScopeRegister, issued.Token, Solve(issued)); !errors.Is(err, ErrExpired) {
+		t.Fatalf("expected ErrExpired, got %v", err)
+	}
+}
+
+func TestLeadingZeroBits(t *testing.T) {
+	// SHA-256("a:0") starts with 0x0e, SHA-256("a:63") with 0x0007.
+	if got := LeadingZeroBits("a", "0"); got != 4 {
+		t.Fatalf("expected 4 leading zero bits, got %d", got)
+	}
+	if got := LeadingZeroBits("a", "63"); got != 13 {
+		t.Fatalf("expected 13 leading zero bits, got %d", got)
+	}
+}
+
+func mustIssue(t *testing.T, pow *ProofOfWork) Challenge {
+	t.Helper()
+	issued, err := pow.Issue(context.Background(), ScopeRegister)
+	if err != nil {
+		t.Fatalf("failed to issue: %v", err)
+	}
+	return issued
+}
@@ -0,0 +1,56 @@
+package challenge
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/redis"
+)
+
+// markUsed records token as used for ttl and reports whether this was its
+// first use. Tokens are remembered in Redis so a solution cannot be replayed
+// against another replica; while Redis is unavailable they are remembered in
+// process.
+func markUsed(ctx context.Context, token string, ttl time.Duration) (bool, error) {
+	sum := sha256.Sum256([]byte(token))
+	key := "challenge:used:" + hex.EncodeToString(sum[:])
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+	if redis.Available() {
+		first, err := redis.Client.SetNX(ctx, key, 1, ttl).Result()
+		if err == nil {
+			return first, nil
+		}
+	}
+	return used.mark(key, ttl, time.Now()), nil
+}
+
+type usedTokens struct {
+	mu      sync.Mutex
+	expires map[string]time.Time
+	swept   time.Time
+}
+
+var used = &usedTokens{expires: map[string]time.Time{}}
+
+func (u *usedTokens) mark(key string, ttl time.Duration, now time.Time) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if now.Sub(u.swept) > time.Minute {
+		for k, expires := range u.expires {
+			if !now.Before(expires) {
+				delete(u.expires, k)
+			}
+		}
+		u.swept = now
+	}
+	if expires, ok := u.expires[key]; ok && now.Before(expires) {
+		return false
+	}
+	u.expires[key] = now.Add(ttl)
+	return true
+}
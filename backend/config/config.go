@@ -0,0 +1,541 @@
+package config
+
+import (
+	"log"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Config holds every setting the backend reads from the environment.
+type Config struct {
+	Environment      string
+	Port             string
+	DatabaseURL      string
+	RedisAddr        string
+	JWTSecret        string
+	CORSAllowOrigins string
+	BodyLimit        int
+	RateLimitMax     int
+	RateLimitWindow  time.Duration
+	// TrustedProxies are the reverse proxies (nginx) allowed to report the
+	// client address in X-Forwarded-For.
+	TrustedProxies     []netip.Prefix
+	OutboxPollInterval time.Duration
+	OutboxRetention    time.Duration
+	ReconcileInterval  time.Duration
+	ShutdownDrainDelay time.Duration
+	MigrateOnStart     bool
+
+	SessionInactivityTimeout      time.Duration
+	SessionMaxLifetime            time.Duration
+	SessionActivityUpdateInterval time.Duration
+
+	FrontendBaseURL          string
+	VerifyEmailBaseURL       string
+	RequireEmailVerification bool
+	ContactFormTo            string
+
+	// ReactionTypes is the reaction vocabulary; like and boo are always part
+	// of it.
+	ReactionTypes []string
+
+	// ContentFilterFile holds the content filter rules; it is re-read every
+	// ContentFilterReloadInterval when it changes.
+	ContentFilterFile           string
+	ContentFilterReloadInterval time.Duration
+
+	// ModerationMode decides which new posts wait for an admin: none (off),
+	// those from low-trust accounts, or all of them. An account is low-trust
+	// while its email is unverified, while it is younger than
+	// ModerationNewAccountAge, once ModerationReportThreshold of its posts
+	// were rejected by admins, or while its reputation score is below
+	// ModerationMinReputation.
+	ModerationMode            string
+	ModerationNewAccountAge   time.Duration
+	ModerationReportThreshold int
+	ModerationMinReputation   int
+
+	// Per-action budgets by reputation tier, counted per user (per client IP
+	// for anonymous callers, who get the new tier's budget): confessions per
+	// hour, comments per minute, connection requests and contact messages
+	// per day, and share link clicks per hour.
+	ConfessionRateLimit        TierLimits
+	CommentRateLimit           TierLimits
+	ConnectionRequestRateLimit TierLimits
+	ContactRateLimit           TierLimits
+	ShareClickRateLimit        TierLimits
+
+	// Registrations, contact messages and anonymous shares require a solved
+	// challenge once a client IP made more than ChallengeThreshold of them
+	// in an hour (0 requires one every time). Proof-of-work challenges need
+	// ChallengeDifficulty leading zero bits and expire after ChallengeTTL.
+	ChallengeThreshold  int
+	ChallengeDifficulty int
+	ChallengeTTL        time.Duration
+
+	// ShareDedupWindow is how long repeated shares of a confession by the
+	// same person, on any channel, count once.
+	ShareDedupWindow time.Duration
+
+	SMTP SMTPConfig
+}
+
+// TierLimits is a budget for each reputation tier.
+type TierLimits struct {
+	New     int
+	Member  int
+	Trusted int
+}
+
+// Moderation modes.
+const (
+	ModerationOff      = "off"
+	ModerationLowTrust = "low_trust"
+	ModerationAll      = "all"
+)
+
+// SMTPConfig holds outgoing mail settings. Email delivery is disabled unless
+// every field is set.
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// Configured reports whether every SMTP setting is present.
+func (s SMTPConfig) Configured() bool {
+	return s.Host != "" && s.Port != "" && s.Username != "" && s.Password != "" && s.From != ""
+}
+
+// App is the configuration the process runs with. It holds the defaults until
+// main replaces it with the result of Load.
+var App = Defaults()
+
+// Defaults returns the configuration used for every setting left unset.
+func Defaults() Config {
+	return Config{
+		Environment:                   "development",
+		Port:                          "5000",
+		RedisAddr:                     "redis:6379",
+		CORSAllowOrigins:              "http://localhost:5173",
+		BodyLimit:                     1024 * 1024,
+		RateLimitMax:                  100,
+		RateLimitWindow:               time.Minute,
+		OutboxPollInterval:            500 * time.Millisecond,
+		OutboxRetention:               24 * time.Hour,
+		ReconcileInterval:             10 * time.Minute,
+		ShutdownDrainDelay:            5 * time.Second,
+		MigrateOnStart:                true,
+		SessionInactivityTimeout:      30 * time.Minute,
+		SessionMaxLifetime:            72 * time.Hour,
+		SessionActivityUpdateInterval: time.Minute,
+		VerifyEmailBaseURL:            "http://localhost:5173/verify-email",
+		ReactionTypes:                 []string{"like", "boo", "heart", "hug", "laugh", "sad", "wow"},
+		ContentFilterFile:             "content_filter.json",
+		ContentFilterReloadInterval:   10 * time.Second,
+		ModerationMode:                ModerationOff,
+		ModerationNewAccountAge:       72 * time.Hour,
+		ModerationReportThreshold:     3,
+		ModerationMinReputation:       25,
+		ConfessionRateLimit:           TierLimits{New: 3, Member: 10, Trusted: 30},
+		CommentRateLimit:              TierLimits{New: 3, Member: 6, Trusted: 15},
+		ConnectionRequestRateLimit:    TierLimits{New: 5, Member: 20, Trusted: 50},
+		ContactRateLimit:              TierLimits{New: 2, Member: 5, Trusted: 10},
+		ShareClickRateLimit:           TierLimits{New: 60, Member: 120, Trusted: 240},
+		ChallengeThreshold:            5,
+		ChallengeDifficulty:           18,
+		ChallengeTTL:                  5 * time.Minute,
+		ShareDedupWindow:              24 * time.Hour,
+	}
+}
+
+// IsReactionType reports whether reactionType is in the configured vocabulary.
+func (c Config) IsReactionType(reactionType string) bool {
+	for _, known := range c.ReactionTypes {
+		if known == reactionType {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidationError lists every missing or invalid setting found by Load.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Load reads .env (when present) and the process environment. It reports all
+// problems at once instead of stopping at the first one.
+func Load() (Config, error) {
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️  No .env file found, using system environment")
+	}
+	return LoadFrom(os.LookupEnv)
+}
+
+// LoadFrom builds a Config from lookup, which has the signature of
+// os.LookupEnv.
+func LoadFrom(lookup func(string) (string, bool)) (Config, error) {
+	r := &envReader{lookup: lookup}
+	cfg := Defaults()
+
+	cfg.Environment = r.string("GO_ENV", cfg.Environment)
+	cfg.Port = r.port("PORT", cfg.Port)
+	cfg.DatabaseURL = r.databaseURL()
+	cfg.RedisAddr = r.redisAddr(cfg.RedisAddr)
+	cfg.JWTSecret = r.required("JWT_SECRET")
+	cfg.CORSAllowOrigins = r.string("CORS_ALLOW_ORIGINS", cfg.CORSAllowOrigins)
+	cfg.BodyLimit = r.positiveInt("API_BODY_LIMIT_BYTES", cfg.BodyLimit)
+	cfg.RateLimitMax = r.positiveInt("RATE_LIMIT_MAX", cfg.RateLimitMax)
+	cfg.RateLimitWindow = r.duration("RATE_LIMIT_WINDOW", cfg.RateLimitWindow, false)
+	cfg.TrustedProxies = r.prefixes("TRUSTED_PROXIES")
+	cfg.OutboxPollInterval = r.duration("OUTBOX_POLL_INTERVAL", cfg.OutboxPollInterval, false)
+	cfg.OutboxRetention = r.duration("OUTBOX_RETENTION", cfg.OutboxRetention, false)
+	cfg.ReconcileInterval = r.duration("COUNTER_RECONCILE_INTERVAL", cfg.ReconcileInterval, false)
+	cfg.ShutdownDrainDelay = r.duration("SHUTDOWN_DRAIN_DELAY", cfg.ShutdownDrainDelay, true)
+	cfg.MigrateOnStart = r.bool("MIGRATE_ON_START", cfg.MigrateOnStart)
+
+	cfg.SessionInactivityTimeout = r.duration("SESSION_INACTIVITY_TIMEOUT", cfg.SessionInactivityTimeout, false)
+	cfg.SessionMaxLifetime = r.duration("SESSION_MAX_LIFETIME", cfg.SessionMaxLifetime, false)
+	cfg.SessionActivityUpdateInterval = r.duration("SESSION_ACTIVITY_UPDATE_INTERVAL", cfg.SessionActivityUpdateInterval, false)
+
+	cfg.FrontendBaseURL = strings.TrimRight(r.absoluteURL("FRONTEND_BASE_URL", ""), "/")
+	cfg.VerifyEmailBaseURL = r.absoluteURL("APP_VERIFY_EMAIL_BASE_URL", cfg.VerifyEmailBaseURL)
+	cfg.RequireEmailVerification = r.bool("REQUIRE_EMAIL_VERIFICATION", false)
+	cfg.ContactFormTo = r.string("CONTACT_FORM_TO", "")
+	cfg.ReactionTypes = r.reactionTypes("REACTION_TYPES", cfg.ReactionTypes)
+	cfg.ContentFilterFile = r.string("CONTENT_FILTER_FILE", cfg.ContentFilterFile)
+	cfg.ContentFilterReloadInterval = r.duration("CONTENT_FILTER_RELOAD_INTERVAL", cfg.ContentFilterReloadInterval, false)
+	cfg.ModerationMode = r.oneOf("MODERATION_MODE", cfg.ModerationMode, ModerationOff, ModerationLowTrust, ModerationAll)
+	cfg.ModerationNewAccountAge = r.duration("MODERATION_NEW_ACCOUNT_AGE", cfg.ModerationNewAccountAge, true)
+	cfg.ModerationReportThreshold = r.positiveInt("MODERATION_REPORT_THRESHOLD", cfg.ModerationReportThreshold)
+	cfg.ModerationMinReputation = r.intBetween("MODERATION_MIN_REPUTATION", cfg.ModerationMinReputation, 0, 100)
+	cfg.ConfessionRateLimit = r.tierLimits("RATE_LIMIT_CONFESSIONS", cfg.ConfessionRateLimit)
+	cfg.CommentRateLimit = r.tierLimits("RATE_LIMIT_COMMENTS", cfg.CommentRateLimit)
+	cfg.ConnectionRequestRateLimit = r.tierLimits("RATE_LIMIT_CONNECTION_REQUESTS", cfg.ConnectionRequestRateLimit)
+	cfg.ContactRateLimit = r.tierLimits("RATE_LIMIT_CONTACT_MESSAGES", cfg.ContactRateLimit)
+	cfg.ShareClickRateLimit = r.tierLimits("RATE_LIMIT_SHARE_CLICKS", cfg.ShareClickRateLimit)
+	cfg.ChallengeThreshold = r.intBetween("CHALLENGE_THRESHOLD", cfg.ChallengeThreshold, 0, 1000)
+	cfg.ChallengeDifficulty = r.intBetween("CHALLENGE_DIFFICULTY", cfg.ChallengeDifficulty, 1, 32)
+	cfg.ChallengeTTL = r.duration("CHALLENGE_TTL", cfg.ChallengeTTL, false)
+	cfg.ShareDedupWindow = r.duration("SHARE_DEDUP_WINDOW", cfg.ShareDedupWindow, false)
+
+	cfg.SMTP = r.smtp()
+
+	if len(r.problems) > 0 {
+		return cfg, &ValidationError{Problems: r.problems}
+	}
+	return cfg, nil
+}
+
+type envReader struct {
+	lookup   func(string) (string, bool)
+	problems []string
+}
+
+func (r *envReader) get(key string) string {
+	value, _ := r.lookup(key)
+	return strings.TrimSpace(value)
+}
+
+func (r *envReader) invalid(key, value, reason string) {
+	r.problems = append(r.problems, key+"="+strconv.Quote(value)+": "+reason)
+}
+
+func (r *envReader) missing(message string) {
+	r.problems = append(r.problems, message)
+}
+
+func (r *envReader) string(key, fallback string) string {
+	if value := r.get(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func (r *envReader) required(key string) string {
+	value := r.get(key)
+	if value == "" {
+		r.missing(key + " is required")
+	}
+	return value
+}
+
+func (r *envReader) positiveInt(key string, fallback int) int {
+	value := r.get(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		r.invalid(key, value, "must be a positive integer")
+		return fallback
+	}
+	return parsed
+}
+
+func (r *envReader) intBetween(key string, fallback, min, max int) int {
+	value := r.get(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < min || parsed > max {
+		r.invalid(key, value, "must be an integer between "+strconv.Itoa(min)+" and "+strconv.Itoa(max))
+		return fallback
+	}
+	return parsed
+}
+
+// tierLimits parses "new,member,trusted" budgets such as "3,10,30".
+func (r *envReader) tierLimits(key string, fallback TierLimits) TierLimits {
+	value := r.get(key)
+	if value == "" {
+		return fallback
+	}
+	parts := strings.Split(value, ",")
+	budgets := make([]int, len(parts))
+	for i, part := range parts {
+		parsed, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || parsed <= 0 {
+			budgets = nil
+			break
+		}
+		budgets[i] = parsed
+	}
+	if len(budgets) != 3 {
+		r.invalid(key, value, "must be three positive integers for the new, member and trusted tiers, such as 3,10,30")
+		return fallback
+	}
+	return TierLimits{New: budgets[0], Member: budgets[1], Trusted: budgets[2]}
+}
+
+// prefixes parses a comma-separated list of IP addresses and CIDR ranges.
+func (r *envReader) prefixes(key string) []netip.Prefix {
+	value := r.get(key)
+	if value == "" {
+		return nil
+	}
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			r.invalid(key, value, strconv.Quote(entry)+" is not an IP address or CIDR range")
+			return nil
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes
+}
+
+func (r *envReader) port(key, fallback string) string {
+	value := r.get(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 || parsed > 65535 {
+		r.invalid(key, value, "must be a port number between 1 and 65535")
+		return fallback
+	}
+	return value
+}
+
+func (r *envReader) duration(key string, fallback time.Duration, allowZero bool) time.Duration {
+	value := r.get(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0 || (parsed == 0 && !allowZero) {
+		r.invalid(key, value, "must be a positive Go duration such as 30s or 5m")
+		return fallback
+	}
+	return parsed
+}
+
+func (r *envReader) bool(key string, fallback bool) bool {
+	value := r.get(key)
+	switch strings.ToLower(value) {
+	case "":
+		return fallback
+	case "1", "true", "yes", "on":
+		return true
+	case "0", "false", "no", "off":
+		return false
+	default:
+		r.invalid(key, value, "must be true or false")
+		return fallback
+	}
+}
+
+func (r *envReader) oneOf(key, fallback string, allowed ...string) string {
+	value := strings.ToLower(r.get(key))
+	if value == "" {
+		return fallback
+	}
+	for _, option := range allowed {
+		if value == option {
+			return value
+		}
+	}
+	r.invalid(key, value, "must be one of "+strings.Join(allowed, ", "))
+	return fallback
+}
+
+func (r *envReader) absoluteURL(key, fallback string) string {
+	value := r.get(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		r.invalid(key, value, "must be an absolute URL")
+		return fallback
+	}
+	return value
+}
+
+// databaseURL prefers DATABASE_URL and otherwise assembles a DSN from the
+// DB_* parts used by the Render blueprint.
+func (r *envReader) databaseURL() string {
+	if value := r.get("DATABASE_URL"); value != "" {
+		parsed, err := url.Parse(value)
+		if err != nil || (parsed.Scheme != "postgres" && parsed.Scheme != "postgresql") {
+			r.invalid("DATABASE_URL", "<redacted>", "must be a postgres:// URL")
+		}
+		return value
+	}
+
+	host := r.get("DB_HOST")
+	if host == "" {
+		r.missing("DATABASE_URL is required (or set DB_HOST, DB_USER and DB_NAME)")
+		return ""
+	}
+	port := r.port("DB_PORT", "5432")
+	user := r.get("DB_USER")
+	name := r.get("DB_NAME")
+	if user == "" {
+		r.missing("DB_USER is required when DB_HOST is set")
+	}
+	if name == "" {
+		r.missing("DB_NAME is required when DB_HOST is set")
+	}
+
+	dsn := url.URL{
+		Scheme: "postgres",
+		Host:   net.JoinHostPort(host, port),
+		Path:   "/" + name,
+	}
+	if password, ok := r.lookup("DB_PASSWORD"); ok && password != "" {
+		dsn.User = url.UserPassword(user, password)
+	} else {
+		dsn.User = url.User(user)
+	}
+	query := url.Values{}
+	query.Set("sslmode", r.string("DB_SSLMODE", "disable"))
+	dsn.RawQuery = query.Encode()
+	return dsn.String()
+}
+
+// redisAddr prefers REDIS_ADDR and otherwise joins REDIS_HOST and REDIS_PORT,
+// which is what docker-compose sets.
+func (r *envReader) redisAddr(fallback string) string {
+	if value := r.get("REDIS_ADDR"); value != "" {
+		if _, _, err := net.SplitHostPort(value); err != nil {
+			r.invalid("REDIS_ADDR", value, "must be host:port")
+			return fallback
+		}
+		return value
+	}
+
+	host := r.get("REDIS_HOST")
+	port := r.port("REDIS_PORT", "6379")
+	if host == "" {
+		return fallback
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// reactionTypeName matches what fits the varchar(10) reactions.type column.
+var reactionTypeName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,9}$`)
+
+// reactionTypes parses a comma-separated vocabulary. like and boo are added
+// when missing so the original API keeps working.
+func (r *envReader) reactionTypes(key string, fallback []string) []string {
+	value := r.get(key)
+	if value == "" {
+		return fallback
+	}
+
+	types := []string{"like", "boo"}
+	seen := map[string]bool{"like": true, "boo": true}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		if !reactionTypeName.MatchString(name) {
+			r.invalid(key, value, strconv.Quote(name)+" must be 1-10 lowercase letters, digits or underscores")
+			return fallback
+		}
+		seen[name] = true
+		types = append(types, name)
+	}
+	return types
+}
+
+// smtp leaves delivery disabled when no SMTP_* variable is set, but treats a
+// partial configuration as a mistake.
+func (r *envReader) smtp() SMTPConfig {
+	smtp := SMTPConfig{
+		Host:     r.get("SMTP_HOST"),
+		Port:     r.get("SMTP_PORT"),
+		Username: r.get("SMTP_USERNAME"),
+		Password: r.get("SMTP_PASSWORD"),
+		From:     r.get("SMTP_FROM"),
+	}
+	if smtp == (SMTPConfig{}) || smtp.Configured() {
+		if smtp.Port != "" {
+			smtp.Port = r.port("SMTP_PORT", smtp.Port)
+		}
+		return smtp
+	}
+
+	fields := map[string]string{
+		"SMTP_HOST":     smtp.Host,
+		"SMTP_PORT":     smtp.Port,
+		"SMTP_USERNAME": smtp.Username,
+		"SMTP_PASSWORD": smtp.Password,
+		"SMTP_FROM":     smtp.From,
+	}
+	for _, key := range []string{"SMTP_HOST", "SMTP_PORT", "SMTP_USERNAME", "SMTP_PASSWORD", "SMTP_FROM"} {
+		if fields[key] == "" {
+			r.missing(key + " is required when any SMTP_* variable is set")
+		}
+	}
+	return smtp
+}
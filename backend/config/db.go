@@ -1,55 +1,73 @@
 package config
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"os"
+	"sync/atomic"
 
-	"github.com/Semkufu95/confessions/Backend/models"
-	"github.com/joho/godotenv"
+	"github.com/Semkufu95/confessions/Backend/migrations"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
-func InitDB() {
-	// Only load .env if running locally
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using system environment variables")
-	}
-
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		log.Fatal("DATABASE_URL is not set")
+var migrated atomic.Bool
 
-	}
+// OpenDB connects to PostgreSQL at dsn without touching the schema.
+func OpenDB(dsn string) (*gorm.DB, error) {
+	return gorm.Open(postgres.Open(dsn), &gorm.Config{})
+}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// InitDB connects to PostgreSQL at dsn and, when migrate is true, applies
+// pending schema migrations.
+func InitDB(dsn string, migrate bool) {
+	db, err := OpenDB(dsn)
 	if err != nil {
 		log.Fatal("Failed to connect to the database: ", err)
 	}
+	DB = db
 
-	//Create uuid-extension before migration
-	UuidExecution(db)
-
-	// Auto-migrate the models
-	err = db.AutoMigrate(
-		&models.User{},
-		&models.Confession{},
-		&models.Comment{},
-		&models.Reply{},
-		&models.Reaction{},
-		&models.Connection{},
-		&models.ConnectionRequest{},
-		&models.Session{},
-		&models.UserSettings{},
-		&models.StatsObservation{},
-	)
-	if err != nil {
-		log.Fatal("Migration failed: ", err)
+	if migrate {
+		sqlDB, err := db.DB()
+		if err != nil {
+			log.Fatal("Failed to access the database handle: ", err)
+		}
+		applied, err := migrations.Up(context.Background(), sqlDB)
+		if err != nil {
+			log.Fatal("Migration failed: ", err)
+		}
+		for _, migration := range applied {
+			log.Printf("applied migration %d_%s", migration.Version, migration.Name)
+		}
+		migrated.Store(true)
 	}
 
-	DB = db
-	fmt.Println("Connected to the database and migrated models")
+	fmt.Println("Connected to the database")
+}
+
+// CheckMigrations reports an error while embedded migrations are still
+// pending. Once the schema is current the result is remembered.
+func CheckMigrations(ctx context.Context) error {
+	if migrated.Load() {
+		return nil
+	}
+	if DB == nil {
+		return errors.New("database is not initialized")
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	pending, err := migrations.Pending(ctx, sqlDB)
+	if err != nil {
+		return err
+	}
+	if pending > 0 {
+		return fmt.Errorf("%d migration(s) pending", pending)
+	}
+	migrated.Store(true)
+	return nil
 }
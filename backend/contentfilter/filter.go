@@ -0,0 +1,259 @@
+// Package contentfilter screens user-written text against a rules file.
+// Each rule finds spans of text and decides what happens to them: the post
+// is rejected, held for review, or stored with the spans masked.
+package contentfilter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Action is what a rule does with matching text. Later actions in the list
+// below are more severe and win when several rules match.
+type Action string
+
+const (
+	Allow  Action = "allow"
+	Mask   Action = "mask"
+	Hold   Action = "hold"
+	Reject Action = "reject"
+)
+
+func (a Action) severity() int {
+	switch a {
+	case Mask:
+		return 1
+	case Hold:
+		return 2
+	case Reject:
+		return 3
+	}
+	return 0
+}
+
+// RuleConfig is one entry of the rules file. Type is words, regex, phone,
+// email or url; Words is used by words rules and Pattern by regex rules.
+// A word ending in * matches every word starting with it.
+type RuleConfig struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Action  Action   `json:"action"`
+	Words   []string `json:"words,omitempty"`
+	Pattern string   `json:"pattern,omitempty"`
+	Message string   `json:"message,omitempty"`
+}
+
+// Rules is the layout of the rules file.
+type Rules struct {
+	Rules []RuleConfig `json:"rules"`
+}
+
+// Match records a rule that fired.
+type Match struct {
+	Rule    string `json:"rule"`
+	Action  Action `json:"action"`
+	Message string `json:"message,omitempty"`
+}
+
+// Result is the outcome of Check. Text is the input with every span of a
+// mask rule replaced by asterisks.
+type Result struct {
+	Action  Action
+	Text    string
+	Matches []Match
+}
+
+// Reason returns the message of the first rule that decided Action.
+func (r Result) Reason() string {
+	for _, match := range r.Matches {
+		if match.Action == r.Action {
+			return match.Message
+		}
+	}
+	return ""
+}
+
+type rule struct {
+	name    string
+	action  Action
+	message string
+	find    func(text string, words []token) []span
+}
+
+type span struct{ start, end int }
+
+// Filter is an immutable set of compiled rules. The zero value and nil
+// allow everything.
+type Filter struct {
+	rules []rule
+}
+
+// Load reads and compiles a rules file.
+func Load(path string) (*Filter, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse compiles rules from their JSON form and reports every invalid rule
+// at once.
+func Parse(data []byte) (*Filter, error) {
+	var config Rules
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid rules file: %w", err)
+	}
+
+	filter := &Filter{}
+	var problems []string
+	seen := map[string]bool{}
+	for i, rc := range config.Rules {
+		label := fmt.Sprintf("rule %d", i+1)
+		if rc.Name != "" {
+			label += " (" + rc.Name + ")"
+		}
+		if rc.Name == "" {
+			problems = append(problems, label+": name is required")
+		} else if seen[rc.Name] {
+			problems = append(problems, label+": duplicate name")
+		}
+		seen[rc.Name] = true
+
+		if rc.Action.severity() == 0 {
+			problems = append(problems, label+": action must be reject, hold or mask")
+		}
+		find, err := compileFinder(rc)
+		if err != nil {
+			problems = append(problems, label+": "+err.Error())
+			continue
+		}
+		filter.rules = append(filter.rules, rule{name: rc.Name, action: rc.Action, message: rc.Message, find: find})
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid rules file:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return filter, nil
+}
+
+func compileFinder(rc RuleConfig) (func(string, []token) []span, error) {
+	switch rc.Type {
+	case "words":
+		if len(rc.Words) == 0 {
+			return nil, fmt.Errorf("words rules need at least one word")
+		}
+		return newWordList(rc.Words).find, nil
+	case "regex":
+		if rc.Pattern == "" {
+			return nil, fmt.Errorf("regex rules need a pattern")
+		}
+		pattern, err := regexp.Compile(rc.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %v", err)
+		}
+		return regexpFinder(pattern, nil), nil
+	case "phone":
+		return regexpFinder(phonePattern, isPhoneNumber), nil
+	case "email":
+		return regexpFinder(emailPattern, nil), nil
+	case "url":
+		return regexpFinder(urlPattern, isNotEmailDomain), nil
+	}
+	return nil, fmt.Errorf("type must be words, regex, phone, email or url")
+}
+
+// Check runs every rule against text.
+func (f *Filter) Check(text string) Result {
+	result := Result{Action: Allow, Text: text}
+	if f == nil || len(f.rules) == 0 {
+		return result
+	}
+
+	words := tokenize(text)
+	var masked []span
+	for _, r := range f.rules {
+		spans := r.find(text, words)
+		if len(spans) == 0 {
+			continue
+		}
+		result.Matches = append(result.Matches, Match{Rule: r.name, Action: r.action, Message: r.message})
+		if r.action.severity() > result.Action.severity() {
+			result.Action = r.action
+		}
+		if r.action == Mask {
+			masked = append(masked, spans...)
+		}
+	}
+	result.Text = mask(text, masked)
+	return result
+}
+
+// mask replaces every non-space rune inside spans with an asterisk.
+func mask(text string, spans []span) string {
+	if len(spans) == 0 {
+		return text
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var b strings.Builder
+	b.Grow(len(text))
+	next := 0
+	for i, r := range text {
+		for next < len(spans) && spans[next].end <= i {
+			next++
+		}
+		if next < len(spans) && spans[next].start <= i && !unicode.IsSpace(r) {
+			b.WriteByte('*')
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+var (
+	emailPattern = regexp.MustCompile(`(?i)[a-z0-9._%+\-]+@[a-z0-9\-]+(?:\.[a-z0-9\-]+)*\.[a-z]{2,}`)
+	phonePattern = regexp.MustCompile(`\+?\(?\d[\d\s().\-]{5,}\d`)
+	urlPattern   = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"']+|\b[a-z0-9][a-z0-9\-]*(?:\.[a-z0-9\-]+)*\.(?:com|net|org|io|co|me|ly|app|dev|info|biz|xyz|link|tz|ke|ug|ng|za|uk)\b(?:/[^\s<>"']*)?`)
+)
+
+func regexpFinder(pattern *regexp.Regexp, keep func(text string, s span) bool) func(string, []token) []span {
+	return func(text string, _ []token) []span {
+		var spans []span
+		for _, loc := range pattern.FindAllStringIndex(text, -1) {
+			s := span{loc[0], loc[1]}
+			if keep == nil || keep(text, s) {
+				spans = append(spans, s)
+			}
+		}
+		return spans
+	}
+}
+
+// isPhoneNumber keeps runs with as many digits as a phone number; shorter
+// ones are usually dates, prices or years.
+func isPhoneNumber(text string, s span) bool {
+	digits := 0
+	for _, r := range text[s.start:s.end] {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	return digits >= 9 && digits <= 15
+}
+
+// isNotEmailDomain skips the domain part of an email address so the url
+// rule does not fire on it.
+func isNotEmailDomain(text string, s span) bool {
+	if s.start == 0 {
+		return true
+	}
+	previous, _ := utf8.DecodeLastRuneInString(text[:s.start])
+	return previous != '@'
+}
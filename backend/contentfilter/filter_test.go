@@ -0,0 +1,108 @@
+package contentfilter
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type corpusCase struct {
+	Name   string `json:"name"`
+	Text   string `json:"text"`
+	Action Action `json:"action"`
+	Masked string `json:"masked"`
+	Reason string `json:"reason"`
+}
+
+func TestCheck_Corpus(t *testing.T) {
+	filter, err := Load("testdata/rules.json")
+	if err != nil {
+		t.Fatalf("failed to load rules: %v", err)
+	}
+	data, err := os.ReadFile("testdata/corpus.json")
+	if err != nil {
+		t.Fatalf("failed to read corpus: %v", err)
+	}
+	var corpus []corpusCase
+	if err := json.Unmarshal(data, &corpus); err != nil {
+		t.Fatalf("invalid corpus: %v", err)
+	}
+
+	for _, tc := range corpus {
+		t.Run(tc.Name, func(t *testing.T) {
+			result := filter.Check(tc.Text)
+			if result.Action != tc.Action {
+				t.Fatalf("expected %s, got %s (matches %+v)", tc.Action, result.Action, result.Matches)
+			}
+			if tc.Masked != "" && result.Text != tc.Masked {
+				t.Fatalf("expected %q, got %q", tc.Masked, result.Text)
+			}
+			if tc.Action == Allow && result.Text != tc.Text {
+				t.Fatalf("expected text to be unchanged, got %q", result.Text)
+			}
+			if tc.Reason != "" && result.Reason() != tc.Reason {
+				t.Fatalf("expected reason %q, got %q", tc.Reason, result.Reason())
+			}
+		})
+	}
+}
+
+func TestParse_ReportsEveryInvalidRule(t *testing.T) {
+	_, err := Parse([]byte(`{"rules": [
+		{"name": "a", "type": "words", "action": "mask"},
+		{"name": "a", "type": "regex", "pattern": "(", "action": "reject"},
+		{"type": "phone", "action": "delete"},
+		{"name": "d", "type": "shout", "action": "hold"}
+	]}`))
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	for _, want := range []string{"rule 1 (a)", "rule 2 (a): duplicate name", "invalid pattern", "rule 3: name is required", "action must be", "rule 4 (d): type must be"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in:\n%v", want, err)
+		}
+	}
+}
+
+func TestReload_SwapsRulesAndKeepsThemOnError(t *testing.T) {
+	defer Set(nil)
+	path := filepath.Join(t.TempDir(), "rules.json")
+	write := func(body string, modTime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatalf("failed to write rules: %v", err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("failed to touch rules: %v", err)
+		}
+	}
+	now := time.Now()
+
+	state, _ := reload(path, fileState{})
+	if Check("darn").Action != Allow {
+		t.Fatalf("expected a missing file to allow everything")
+	}
+
+	write(`{"rules": [{"name": "mild", "type": "words", "words": ["darn"], "action": "mask"}]}`, now)
+	state, changed := reload(path, state)
+	if !changed || Check("darn").Action != Mask {
+		t.Fatalf("expected the new rules to be applied")
+	}
+	if _, changed := reload(path, state); changed {
+		t.Fatalf("expected an unchanged file not to be reloaded")
+	}
+
+	write(`{"rules": [`, now.Add(time.Second))
+	state, changed = reload(path, state)
+	if changed || Check("darn").Action != Mask {
+		t.Fatalf("expected a broken file to keep the previous rules")
+	}
+
+	write(`{"rules": [{"name": "mild", "type": "words", "words": ["darn"], "action": "reject"}]}`, now.Add(2*time.Second))
+	if _, changed = reload(path, state); !changed || Check("darn").Action != Reject {
+		t.Fatalf("expected the fixed file to be applied")
+	}
+}
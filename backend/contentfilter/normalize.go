@@ -0,0 +1,181 @@
+package contentfilter
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// leet maps look-alike digits and symbols to the letters they stand for.
+var leet = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '8': 'b',
+	'@': 'a', '$': 's', '!': 'i', '|': 'l', '+': 't',
+}
+
+// token is a normalized word and the byte range it came from.
+type token struct {
+	word       string
+	start, end int
+}
+
+func normalizeRune(r rune) (rune, bool) {
+	if mapped, ok := leet[r]; ok {
+		return mapped, true
+	}
+	if unicode.IsLetter(r) || unicode.IsDigit(r) {
+		return unicode.ToLower(r), true
+	}
+	return 0, false
+}
+
+// normalize lowercases word and undoes leetspeak.
+func normalize(word string) string {
+	var b strings.Builder
+	for _, r := range word {
+		if n, ok := normalizeRune(r); ok {
+			b.WriteRune(n)
+		}
+	}
+	return b.String()
+}
+
+// squeeze collapses repeated letters, so "fuuuck" and "fuck" compare equal.
+func squeeze(word string) string {
+	var b strings.Builder
+	var last rune = -1
+	for _, r := range word {
+		if r != last {
+			b.WriteRune(r)
+		}
+		last = r
+	}
+	return b.String()
+}
+
+// tokenize splits text into normalized words. Symbols only stand in for
+// letters inside a word, so "sh!t" is a word but the "!" in "hey!" is not.
+// Letters spelled out one at a time with a single separator between them
+// ("f.u.c.k", "f u c k") also produce a token for the joined word.
+func tokenize(text string) []token {
+	type char struct {
+		r          rune
+		start, end int
+		symbol     bool
+	}
+	var words []token
+	var run []char
+	flush := func() {
+		for len(run) > 0 && run[len(run)-1].symbol {
+			run = run[:len(run)-1]
+		}
+		for len(run) > 0 && run[0].symbol {
+			run = run[1:]
+		}
+		if len(run) > 0 {
+			var b strings.Builder
+			for _, c := range run {
+				b.WriteRune(c.r)
+			}
+			words = append(words, token{word: b.String(), start: run[0].start, end: run[len(run)-1].end})
+		}
+		run = run[:0]
+	}
+	for i, r := range text {
+		n, ok := normalizeRune(r)
+		if !ok {
+			flush()
+			continue
+		}
+		symbol := !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		run = append(run, char{r: n, start: i, end: i + utf8.RuneLen(r), symbol: symbol})
+	}
+	flush()
+
+	return append(words, spelledOut(text, words)...)
+}
+
+// spelledOut joins runs of three or more single-letter tokens separated by
+// exactly one non-newline rune.
+func spelledOut(text string, words []token) []token {
+	var joined []token
+	for i := 0; i < len(words); {
+		j := i
+		for j < len(words) && utf8.RuneCountInString(words[j].word) == 1 &&
+			(j == i || singleSeparator(text[words[j-1].end:words[j].start])) {
+			j++
+		}
+		if j-i >= 3 {
+			var b strings.Builder
+			for _, w := range words[i:j] {
+				b.WriteString(w.word)
+			}
+			joined = append(joined, token{word: b.String(), start: words[i].start, end: words[j-1].end})
+		}
+		if j == i {
+			j++
+		}
+		i = j
+	}
+	return joined
+}
+
+func singleSeparator(gap string) bool {
+	return utf8.RuneCountInString(gap) == 1 && gap != "\n"
+}
+
+// wordList matches tokens against banned words, exactly or by prefix.
+type wordList struct {
+	exact    map[string]bool
+	squeezed map[string]int
+	prefixes []string
+}
+
+func newWordList(words []string) *wordList {
+	list := &wordList{exact: map[string]bool{}, squeezed: map[string]int{}}
+	for _, word := range words {
+		if strings.HasSuffix(word, "*") {
+			if prefix := normalize(strings.TrimSuffix(word, "*")); prefix != "" {
+				list.prefixes = append(list.prefixes, prefix)
+			}
+			continue
+		}
+		word = normalize(word)
+		if word == "" {
+			continue
+		}
+		list.exact[word] = true
+		// A squeezed match must be at least as long as the banned word, so
+		// "as" does not match "ass" while "asssss" does.
+		key := squeeze(word)
+		if length, ok := list.squeezed[key]; !ok || len(word) < length {
+			list.squeezed[key] = len(word)
+		}
+	}
+	return list
+}
+
+func (l *wordList) matches(word string) bool {
+	if l.exact[word] {
+		return true
+	}
+	squeezed := squeeze(word)
+	if length, ok := l.squeezed[squeezed]; ok && len(word) >= length {
+		return true
+	}
+	for _, prefix := range l.prefixes {
+		if strings.HasPrefix(word, prefix) || strings.HasPrefix(squeezed, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *wordList) find(_ string, words []token) []span {
+	var spans []span
+	for _, w := range words {
+		if l.matches(w.word) {
+			spans = append(spans, span{w.start, w.end})
+		}
+	}
+	return spans
+}
@@ -0,0 +1,102 @@
+package contentfilter
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"io/fs"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	current atomic.Pointer[Filter]
+	metrics = expvar.NewMap("content_filter")
+)
+
+// Current returns the active filter; it is nil until rules are loaded.
+func Current() *Filter {
+	return current.Load()
+}
+
+// Set replaces the active filter.
+func Set(filter *Filter) {
+	current.Store(filter)
+}
+
+// Check runs the active filter against text.
+func Check(text string) Result {
+	result := Current().Check(text)
+	metrics.Add("checks:"+string(result.Action), 1)
+	return result
+}
+
+// Watch loads the rules at path and reloads them whenever the file's size
+// or modification time changes. A file that fails to parse is logged and the
+// previous rules stay active; a missing file turns filtering off.
+func Watch(ctx context.Context, wg *sync.WaitGroup, path string, interval time.Duration) {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	last, _ := reload(path, fileState{})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				last, _ = reload(path, last)
+			}
+		}
+	}()
+}
+
+type fileState struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+// reload swaps in the rules at path if the file changed since previous.
+// It returns the state that was seen and whether a new filter was applied.
+func reload(path string, previous fileState) (fileState, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("content filter: %v", err)
+			return previous, false
+		}
+		if previous.exists || Current() == nil {
+			log.Printf("content filter: %s not found, filtering is off", path)
+			Set(&Filter{})
+		}
+		return fileState{}, false
+	}
+
+	state := fileState{exists: true, size: info.Size(), modTime: info.ModTime()}
+	if state == previous {
+		return previous, false
+	}
+
+	filter, err := Load(path)
+	if err != nil {
+		metrics.Add("reload_errors", 1)
+		log.Printf("content filter: keeping previous rules: %v", err)
+		return state, false
+	}
+	Set(filter)
+	metrics.Add("reloads", 1)
+	log.Printf("content filter: loaded %d rule(s) from %s", len(filter.rules), path)
+	return state, true
+}
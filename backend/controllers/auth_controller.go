@@ -7,11 +7,11 @@ import (
 	"encoding/hex"
 	"errors"
 	"net/url"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/middleware"
 	"github.com/Semkufu95/confessions/Backend/models"
 	"github.com/Semkufu95/confessions/Backend/utils"
 	"github.com/gofiber/fiber/v2"
@@ -258,6 +258,21 @@ func Logout(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"message": "Logged out"})
 }
 
+// CreateWebsocketTicket issues a single-use ticket for signing the caller's
+// next websocket connection in, so the token never appears in a URL.
+func CreateWebsocketTicket(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(string)
+	sessionID, _ := c.Locals("session_id").(string)
+	ticket, err := middleware.IssueWebsocketTicket(c.UserContext(), userID, sessionID)
+	if err != nil {
+		if errors.Is(err, middleware.ErrTicketsUnavailable) {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Realtime sign-in is unavailable"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to issue websocket ticket"})
+	}
+	return c.JSON(fiber.Map{"ticket": ticket, "expires_in": int(middleware.WebsocketTicketTTL.Seconds())})
+}
+
 func createSessionAndToken(userID uuid.UUID) (string, string, error) {
 	now := time.Now()
 	session := models.Session{
@@ -326,12 +341,7 @@ func hashEmailVerificationToken(token string) string {
 }
 
 func buildEmailVerificationURL(token string) (string, error) {
-	baseURL := strings.TrimSpace(os.Getenv("APP_VERIFY_EMAIL_BASE_URL"))
-	if baseURL == "" {
-		baseURL = "http://localhost:5173/verify-email"
-	}
-
-	parsedURL, err := url.Parse(baseURL)
+	parsedURL, err := url.Parse(config.App.VerifyEmailBaseURL)
 	if err != nil {
 		return "", err
 	}
@@ -343,10 +353,5 @@ func buildEmailVerificationURL(token string) (string, error) {
 }
 
 func requireEmailVerification() bool {
-	switch strings.ToLower(strings.TrimSpace(os.Getenv("REQUIRE_EMAIL_VERIFICATION"))) {
-	case "1", "true", "yes", "on":
-		return true
-	default:
-		return false
-	}
+	return config.App.RequireEmailVerification
 }
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/cards"
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/Semkufu95/confessions/Backend/redis"
+	"github.com/gofiber/fiber/v2"
+)
+
+// cardCacheTTL is how long rendered cards of a confession stay in Redis
+// after the last one was drawn. Each format keeps only its latest card, which
+// is redrawn whenever its content hash changes.
+const cardCacheTTL = 24 * time.Hour
+
+// confessionCard is what a confession's image card shows: its text,
+// category, most used reactions and comment count.
+func confessionCard(confession models.Confession) cards.Card {
+	card := cards.Card{Content: confession.Content, Category: confession.Category}
+
+	order := map[string]int{}
+	for i, reactionType := range config.App.ReactionTypes {
+		order[reactionType] = i
+	}
+	for reactionType, count := range confession.Reactions {
+		if _, known := order[reactionType]; known && count > 0 {
+			card.Stats = append(card.Stats, cards.Stat{Label: reactionType, Count: count})
+		}
+	}
+	sort.Slice(card.Stats, func(i, j int) bool {
+		if card.Stats[i].Count != card.Stats[j].Count {
+			return card.Stats[i].Count > card.Stats[j].Count
+		}
+		return order[card.Stats[i].Label] < order[card.Stats[j].Label]
+	})
+	if len(card.Stats) > 4 {
+		card.Stats = card.Stats[:4]
+	}
+	if confession.Comments > 0 {
+		card.Stats = append(card.Stats, cards.Stat{Label: "comments", Count: confession.Comments})
+	}
+	return card
+}
+
+// sendCard answers with confession's card in format, rendered at most once
+// per content hash across replicas. The hash doubles as the ETag and as the
+// cached card's version.
+func sendCard(c *fiber.Ctx, confession models.Confession, format cards.Format) error {
+	card := confessionCard(confession)
+	hash := cards.Hash(card, format)
+	etag := `"` + hash + `"`
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	if etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	result, err := redis.CachedField(c.UserContext(), "card", redis.CardCacheKey(confession.ID.String()), format.Name, hash, cardCacheTTL, func(context.Context) ([]byte, error) {
+		var image bytes.Buffer
+		if err := cards.Render(&image, card, format); err != nil {
+			return nil, err
+		}
+		return image.Bytes(), nil
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to render card"})
+	}
+	if result.Hit {
+		c.Set("X-Cache", "HIT")
+	} else {
+		c.Set("X-Cache", "MISS")
+	}
+	c.Set(fiber.HeaderContentType, "image/png")
+	return c.Send(result.Body)
+}
+
+// GetConfessionCard renders a published confession as a PNG for sharing as
+// a picture, in the format given by ?format=square (default) or story.
+func GetConfessionCard(c *fiber.Ctx) error {
+	format, ok := cards.FormatByName(c.Query("format", cards.Square.Name))
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "format must be one of square, story, og"})
+	}
+	var confession models.Confession
+	if err := config.DB.Scopes(models.Published).First(&confession, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Confession not found"})
+	}
+	return sendCard(c, confession, format)
+}
@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/Semkufu95/confessions/Backend/cards"
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/models"
+)
+
+func TestConfessionCard_ShowsTopReactionsThenComments(t *testing.T) {
+	defer func(previous config.Config) { config.App = previous }(config.App)
+	config.App.ReactionTypes = []string{"like", "boo", "heart", "hug", "laugh", "sad", "wow"}
+
+	card := confessionCard(models.Confession{
+		Content:  "text",
+		Category: "love",
+		Comments: 7,
+		Reactions: models.ReactionCounts{
+			"wow": 2, "hug": 2, "like": 9, "sad": 1, "heart": 4, "retired": 50,
+		},
+	})
+
+	want := []cards.Stat{{Label: "like", Count: 9}, {Label: "heart", Count: 4}, {Label: "hug", Count: 2}, {Label: "wow", Count: 2}, {Label: "comments", Count: 7}}
+	if len(card.Stats) != len(want) {
+		t.Fatalf("stats = %v, want %v", card.Stats, want)
+	}
+	for i := range want {
+		if card.Stats[i] != want[i] {
+			t.Fatalf("stats = %v, want %v", card.Stats, want)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	"github.com/Semkufu95/confessions/Backend/challenge"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetChallenge issues a challenge for ?scope=register|contact|share, for
+// clients that solve one up front instead of waiting for a 428.
+func GetChallenge(c *fiber.Ctx) error {
+	scope := c.Query("scope")
+	if !challenge.ValidScope(scope) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "scope must be register, contact or share"})
+	}
+	verifier := challenge.Current()
+	if verifier == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Challenges are disabled"})
+	}
+	issued, err := verifier.Issue(c.UserContext(), scope)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to issue challenge"})
+	}
+	return c.JSON(issued)
+}
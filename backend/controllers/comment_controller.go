@@ -1,15 +1,20 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"strings"
 	"time"
 
 	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/counters"
+	"github.com/Semkufu95/confessions/Backend/events"
 	"github.com/Semkufu95/confessions/Backend/models"
 	"github.com/Semkufu95/confessions/Backend/redis"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // PostComment allows a user to comment on a confession
@@ -42,44 +47,91 @@ func PostComment(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid confession id"})
 	}
+	screened, err := screenContent(input.Content)
+	if err != nil {
+		return respondWithError(c, err, "Could not post comment")
+	}
+	if err := premoderate(c.UserContext(), userID, &screened); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not post comment"})
+	}
 
 	comment := models.Comment{
 		UserID:       userID,
 		ConfessionID: parsedConfessionID,
-		Content:      input.Content,
+		Content:      screened.Content,
+		Status:       screened.Status,
 		CreatedAt:    time.Now(),
-	}
 
-	if err := config.DB.Create(&comment).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not post comment"})
-	}
-	_ = syncConfessionCommentCount(comment.ConfessionID)
-
-	// Preload author before returning
-	if err := config.DB.Preload("Author").First(&comment, "id = ?", comment.ID).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load comment author"})
-	}
-
-	data, _ := json.Marshal(comment)
-	redis.Client.Publish(redis.Ctx, "confessions:comment:created", data)
-
-	return c.JSON(comment)
+		ModerationReason: screened.Reason,
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var published int64
+		if err := tx.Model(&models.Confession{}).Scopes(models.Published).Where("id = ?", comment.ConfessionID).Count(&published).Error; err != nil {
+			return err
+		}
+		if published == 0 {
+			return fiber.NewError(fiber.StatusNotFound, "Confession not found")
+		}
+		// Held comments are not counted until they are published.
+		if comment.Status == models.StatusPublished {
+			if err := counters.AddComments(tx, comment.ConfessionID, 1); err != nil {
+				if errors.Is(err, counters.ErrNotFound) {
+					return fiber.NewError(fiber.StatusNotFound, "Confession not found")
+				}
+				return err
+			}
+		}
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+
+		// Preload author before returning
+		if err := tx.Preload("Author").First(&comment, "id = ?", comment.ID).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to load comment author")
+		}
+
+		if comment.Status != models.StatusPublished {
+			return nil
+		}
+		if err := notifyComment(tx, comment); err != nil {
+			return err
+		}
+		return events.Enqueue(tx, userIDStr, events.CommentCreated{CommentPayload: events.NewCommentPayload(comment)})
+	}); err != nil {
+		return respondWithError(c, err, "Could not post comment")
+	}
+
+	return c.Status(heldStatusCode(comment.Status)).JSON(comment)
 }
 
 // GetCommentsByConfession returns comments for a given confession, including author data
 func GetCommentsByConfession(c *fiber.Ctx) error {
 	confessionID := c.Params("id")
 
-	var comments []models.Comment
-	if err := config.DB.
-		Preload("Author").
-		Where("confession_id = ?", confessionID).
-		Order("created_at asc").
-		Find(&comments).Error; err != nil {
+	result, err := redis.Cached(c.UserContext(), "comments", redis.CommentsCacheKey(confessionID), readCacheTTL, func(context.Context) ([]byte, error) {
+		var comments []models.Comment
+		if err := config.DB.
+			Scopes(models.Published).
+			Preload("Author").
+			Where("confession_id = ?", confessionID).
+			Order("created_at asc").
+			Find(&comments).Error; err != nil {
+			return nil, err
+		}
+		return json.Marshal(comments)
+	})
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load comments"})
 	}
 
-	return c.JSON(comments)
+	c.Vary(fiber.HeaderAuthorization)
+	if userID, ok := optionalUserID(c); ok {
+		if result.Body, err = personalizeComments(c.UserContext(), userID, result.Body); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load comments"})
+		}
+	}
+	return sendCachedJSON(c, result)
 }
 
 // DeleteComment allows a user to delete their own comment
@@ -99,13 +151,20 @@ func DeleteComment(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You cannot delete this comment"})
 	}
 
-	if err := config.DB.Delete(&comment).Error; err != nil {
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&comment).Error; err != nil {
+			return err
+		}
+		if comment.Status != models.StatusPublished {
+			return nil
+		}
+		if err := counters.AddComments(tx, comment.ConfessionID, -1); err != nil && !errors.Is(err, counters.ErrNotFound) {
+			return err
+		}
+		return events.Enqueue(tx, userID, events.CommentDeleted{ID: comment.ID, ConfessionID: comment.ConfessionID})
+	}); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete comment"})
 	}
-	_ = syncConfessionCommentCount(comment.ConfessionID)
-
-	data, _ := json.Marshal(fiber.Map{"id": id, "confession_id": comment.ConfessionID})
-	redis.Client.Publish(redis.Ctx, "confessions:comment:deleted", data)
 
 	return c.JSON(fiber.Map{"message": "Comment deleted"})
 }
@@ -140,32 +199,44 @@ func UpdateComment(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You cannot update this comment"})
 	}
 
-	comment.Content = input.Content
-
-	if err := config.DB.Save(&comment).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update comment"})
-	}
-
-	// Reload with author after update
-	if err := config.DB.Preload("Author").First(&comment, "id = ?", comment.ID).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load updated comment author"})
-	}
-
-	data, _ := json.Marshal(comment)
-	redis.Client.Publish(redis.Ctx, "confessions:comment:updated", data)
-
-	return c.JSON(comment)
-}
-
-func syncConfessionCommentCount(confessionID uuid.UUID) error {
-	var total int64
-	if err := config.DB.Model(&models.Comment{}).
-		Where("confession_id = ?", confessionID).
-		Count(&total).Error; err != nil {
-		return err
-	}
-
-	return config.DB.Model(&models.Confession{}).
-		Where("id = ?", confessionID).
-		Update("comments", total).Error
+	screened, err := screenContent(input.Content)
+	if err != nil {
+		return respondWithError(c, err, "Failed to update comment")
+	}
+
+	// An edit can hold a published comment but never releases a held one.
+	wasPublished := comment.Status == models.StatusPublished
+	comment.Content = screened.Content
+	if wasPublished {
+		comment.Status = screened.Status
+		comment.ModerationReason = screened.Reason
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Write only the edited columns; counters are owned by the counters package.
+		if err := tx.Model(&comment).Select("content", "status", "moderation_reason", "updated_at").Updates(&comment).Error; err != nil {
+			return err
+		}
+
+		// Reload with author after update
+		if err := tx.Preload("Author").First(&comment, "id = ?", comment.ID).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to load updated comment author")
+		}
+
+		switch {
+		case comment.Status == models.StatusPublished:
+			return events.Enqueue(tx, userID, events.CommentUpdated{CommentPayload: events.NewCommentPayload(comment)})
+		case wasPublished:
+			// Withdraw it from the thread until reviewed.
+			if err := counters.AddComments(tx, comment.ConfessionID, -1); err != nil && !errors.Is(err, counters.ErrNotFound) {
+				return err
+			}
+			return events.Enqueue(tx, userID, events.CommentDeleted{ID: comment.ID, ConfessionID: comment.ConfessionID})
+		}
+		return nil
+	}); err != nil {
+		return respondWithError(c, err, "Failed to update comment")
+	}
+
+	return c.Status(heldStatusCode(comment.Status)).JSON(comment)
 }
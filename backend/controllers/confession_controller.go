@@ -1,17 +1,21 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
 	"net/url"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/counters"
+	"github.com/Semkufu95/confessions/Backend/events"
 	"github.com/Semkufu95/confessions/Backend/models"
 	"github.com/Semkufu95/confessions/Backend/redis"
+	"github.com/Semkufu95/confessions/Backend/relations"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 var allowedConfessionCategories = map[string]struct{}{
@@ -53,6 +57,10 @@ func CreateConfession(c *fiber.Ctx) error {
 	if !isCategoryValid {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid category"})
 	}
+	screened, err := screenContent(input.Content)
+	if err != nil {
+		return respondWithError(c, err, "Could not save confession")
+	}
 
 	userID, ok := c.Locals("user_id").(string)
 	if !ok || userID == "" {
@@ -62,52 +70,71 @@ func CreateConfession(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
 	}
+	if err := premoderate(c.UserContext(), parsedUserID, &screened); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not save confession"})
+	}
 
 	confession := models.Confession{
 		UserID:    parsedUserID,
-		Content:   input.Content,
+		Content:   screened.Content,
 		Category:  category,
+		Status:    screened.Status,
 		CreatedAt: time.Now(),
+
+		ModerationReason: screened.Reason,
 	}
 
-	if err := config.DB.Create(&confession).Error; err != nil {
+	// 🔹 Save and queue the realtime event atomically
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&confession).Error; err != nil {
+			return err
+		}
+		// Held confessions stay out of feeds and realtime updates.
+		if confession.Status != models.StatusPublished {
+			return nil
+		}
+		return events.Enqueue(tx, userID, events.ConfessionCreated{ConfessionPayload: events.NewConfessionPayload(confession)})
+	}); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not save confession"})
 	}
 
-	// 🔹 Publish event to Redis for real-time updates
-	data, _ := json.Marshal(confession)
-	redis.Client.Publish(redis.Ctx, "confessions:confession:created", data)
-
-	return c.JSON(confession)
+	return c.Status(heldStatusCode(confession.Status)).JSON(confession)
 }
 
 // GetAllConfessions returns all confessions (latest first)
 func GetAllConfessions(c *fiber.Ctx) error {
-	var confessions []models.Confession
-	if err := config.DB.Order("created_at desc").Find(&confessions).Error; err != nil {
+	result, err := redis.Cached(c.UserContext(), "feed", redis.FeedCacheKey, readCacheTTL, func(context.Context) ([]byte, error) {
+		confessions, err := loadConfessionFeed()
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(confessions)
+	})
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch confessions"})
 	}
 
-	type commentCountRow struct {
-		ConfessionID uuid.UUID
-		Total        int64
+	c.Vary(fiber.HeaderAuthorization)
+	if userID, ok := optionalUserID(c); ok {
+		if result.Body, err = personalizeFeed(c.UserContext(), userID, result.Body); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch confessions"})
+		}
 	}
+	return sendCachedJSON(c, result)
+}
 
-	var countRows []commentCountRow
-	_ = config.DB.Model(&models.Comment{}).
-		Select("confession_id, COUNT(*) as total").
-		Group("confession_id").
-		Scan(&countRows).Error
+// confessionFeed is the query behind the public feed: published
+// confessions, newest first.
+func confessionFeed(db *gorm.DB) *gorm.DB {
+	return db.Scopes(models.Published).Order("created_at desc")
+}
 
-	commentCountByConfessionID := make(map[uuid.UUID]int, len(countRows))
-	for _, row := range countRows {
-		commentCountByConfessionID[row.ConfessionID] = int(row.Total)
-	}
-	for i := range confessions {
-		confessions[i].Comments = commentCountByConfessionID[confessions[i].ID]
+func loadConfessionFeed() ([]models.Confession, error) {
+	var confessions []models.Confession
+	if err := config.DB.Scopes(confessionFeed).Find(&confessions).Error; err != nil {
+		return nil, err
 	}
-
-	return c.JSON(confessions)
+	return confessions, nil
 }
 
 // GetConfessionByID returns a single confession by ID
@@ -115,10 +142,21 @@ func GetConfessionByID(c *fiber.Ctx) error {
 	id := c.Params("id")
 
 	var confession models.Confession
-	if err := config.DB.First(&confession, "id = ?", id).Error; err != nil {
+	if err := config.DB.Scopes(models.Published).First(&confession, "id = ?", id).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Confession not found"})
 	}
 
+	// Confessions by muted or blocked authors are not found for the viewer.
+	if viewerID, ok := optionalUserID(c); ok {
+		hidden, err := relations.CachedHidden(c.UserContext(), config.DB, viewerID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch confession"})
+		}
+		if hidden[confession.UserID] {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Confession not found"})
+		}
+	}
+
 	return c.JSON(confession)
 }
 
@@ -140,14 +178,15 @@ func DeleteConfession(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You cannot delete this confession"})
 	}
 
-	if err := config.DB.Delete(&confession).Error; err != nil {
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&confession).Error; err != nil {
+			return err
+		}
+		return events.Enqueue(tx, userID, events.ConfessionDeleted{ID: confession.ID})
+	}); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete confession"})
 	}
 
-	// 🔹 Publish delete event
-	data, _ := json.Marshal(fiber.Map{"id": id})
-	redis.Client.Publish(redis.Ctx, "confessions:confession:deleted", data)
-
 	return c.JSON(fiber.Map{"message": "Confession deleted"})
 }
 
@@ -190,70 +229,70 @@ func UpdateConfession(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You cannot update this confession"})
 	}
 
-	confession.Content = input.Content
+	screened, err := screenContent(input.Content)
+	if err != nil {
+		return respondWithError(c, err, "Failed to update confession")
+	}
+
+	// An edit can hold a published confession but never releases a held one.
+	wasPublished := confession.Status == models.StatusPublished
+	confession.Content = screened.Content
+	if wasPublished {
+		confession.Status = screened.Status
+		confession.ModerationReason = screened.Reason
+	}
 	if normalizedCategory != nil {
 		confession.Category = *normalizedCategory
 	}
-	if err := config.DB.Save(&confession).Error; err != nil {
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Write only the edited columns; counters are owned by the counters package.
+		if err := tx.Model(&confession).Select("content", "category", "status", "moderation_reason").Updates(&confession).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&confession, "id = ?", confession.ID).Error; err != nil {
+			return err
+		}
+		switch {
+		case confession.Status == models.StatusPublished:
+			return events.Enqueue(tx, userID, events.ConfessionUpdated{ConfessionPayload: events.NewConfessionPayload(confession)})
+		case wasPublished:
+			// Withdraw it from feeds and connected clients until reviewed.
+			return events.Enqueue(tx, userID, events.ConfessionDeleted{ID: confession.ID})
+		}
+		return nil
+	}); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update confession"})
 	}
 
-	// publish update event
-	data, _ := json.Marshal(confession)
-	redis.Client.Publish(redis.Ctx, "confessions:confession:updated", data)
-
-	return c.JSON(confession)
+	return c.Status(heldStatusCode(confession.Status)).JSON(confession)
 }
 
-// StarConfession increments stars for a confession
+// StarConfession increments stars for a published confession
 func StarConfession(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var confession models.Confession
-	if err := config.DB.First(&confession, "id = ?", id).Error; err != nil {
+	if err := config.DB.Scopes(models.Published).First(&confession, "id = ?", id).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Confession not found"})
 	}
 
-	confession.Stars += 1
-	if err := config.DB.Save(&confession).Error; err != nil {
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := counters.IncrementStars(tx, confession.ID); err != nil {
+			return err
+		}
+		if err := tx.First(&confession, "id = ?", confession.ID).Error; err != nil {
+			return err
+		}
+		return events.Enqueue(tx, actorID(c), events.ConfessionStarred{ConfessionPayload: events.NewConfessionPayload(confession)})
+	}); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to star confession"})
 	}
 
-	// publish star event
-	data, _ := json.Marshal(confession)
-	redis.Client.Publish(redis.Ctx, "confessions:confession:starred", data)
-
 	return c.JSON(confession)
 }
 
-func ShareConfession(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var confession models.Confession
-	if err := config.DB.First(&confession, "id = ?", id).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Confession not found"})
-	}
-
-	confession.Shares += 1
-	if err := config.DB.Save(&confession).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to share confession"})
-	}
-
-	frontendBaseURL := resolveFrontendBaseURL(c)
-	shareURL := frontendBaseURL + "/confession/" + confession.ID.String()
-
-	data, _ := json.Marshal(confession)
-	redis.Client.Publish(redis.Ctx, "confessions:confession:updated", data)
-
-	return c.JSON(fiber.Map{
-		"message":    "Confession shared",
-		"share_url":  shareURL,
-		"confession": confession,
-	})
-}
-
 func resolveFrontendBaseURL(c *fiber.Ctx) string {
-	configured := strings.TrimSpace(os.Getenv("FRONTEND_BASE_URL"))
-	if configured != "" {
-		return strings.TrimRight(configured, "/")
+	if config.App.FrontendBaseURL != "" {
+		return config.App.FrontendBaseURL
 	}
 
 	origin := strings.TrimSpace(c.Get("Origin"))
@@ -273,26 +312,30 @@ func resolveFrontendBaseURL(c *fiber.Ctx) string {
 func GetConfessionWithComments(c *fiber.Ctx) error {
 	id := c.Params("id")
 
-	var confession models.Confession
-	if err := config.DB.First(&confession, "id = ?", id).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Confession not found"})
-	}
+	result, err := redis.Cached(c.UserContext(), "confession", redis.ConfessionCacheKey(id), readCacheTTL, func(context.Context) ([]byte, error) {
+		var confession models.Confession
+		if err := config.DB.Scopes(models.Published).First(&confession, "id = ?", id).Error; err != nil {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Confession not found")
+		}
 
-	var comments []models.Comment
-	if err := config.DB.Preload("Author").Where("confession_id = ?", id).Order("created_at asc").Find(&comments).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch comments"})
+		var comments []models.Comment
+		if err := config.DB.Scopes(models.Published).Preload("Author").Where("confession_id = ?", id).Order("created_at asc").Find(&comments).Error; err != nil {
+			return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch comments")
+		}
+		return json.Marshal(fiber.Map{
+			"confession": confession,
+			"comments":   comments,
+		})
+	})
+	if err != nil {
+		return respondWithError(c, err, "Failed to fetch confession")
 	}
-	confession.Comments = len(comments)
-	_ = config.DB.Model(&models.Confession{}).Where("id = ?", confession.ID).Update("comments", confession.Comments).Error
 
-	result := fiber.Map{
-		"confession": confession,
-		"comments":   comments,
+	c.Vary(fiber.HeaderAuthorization)
+	if userID, ok := optionalUserID(c); ok {
+		if result.Body, err = personalizeConfessionDetail(c.UserContext(), userID, result.Body); err != nil {
+			return respondWithError(c, err, "Failed to fetch confession")
+		}
 	}
-
-	// cache fetch result (optional, TTL 60s)
-	data, _ := json.Marshal(result)
-	redis.Client.Set(redis.Ctx, "confessions:"+id+":with_comments", data, 60*time.Second)
-
-	return c.JSON(result)
+	return sendCachedJSON(c, result)
 }
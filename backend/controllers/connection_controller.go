@@ -7,8 +7,9 @@ import (
 	"strings"
 
 	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/events"
 	"github.com/Semkufu95/confessions/Backend/models"
-	"github.com/Semkufu95/confessions/Backend/redis"
+	"github.com/Semkufu95/confessions/Backend/relations"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -98,8 +99,19 @@ func GetAllConnections(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch connections"})
 	}
 
+	hidden := map[uuid.UUID]bool{}
+	if viewerID, ok := optionalUserID(c); ok {
+		var err error
+		if hidden, err = relations.CachedHidden(c.UserContext(), config.DB, viewerID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch connections"})
+		}
+	}
+
 	response := make([]connectionResponse, 0, len(connections))
 	for _, item := range connections {
+		if hidden[item.UserID] {
+			continue
+		}
 		response = append(response, mapConnectionResponse(item))
 	}
 
@@ -195,34 +207,46 @@ func ConnectToConnection(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "You cannot connect to your own post"})
 	}
 
+	// A block in either direction looks like a missing post so the sender
+	// cannot tell they were blocked.
+	blocked, err := relations.Blocked(c.UserContext(), config.DB, senderID, connection.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch connection"})
+	}
+	if blocked {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Connection not found"})
+	}
+
 	var existing models.ConnectionRequest
 	err = config.DB.Where("connection_id = ? AND sender_id = ?", connection.ID, senderID).First(&existing).Error
 	if err == nil {
 		if strings.EqualFold(existing.Status, "declined") {
-			existing.Status = "pending"
-			if saveErr := config.DB.Save(&existing).Error; saveErr != nil {
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to resend connection request"})
-			}
-
 			senderUsername := "Someone"
 			var sender models.User
 			if err := config.DB.First(&sender, "id = ?", senderID).Error; err == nil {
 				senderUsername = sender.Username
 			}
 
-			if redis.Client != nil {
-				eventPayload := fiber.Map{
-					"request_id":       existing.ID.String(),
-					"connection_id":    connection.ID.String(),
-					"connection_title": connection.Title,
-					"sender_id":        senderID.String(),
-					"sender_username":  senderUsername,
-					"receiver_id":      connection.UserID.String(),
-					"status":           existing.Status,
+			existing.Status = "pending"
+			if saveErr := config.DB.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Save(&existing).Error; err != nil {
+					return err
 				}
-				if data, marshalErr := json.Marshal(eventPayload); marshalErr == nil {
-					redis.Client.Publish(redis.Ctx, "connections:friend:added", data)
+				if err := notifyConnectionRequest(tx, existing, connection.Title, senderUsername); err != nil {
+					return err
 				}
+				return events.Enqueue(tx, senderID.String(), events.FriendAdded{FriendRequestPayload: events.FriendRequestPayload{
+					RequestID:       existing.ID,
+					ConnectionID:    connection.ID,
+					ConnectionTitle: connection.Title,
+					SenderID:        senderID,
+					SenderUsername:  senderUsername,
+					ReceiverID:      connection.UserID,
+					Status:          existing.Status,
+					PreviousStatus:  "declined",
+				}})
+			}); saveErr != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to resend connection request"})
 			}
 
 			return c.JSON(fiber.Map{
@@ -247,28 +271,30 @@ func ConnectToConnection(c *fiber.Ctx) error {
 		Status:       "pending",
 	}
 
-	if err := config.DB.Create(&request).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create connection request"})
-	}
-
 	senderUsername := "Someone"
 	var sender models.User
 	if err := config.DB.First(&sender, "id = ?", senderID).Error; err == nil {
 		senderUsername = sender.Username
 	}
-	if redis.Client != nil {
-		eventPayload := fiber.Map{
-			"request_id":       request.ID.String(),
-			"connection_id":    connection.ID.String(),
-			"connection_title": connection.Title,
-			"sender_id":        senderID.String(),
-			"sender_username":  senderUsername,
-			"receiver_id":      connection.UserID.String(),
-			"status":           request.Status,
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&request).Error; err != nil {
+			return err
 		}
-		if data, marshalErr := json.Marshal(eventPayload); marshalErr == nil {
-			redis.Client.Publish(redis.Ctx, "connections:friend:added", data)
+		if err := notifyConnectionRequest(tx, request, connection.Title, senderUsername); err != nil {
+			return err
 		}
+		return events.Enqueue(tx, senderID.String(), events.FriendAdded{FriendRequestPayload: events.FriendRequestPayload{
+			RequestID:       request.ID,
+			ConnectionID:    connection.ID,
+			ConnectionTitle: connection.Title,
+			SenderID:        senderID,
+			SenderUsername:  senderUsername,
+			ReceiverID:      connection.UserID,
+			Status:          request.Status,
+		}})
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create connection request"})
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
@@ -389,23 +415,25 @@ func RespondToFriendRequest(c *fiber.Ctx) error {
 		request.Status = "declined"
 	}
 
-	if err := config.DB.Save(&request).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update connection request"})
-	}
-
-	if redis.Client != nil {
-		eventPayload := fiber.Map{
-			"request_id":       request.ID.String(),
-			"connection_id":    request.ConnectionID.String(),
-			"connection_title": request.Connection.Title,
-			"sender_id":        request.SenderID.String(),
-			"sender_username":  request.Sender.Username,
-			"receiver_id":      request.ReceiverID.String(),
-			"status":           request.Status,
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&request).Error; err != nil {
+			return err
 		}
-		if data, marshalErr := json.Marshal(eventPayload); marshalErr == nil {
-			redis.Client.Publish(redis.Ctx, "connections:friend:request:updated", data)
+		if err := notifyConnectionResponse(tx, request, request.Connection.Title); err != nil {
+			return err
 		}
+		return events.Enqueue(tx, userID.String(), events.FriendRequestUpdated{FriendRequestPayload: events.FriendRequestPayload{
+			RequestID:       request.ID,
+			ConnectionID:    request.ConnectionID,
+			ConnectionTitle: request.Connection.Title,
+			SenderID:        request.SenderID,
+			SenderUsername:  request.Sender.Username,
+			ReceiverID:      request.ReceiverID,
+			Status:          request.Status,
+			PreviousStatus:  "pending",
+		}})
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update connection request"})
 	}
 
 	return c.JSON(fiber.Map{
@@ -428,6 +456,15 @@ func GetConnectionProfile(c *fiber.Ctx) error {
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch profile"})
 	}
+	if viewerID, ok := optionalUserID(c); ok {
+		blocked, err := relations.Blocked(c.UserContext(), config.DB, viewerID, connection.UserID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch profile"})
+		}
+		if blocked {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Connection not found"})
+		}
+	}
 
 	var postedConnections []models.Connection
 	if err := config.DB.
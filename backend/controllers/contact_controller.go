@@ -1,9 +1,9 @@
 package controllers
 
 import (
-	"os"
 	"strings"
 
+	"github.com/Semkufu95/confessions/Backend/config"
 	"github.com/Semkufu95/confessions/Backend/utils"
 	"github.com/gofiber/fiber/v2"
 )
@@ -44,7 +44,7 @@ func SendContactMessage(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid email format"})
 	}
 
-	recipient := strings.TrimSpace(os.Getenv("CONTACT_FORM_TO"))
+	recipient := config.App.ContactFormTo
 	if recipient == "" {
 		recipient = defaultContactRecipient
 	}
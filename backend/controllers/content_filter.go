@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"github.com/Semkufu95/confessions/Backend/contentfilter"
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// screening is what a new or edited post is stored with after the content
+// filter (and, for new posts, pre-moderation) had its say.
+type screening struct {
+	Content string
+	Status  string
+	Reason  string
+}
+
+// screenContent runs text through the content filter. A rejection comes back
+// as a 422 error; otherwise it returns the text to store (with masked spans)
+// and the status the post starts in.
+func screenContent(text string) (screening, error) {
+	result := contentfilter.Check(text)
+	switch result.Action {
+	case contentfilter.Reject:
+		reason := result.Reason()
+		if reason == "" {
+			reason = "This content is not allowed"
+		}
+		return screening{}, fiber.NewError(fiber.StatusUnprocessableEntity, reason)
+	case contentfilter.Hold:
+		return screening{Content: result.Text, Status: models.StatusPending, Reason: heldBy(result)}, nil
+	}
+	return screening{Content: result.Text, Status: models.StatusPublished}, nil
+}
+
+// heldBy names the rules that held a post, for the moderation queue.
+func heldBy(result contentfilter.Result) string {
+	reason := "content filter:"
+	for _, match := range result.Matches {
+		if match.Action == contentfilter.Hold {
+			reason += " " + match.Rule
+		}
+	}
+	return reason
+}
+
+// heldStatusCode is 202 for posts waiting for review and 200 otherwise.
+func heldStatusCode(status string) int {
+	if status == models.StatusPending {
+		return fiber.StatusAccepted
+	}
+	return fiber.StatusOK
+}
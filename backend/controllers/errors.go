@@ -0,0 +1,18 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// respondWithError renders an error returned from a DB transaction or cache
+// loader. Steps that need a specific status and message return
+// fiber.NewError; anything else is reported as a 500 with the fallback message.
+func respondWithError(c *fiber.Ctx, err error, fallback string) error {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{"error": fiberErr.Message})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+}
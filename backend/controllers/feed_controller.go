@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/feed"
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/Semkufu95/confessions/Backend/redis"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// forYouCacheTTL bounds how long a ranking is reused. Counters shown in the
+// response are always read fresh; only the order is cached.
+const forYouCacheTTL = 5 * time.Minute
+
+// GetForYouFeed returns confessions ranked for the caller. Signed-in users get
+// their category affinity and mutes applied; anonymous visitors share one
+// ranking. Pages are addressed with ?offset= and ?limit=.
+func GetForYouFeed(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	viewerID, signedIn := optionalUserID(c)
+	cacheUser := "anonymous"
+	if signedIn {
+		cacheUser = viewerID.String()
+	}
+
+	result, err := redis.Cached(ctx, "for_you", redis.ForYouCacheKey(redis.ForYouEpoch(ctx), cacheUser), forYouCacheTTL, func(ctx context.Context) ([]byte, error) {
+		now := time.Now()
+		candidates, err := feed.LoadCandidates(ctx, config.DB)
+		if err != nil {
+			return nil, err
+		}
+		var profile feed.Profile
+		if signedIn {
+			if profile, err = feed.LoadProfile(ctx, config.DB, viewerID, now); err != nil {
+				return nil, err
+			}
+		}
+
+		ranked := feed.Rank(candidates, profile, feed.DefaultWeights, now)
+		ids := make([]uuid.UUID, len(ranked))
+		for i, candidate := range ranked {
+			ids[i] = candidate.ID
+		}
+		return json.Marshal(ids)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to rank confessions"})
+	}
+
+	var ids []uuid.UUID
+	if err := json.Unmarshal(result.Body, &ids); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to rank confessions"})
+	}
+
+	offset, _ := strconv.Atoi(c.Query("offset"))
+	if offset < 0 || offset > len(ids) {
+		offset = len(ids)
+	}
+	end := offset + pageLimit(c)
+	if end > len(ids) {
+		end = len(ids)
+	}
+	page := ids[offset:end]
+
+	confessions, err := loadConfessionsInOrder(page)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch confessions"})
+	}
+	if signedIn && len(confessions) > 0 {
+		pageIDs := make([]string, len(confessions))
+		for i, confession := range confessions {
+			pageIDs[i] = confession.ID.String()
+		}
+		mine, err := myReactions(viewerID, "confession_id", pageIDs)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch confessions"})
+		}
+		for i := range confessions {
+			confessions[i].MyReaction = mine[confessions[i].ID.String()]
+		}
+	}
+
+	var nextOffset *int
+	if end < len(ids) {
+		nextOffset = &end
+	}
+	c.Vary(fiber.HeaderAuthorization)
+	return c.JSON(fiber.Map{
+		"confessions": confessions,
+		"next_offset": nextOffset,
+	})
+}
+
+// loadConfessionsInOrder fetches confessions by id in the given order,
+// skipping any deleted or held since the ranking was cached.
+func loadConfessionsInOrder(ids []uuid.UUID) ([]models.Confession, error) {
+	confessions := make([]models.Confession, 0, len(ids))
+	if len(ids) == 0 {
+		return confessions, nil
+	}
+
+	var rows []models.Confession
+	if err := config.DB.Scopes(models.Published).Where("id IN ?", ids).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]models.Confession, len(rows))
+	for _, row := range rows {
+		byID[row.ID] = row
+	}
+	for _, id := range ids {
+		if confession, ok := byID[id]; ok {
+			confessions = append(confessions, confession)
+		}
+	}
+	return confessions, nil
+}
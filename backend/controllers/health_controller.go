@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/redis"
+	"github.com/gofiber/fiber/v2"
+)
+
+const readinessCheckTimeout = 2 * time.Second
+
+var shuttingDown atomic.Bool
+
+// MarkShuttingDown makes /readyz fail so load balancers stop routing new
+// traffic while in-flight requests drain.
+func MarkShuttingDown() {
+	shuttingDown.Store(true)
+}
+
+type dependencyCheck struct {
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+type readinessResponse struct {
+	Status string                     `json:"status"`
+	Checks map[string]dependencyCheck `json:"checks"`
+}
+
+// Healthz reports that the process is alive and serving HTTP.
+func Healthz(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// Readyz checks every dependency and answers 503 when a critical one fails
+// or shutdown has started. Redis and its subscribers are non-critical
+// because the app runs in degraded mode without them. The endpoint is
+// public, so failures are logged and only their status is returned.
+func Readyz(c *fiber.Ctx) error {
+	checks := map[string]dependencyCheck{
+		"postgres":   runCheck(c.UserContext(), "postgres", true, pingPostgres),
+		"migrations": runCheck(c.UserContext(), "migrations", true, config.CheckMigrations),
+		"redis":      runCheck(c.UserContext(), "redis", false, pingRedis),
+	}
+
+	subscriptions := redis.Subscriptions()
+	names := make([]string, 0, len(subscriptions))
+	for name := range subscriptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		running := subscriptions[name]
+		checks["redis_"+name] = runCheck(c.UserContext(), "redis_"+name, false, func(context.Context) error {
+			if !running {
+				return errors.New("not subscribed")
+			}
+			return nil
+		})
+	}
+
+	checks["shutdown"] = runCheck(c.UserContext(), "shutdown", true, func(context.Context) error {
+		if shuttingDown.Load() {
+			return errors.New("server is shutting down")
+		}
+		return nil
+	})
+
+	status := "ok"
+	for _, check := range checks {
+		if check.Status == "ok" {
+			continue
+		}
+		if check.Critical {
+			status = "unavailable"
+			break
+		}
+		status = "degraded"
+	}
+
+	response := readinessResponse{Status: status, Checks: checks}
+	if status == "unavailable" {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(response)
+	}
+	return c.JSON(response)
+}
+
+func runCheck(parent context.Context, name string, critical bool, check func(context.Context) error) dependencyCheck {
+	ctx, cancel := context.WithTimeout(parent, readinessCheckTimeout)
+	defer cancel()
+
+	started := time.Now()
+	err := check(ctx)
+	result := dependencyCheck{
+		Status:    "ok",
+		Critical:  critical,
+		LatencyMS: float64(time.Since(started).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = "failing"
+		log.Printf("readyz: %s check failing: %v", name, err)
+	}
+	return result
+}
+
+func pingPostgres(ctx context.Context) error {
+	if config.DB == nil {
+		return errors.New("database is not initialized")
+	}
+	sqlDB, err := config.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func pingRedis(ctx context.Context) error {
+	if redis.Client == nil {
+		return errors.New("redis client is not initialized")
+	}
+	return redis.Client.Ping(ctx).Err()
+}
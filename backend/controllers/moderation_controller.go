@@ -0,0 +1,411 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/counters"
+	"github.com/Semkufu95/confessions/Backend/events"
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/Semkufu95/confessions/Backend/reputation"
+	"github.com/Semkufu95/confessions/Backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// premoderate holds a post the content filter let through when
+// config.App.ModerationMode says its author's posts must be reviewed first.
+func premoderate(ctx context.Context, userID uuid.UUID, screened *screening) error {
+	if screened.Status != models.StatusPublished {
+		return nil
+	}
+	switch config.App.ModerationMode {
+	case config.ModerationAll:
+		screened.Status, screened.Reason = models.StatusPending, "pre-moderation: all posts"
+		return nil
+	case config.ModerationLowTrust:
+	default:
+		return nil
+	}
+
+	author, err := reputation.Get(ctx, config.DB, userID)
+	if err != nil {
+		return err
+	}
+	reports, err := priorReports(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if reason := lowTrustReason(author, reports); reason != "" {
+		screened.Status, screened.Reason = models.StatusPending, "pre-moderation: "+reason
+	}
+	return nil
+}
+
+// lowTrustReason explains why an author counts as low-trust, or returns "".
+func lowTrustReason(author reputation.Reputation, reports int64) string {
+	switch {
+	case !author.Signals.EmailVerified:
+		return "unverified email"
+	case author.Signals.AccountAge < config.App.ModerationNewAccountAge:
+		return "new account"
+	case reports >= int64(config.App.ModerationReportThreshold):
+		return "prior reports"
+	case author.Score < config.App.ModerationMinReputation:
+		return "low reputation"
+	}
+	return ""
+}
+
+// priorReports counts the reports against userID that admins upheld, that
+// is their posts that were rejected. Open reports only put a post in the
+// reported queue; anyone can file them, so they never hold an author.
+func priorReports(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var total int64
+	err := config.DB.WithContext(ctx).Raw(`
+		SELECT (SELECT count(*) FROM confessions WHERE user_id = @user AND status = @rejected)
+			+ (SELECT count(*) FROM comments WHERE user_id = @user AND status = @rejected)`,
+		map[string]interface{}{"user": userID, "rejected": models.StatusRejected},
+	).Scan(&total).Error
+	return total, err
+}
+
+type moderationQueueItem struct {
+	ID             uuid.UUID  `json:"id"`
+	ConfessionID   *uuid.UUID `json:"confession_id,omitempty"`
+	Content        string     `json:"content"`
+	Category       string     `json:"category,omitempty"`
+	Reason         string     `json:"reason"`
+	AuthorID       uuid.UUID  `json:"author_id"`
+	AuthorUsername string     `json:"author_username"`
+	// AuthorReputation is the author's current reputation score.
+	AuthorReputation int `json:"author_reputation"`
+	// Reports and ReportedAt are the open reports on a reported post and
+	// when the oldest was filed.
+	Reports    int        `json:"reports,omitempty"`
+	ReportedAt *time.Time `json:"reported_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// GetModerationQueue lists pending confessions (or comments with
+// ?type=comment), oldest first. With ?reported=true it lists published ones
+// with open reports instead, oldest report first, with the number of open
+// reports and the latest report's reason.
+func GetModerationQueue(c *fiber.Ctx) error {
+	table, columns, reportColumn := "confessions", "t.category", "confession_id"
+	switch c.Query("type", "confession") {
+	case "confession":
+	case "comment":
+		table, columns, reportColumn = "comments", "t.confession_id", "comment_id"
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "type must be confession or comment"})
+	}
+	reported := c.QueryBool("reported")
+	cursor, err := parseCursor(c.Query("cursor"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid cursor"})
+	}
+	limit := pageLimit(c)
+
+	query := config.DB.Table(table + " AS t").Joins("JOIN users u ON u.id = t.user_id")
+	if reported {
+		open := config.DB.Table("reports").
+			Select(reportColumn + " AS post_id, count(*) AS reports, min(created_at) AS reported_at, (array_agg(reason ORDER BY created_at DESC))[1] AS reason").
+			Where("resolved_at IS NULL AND " + reportColumn + " IS NOT NULL").
+			Group(reportColumn)
+		query = query.
+			Select("t.id, t.content, r.reason, r.reports, r.reported_at, t.user_id AS author_id, u.username AS author_username, t.created_at, "+columns).
+			Joins("JOIN (?) AS r ON r.post_id = t.id", open).
+			Where("t.status = ?", models.StatusPublished)
+		if cursor != nil {
+			query = query.Where("(r.reported_at, t.id) > (?, ?)", cursor.At, cursor.ID)
+		}
+		query = query.Order("r.reported_at ASC, t.id ASC")
+	} else {
+		query = query.
+			Select("t.id, t.content, t.moderation_reason AS reason, t.user_id AS author_id, u.username AS author_username, t.created_at, "+columns).
+			Where("t.status = ?", models.StatusPending)
+		if cursor != nil {
+			query = query.Where("(t.created_at, t.id) > (?, ?)", cursor.At, cursor.ID)
+		}
+		query = query.Order("t.created_at ASC, t.id ASC")
+	}
+	items := []moderationQueueItem{}
+	if err := query.Limit(limit + 1).Scan(&items).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load the moderation queue"})
+	}
+
+	nextCursor := ""
+	if len(items) > limit {
+		items = items[:limit]
+		last := items[limit-1]
+		at := last.CreatedAt
+		if last.ReportedAt != nil {
+			at = *last.ReportedAt
+		}
+		nextCursor = pageCursor{At: at, ID: last.ID}.encode()
+	}
+
+	authorIDs := make([]uuid.UUID, len(items))
+	for i, item := range items {
+		authorIDs[i] = item.AuthorID
+	}
+	reputations, err := reputation.Lookup(c.UserContext(), config.DB, authorIDs)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load the moderation queue"})
+	}
+	for i := range items {
+		items[i].AuthorReputation = reputations[items[i].AuthorID].Score
+	}
+	return c.JSON(fiber.Map{"items": items, "next_cursor": nextCursor})
+}
+
+// moderationDecision is an admin's verdict on a pending or reported post.
+type moderationDecision struct {
+	adminID uuid.UUID
+	approve bool
+	reason  string
+	at      time.Time
+}
+
+// readDecision reads the admin and, for rejections, the optional reason
+// shown to the author.
+func readDecision(c *fiber.Ctx, approve bool) (moderationDecision, error) {
+	adminID, err := authUserID(c)
+	if err != nil {
+		return moderationDecision{}, fiber.NewError(fiber.StatusUnauthorized, "Invalid token claims")
+	}
+	decision := moderationDecision{adminID: adminID, approve: approve, at: time.Now()}
+	if approve {
+		return decision, nil
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&input); err != nil {
+			return moderationDecision{}, fiber.NewError(fiber.StatusBadRequest, "Invalid input")
+		}
+	}
+	decision.reason = strings.TrimSpace(input.Reason)
+	if len(decision.reason) > 500 {
+		return moderationDecision{}, fiber.NewError(fiber.StatusBadRequest, "Reason must be 500 characters or less")
+	}
+	if decision.reason == "" {
+		decision.reason = "Rejected by a moderator"
+	}
+	return decision, nil
+}
+
+func (d moderationDecision) updates() map[string]interface{} {
+	status, reason := models.StatusPublished, ""
+	if !d.approve {
+		status, reason = models.StatusRejected, d.reason
+	}
+	return map[string]interface{}{
+		"status":            status,
+		"moderation_reason": reason,
+		"moderated_by":      d.adminID,
+		"moderated_at":      d.at,
+	}
+}
+
+// lockReviewable loads a post awaiting review for update: pending, or
+// published with open reports in reportColumn. Anything else fails with 404.
+func lockReviewable(tx *gorm.DB, dest interface{}, rawID, name, reportColumn string) error {
+	notFound := fiber.NewError(fiber.StatusNotFound, "No pending or reported "+name+" with this id")
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return notFound
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("status = ? OR (status = ? AND EXISTS (SELECT 1 FROM reports WHERE reports."+reportColumn+" = ? AND reports.resolved_at IS NULL))",
+			models.StatusPending, models.StatusPublished, id).
+		First(dest, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return notFound
+		}
+		return err
+	}
+	return nil
+}
+
+// resolveReports closes the open reports on a post once an admin decided on
+// it.
+func resolveReports(tx *gorm.DB, reportColumn string, id uuid.UUID, decision moderationDecision) error {
+	return tx.Model(&models.Report{}).
+		Where(reportColumn+" = ? AND resolved_at IS NULL", id).
+		Updates(map[string]interface{}{"resolved_by": decision.adminID, "resolved_at": decision.at}).Error
+}
+
+func ApproveConfession(c *fiber.Ctx) error { return decideConfession(c, true) }
+func RejectConfession(c *fiber.Ctx) error  { return decideConfession(c, false) }
+func ApproveComment(c *fiber.Ctx) error    { return decideComment(c, true) }
+func RejectComment(c *fiber.Ctx) error     { return decideComment(c, false) }
+
+// decideConfession publishes or rejects a pending confession, or keeps or
+// rejects a reported one. Publishing emits the created event the author's
+// post skipped, on the author's behalf; rejecting a published confession
+// withdraws it like a delete. Either way its open reports are resolved.
+func decideConfession(c *fiber.Ctx, approve bool) error {
+	decision, err := readDecision(c, approve)
+	if err != nil {
+		return respondWithError(c, err, "Invalid input")
+	}
+
+	var confession models.Confession
+	changed := true
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockReviewable(tx, &confession, c.Params("id"), "confession", "confession_id"); err != nil {
+			return err
+		}
+		if err := resolveReports(tx, "confession_id", confession.ID, decision); err != nil {
+			return err
+		}
+		wasPublished := confession.Status == models.StatusPublished
+		// Keeping a reported confession only closes its reports.
+		if wasPublished && approve {
+			changed = false
+			return nil
+		}
+		if err := tx.Model(&confession).Updates(decision.updates()).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&confession, "id = ?", confession.ID).Error; err != nil {
+			return err
+		}
+		if err := notifyModeration(tx, confession.UserID, "confession", &confession.ID, nil, confession.Content, decision, wasPublished); err != nil {
+			return err
+		}
+		author := confession.UserID.String()
+		if !approve {
+			if wasPublished {
+				return events.Enqueue(tx, author, events.ConfessionDeleted{ID: confession.ID})
+			}
+			return nil
+		}
+		return events.Enqueue(tx, author, events.ConfessionCreated{ConfessionPayload: events.NewConfessionPayload(confession)})
+	}); err != nil {
+		return respondWithError(c, err, "Failed to moderate confession")
+	}
+
+	if changed {
+		moderationDecided(confession.UserID, "confession", decision)
+	}
+	return c.JSON(confession)
+}
+
+// decideComment publishes or rejects a pending comment, or keeps or rejects
+// a reported one, like decideConfession. The comment count of its confession
+// follows whether it is published.
+func decideComment(c *fiber.Ctx, approve bool) error {
+	decision, err := readDecision(c, approve)
+	if err != nil {
+		return respondWithError(c, err, "Invalid input")
+	}
+
+	var comment models.Comment
+	changed := true
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockReviewable(tx, &comment, c.Params("id"), "comment", "comment_id"); err != nil {
+			return err
+		}
+		if err := resolveReports(tx, "comment_id", comment.ID, decision); err != nil {
+			return err
+		}
+		wasPublished := comment.Status == models.StatusPublished
+		// Keeping a reported comment only closes its reports.
+		if wasPublished && approve {
+			changed = false
+			return tx.Preload("Author").First(&comment, "id = ?", comment.ID).Error
+		}
+		if err := tx.Model(&comment).Updates(decision.updates()).Error; err != nil {
+			return err
+		}
+		if err := tx.Preload("Author").First(&comment, "id = ?", comment.ID).Error; err != nil {
+			return err
+		}
+		if err := notifyModeration(tx, comment.UserID, "comment", &comment.ConfessionID, &comment.ID, comment.Content, decision, wasPublished); err != nil {
+			return err
+		}
+		author := comment.UserID.String()
+		if !approve {
+			if !wasPublished {
+				return nil
+			}
+			if err := counters.AddComments(tx, comment.ConfessionID, -1); err != nil && !errors.Is(err, counters.ErrNotFound) {
+				return err
+			}
+			return events.Enqueue(tx, author, events.CommentDeleted{ID: comment.ID, ConfessionID: comment.ConfessionID})
+		}
+		if err := counters.AddComments(tx, comment.ConfessionID, 1); err != nil && !errors.Is(err, counters.ErrNotFound) {
+			return err
+		}
+		if err := notifyComment(tx, comment); err != nil {
+			return err
+		}
+		return events.Enqueue(tx, author, events.CommentCreated{CommentPayload: events.NewCommentPayload(comment)})
+	}); err != nil {
+		return respondWithError(c, err, "Failed to moderate comment")
+	}
+
+	if changed {
+		moderationDecided(comment.UserID, "comment", decision)
+	}
+	return c.JSON(comment)
+}
+
+// moderationDecided runs after a decision is committed and never fails the
+// request: a rejection counts against the author's reputation right away
+// (a held post publishes no event when rejected), and the author is emailed when SMTP is
+// configured, on top of the in-app notification the decision wrote.
+func moderationDecided(authorID uuid.UUID, kind string, decision moderationDecision) {
+	if !decision.approve {
+		if err := reputation.Refresh(context.Background(), config.DB, authorID); err != nil {
+			log.Printf("moderation: failed to refresh reputation of %s: %v", authorID, err)
+		}
+	}
+	if !config.App.SMTP.Configured() {
+		return
+	}
+	go func() {
+		var author models.User
+		if err := config.DB.Select("email").First(&author, "id = ?", authorID).Error; err != nil {
+			log.Printf("moderation: failed to load author %s: %v", authorID, err)
+			return
+		}
+		if err := utils.SendModerationDecision(author.Email, kind, decision.approve, decision.reason); err != nil {
+			log.Printf("moderation: failed to notify author %s: %v", authorID, err)
+		}
+	}()
+}
+
+// GetMyModeration lists the caller's pending and rejected posts, newest
+// first, so authors can follow what happened to them.
+func GetMyModeration(c *fiber.Ctx) error {
+	userID, err := authUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+	}
+	const limit = 50
+	unpublished := []string{models.StatusPending, models.StatusRejected}
+
+	confessions := []models.Confession{}
+	if err := config.DB.Where("user_id = ? AND status IN ?", userID, unpublished).
+		Order("created_at DESC").Limit(limit).Find(&confessions).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load your posts"})
+	}
+	comments := []models.Comment{}
+	if err := config.DB.Where("user_id = ? AND status IN ?", userID, unpublished).
+		Order("created_at DESC").Limit(limit).Find(&comments).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load your posts"})
+	}
+	return c.JSON(fiber.Map{"confessions": confessions, "comments": comments})
+}
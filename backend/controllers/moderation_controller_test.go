@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/Semkufu95/confessions/Backend/reputation"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestLowTrustReason(t *testing.T) {
+	defer func(previous config.Config) { config.App = previous }(config.App)
+	config.App.ModerationNewAccountAge = 72 * time.Hour
+	config.App.ModerationReportThreshold = 2
+	config.App.ModerationMinReputation = 25
+	established := 30 * 24 * time.Hour
+
+	cases := []struct {
+		name    string
+		signals reputation.Signals
+		reports int64
+		want    string
+	}{
+		{"trusted", reputation.Signals{EmailVerified: true, AccountAge: established}, 1, ""},
+		{"unverified", reputation.Signals{AccountAge: established}, 0, "unverified email"},
+		{"new account", reputation.Signals{EmailVerified: true, AccountAge: time.Hour}, 0, "new account"},
+		{"reported", reputation.Signals{EmailVerified: true, AccountAge: established}, 2, "prior reports"},
+		{"low reputation", reputation.Signals{EmailVerified: true, AccountAge: established, UpheldReports: 1}, 0, "low reputation"},
+	}
+	for _, tc := range cases {
+		if got := lowTrustReason(reputation.New(tc.signals), tc.reports); got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestPremoderate_Modes(t *testing.T) {
+	defer func(previous config.Config) { config.App = previous }(config.App)
+
+	config.App.ModerationMode = config.ModerationOff
+	screened := screening{Content: "hi", Status: models.StatusPublished}
+	if err := premoderate(context.Background(), uuid.New(), &screened); err != nil || screened.Status != models.StatusPublished {
+		t.Fatalf("expected off to publish, got %+v (%v)", screened, err)
+	}
+
+	config.App.ModerationMode = config.ModerationAll
+	if err := premoderate(context.Background(), uuid.New(), &screened); err != nil || screened.Status != models.StatusPending {
+		t.Fatalf("expected all to hold, got %+v (%v)", screened, err)
+	}
+
+	// A post the content filter already held keeps the filter's reason.
+	held := screening{Status: models.StatusPending, Reason: "content filter: phone"}
+	if err := premoderate(context.Background(), uuid.New(), &held); err != nil || held.Reason != "content filter: phone" {
+		t.Fatalf("expected the filter reason to be kept, got %+v (%v)", held, err)
+	}
+}
+
+func TestModeration_ReportedConfessionIsQueuedAndRejected(t *testing.T) {
+	app, confession, users := setupReactionTest(t, 3)
+	app.Post("/confessions/:id/report", ReportConfession)
+	app.Get("/admin/moderation", GetModerationQueue)
+	app.Post("/admin/moderation/confessions/:id/approve", ApproveConfession)
+	app.Post("/admin/moderation/confessions/:id/reject", RejectConfession)
+	admin := users[2].ID.String()
+
+	send := func(method, path, user, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", user)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp
+	}
+	queued := func() *moderationQueueItem {
+		t.Helper()
+		var page struct {
+			Items []moderationQueueItem `json:"items"`
+		}
+		resp := send(http.MethodGet, "/admin/moderation?reported=true&limit=100", admin, "")
+		if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+			t.Fatalf("failed to decode the queue: %v", err)
+		}
+		for i := range page.Items {
+			if page.Items[i].ID == confession.ID {
+				return &page.Items[i]
+			}
+		}
+		return nil
+	}
+
+	if resp := send(http.MethodPost, "/confessions/"+confession.ID.String()+"/report", users[1].ID.String(), `{"reason":"spam"}`); resp.StatusCode != fiber.StatusAccepted {
+		t.Fatalf("expected the report to be accepted, got %d", resp.StatusCode)
+	}
+	item := queued()
+	if item == nil || item.Reports != 1 || item.Reason != "spam" {
+		t.Fatalf("expected the reported confession in the queue, got %+v", item)
+	}
+
+	if resp := send(http.MethodPost, "/admin/moderation/confessions/"+confession.ID.String()+"/reject", admin, `{"reason":"spam"}`); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected the reported confession to be rejected, got %d", resp.StatusCode)
+	}
+	var stored models.Confession
+	if err := config.DB.First(&stored, "id = ?", confession.ID).Error; err != nil || stored.Status != models.StatusRejected {
+		t.Fatalf("expected the confession to be rejected, got %+v (%v)", stored, err)
+	}
+	var notified int64
+	config.DB.Model(&models.Notification{}).Where("user_id = ? AND kind = ? AND confession_id = ?", users[0].ID, models.NotificationModeration, confession.ID).Count(&notified)
+	if notified != 1 {
+		t.Fatalf("expected the author to be notified once, got %d", notified)
+	}
+	var open int64
+	config.DB.Model(&models.Report{}).Where("confession_id = ? AND resolved_at IS NULL", confession.ID).Count(&open)
+	if open != 0 || queued() != nil {
+		t.Fatalf("expected the reports to be resolved, %d still open", open)
+	}
+	if resp := send(http.MethodPost, "/admin/moderation/confessions/"+confession.ID.String()+"/approve", admin, ""); resp.StatusCode != fiber.StatusNotFound {
+		t.Fatalf("expected a decided confession to be 404, got %d", resp.StatusCode)
+	}
+}
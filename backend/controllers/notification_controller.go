@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/Semkufu95/confessions/Backend/notifications"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxThreadNotifications bounds how many earlier commenters one comment
+// notifies.
+const maxThreadNotifications = 50
+
+func dedupeKey(parts ...string) *string {
+	key := strings.Join(parts, ":")
+	return &key
+}
+
+// notifyComment tells the confession's author, and the people who commented
+// on it before, about a newly published comment. It must run in the
+// transaction that publishes the comment, with the author preloaded.
+func notifyComment(tx *gorm.DB, comment models.Comment) error {
+	var confession models.Confession
+	if err := tx.Select("id", "user_id").First(&confession, "id = ?", comment.ConfessionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	body := excerpt(comment.Content, 140)
+	key := dedupeKey("comment", comment.ID.String())
+	if err := notifications.Notify(tx, models.Notification{
+		UserID:       confession.UserID,
+		ActorID:      &comment.UserID,
+		Kind:         models.NotificationComment,
+		ConfessionID: &confession.ID,
+		CommentID:    &comment.ID,
+		Title:        comment.Author.Username + " commented on your confession",
+		Body:         body,
+		DedupeKey:    key,
+	}); err != nil {
+		return err
+	}
+
+	// Comments are flat, so a reply is a later comment in the same thread.
+	var participants []uuid.UUID
+	if err := tx.Model(&models.Comment{}).
+		Select("user_id").
+		Scopes(models.Published).
+		Where("confession_id = ? AND id <> ? AND user_id NOT IN ?", confession.ID, comment.ID, []uuid.UUID{comment.UserID, confession.UserID}).
+		Group("user_id").
+		Order("max(created_at) DESC").
+		Limit(maxThreadNotifications).
+		Pluck("user_id", &participants).Error; err != nil {
+		return err
+	}
+	for _, participant := range participants {
+		if err := notifications.Notify(tx, models.Notification{
+			UserID:       participant,
+			ActorID:      &comment.UserID,
+			Kind:         models.NotificationCommentReply,
+			ConfessionID: &confession.ID,
+			CommentID:    &comment.ID,
+			Title:        comment.Author.Username + " replied in a thread you commented on",
+			Body:         body,
+			DedupeKey:    key,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyReaction tells the author of a confession or comment that someone
+// reacted. Reactions are anonymous, and each person notifies an author
+// once per post however often they change or re-add their reaction.
+func notifyReaction(tx *gorm.DB, actorID, authorID, confessionID uuid.UUID, commentID *uuid.UUID, reactionType string) error {
+	n := models.Notification{
+		UserID:       authorID,
+		ActorID:      &actorID,
+		Kind:         models.NotificationReaction,
+		ConfessionID: &confessionID,
+		CommentID:    commentID,
+		Title:        "Someone reacted to your confession",
+		Body:         reactionType,
+		DedupeKey:    dedupeKey("reaction", actorID.String(), confessionID.String()),
+	}
+	if commentID != nil {
+		n.Title = "Someone reacted to your comment"
+		n.DedupeKey = dedupeKey("reaction", actorID.String(), commentID.String())
+	}
+	return notifications.Notify(tx, n)
+}
+
+// notifyConnectionRequest tells a post's owner that someone asked to connect.
+func notifyConnectionRequest(tx *gorm.DB, request models.ConnectionRequest, connectionTitle, senderUsername string) error {
+	return notifications.Notify(tx, models.Notification{
+		UserID:              request.ReceiverID,
+		ActorID:             &request.SenderID,
+		Kind:                models.NotificationConnectionRequest,
+		ConnectionRequestID: &request.ID,
+		Title:               senderUsername + " wants to connect",
+		Body:                connectionTitle,
+	})
+}
+
+// notifyConnectionResponse tells the sender of a request how it was answered.
+func notifyConnectionResponse(tx *gorm.DB, request models.ConnectionRequest, connectionTitle string) error {
+	return notifications.Notify(tx, models.Notification{
+		UserID:              request.SenderID,
+		ActorID:             &request.ReceiverID,
+		Kind:                models.NotificationConnectionResponse,
+		ConnectionRequestID: &request.ID,
+		Title:               "Your connection request was " + request.Status,
+		Body:                connectionTitle,
+	})
+}
+
+// notifyModeration tells an author what an admin decided on their post.
+// Admins act anonymously, so there is no actor. removed is a rejection of a
+// post that was already published.
+func notifyModeration(tx *gorm.DB, authorID uuid.UUID, name string, confessionID, commentID *uuid.UUID, content string, decision moderationDecision, removed bool) error {
+	n := models.Notification{
+		UserID:       authorID,
+		Kind:         models.NotificationModeration,
+		ConfessionID: confessionID,
+		CommentID:    commentID,
+	}
+	postID := confessionID
+	if commentID != nil {
+		postID = commentID
+	}
+	switch {
+	case decision.approve:
+		n.Title = "Your " + name + " was published"
+		n.Body = excerpt(content, 140)
+		n.DedupeKey = dedupeKey("moderation", postID.String(), models.StatusPublished)
+	case removed:
+		n.Title = "Your " + name + " was removed by a moderator"
+		n.Body = decision.reason
+		n.DedupeKey = dedupeKey("moderation", postID.String(), models.StatusRejected)
+	default:
+		n.Title = "Your " + name + " was rejected"
+		n.Body = decision.reason
+		n.DedupeKey = dedupeKey("moderation", postID.String(), models.StatusRejected)
+	}
+	return notifications.Notify(tx, n)
+}
+
+// GetMyNotifications lists the user's notifications, newest first, with
+// their unread count. ?unread=true leaves out read ones.
+func GetMyNotifications(c *fiber.Ctx) error {
+	userID, err := authUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+	}
+	cursor, err := parseCursor(c.Query("cursor"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid cursor"})
+	}
+	limit := pageLimit(c)
+
+	query := config.DB.Where("user_id = ?", userID)
+	if c.QueryBool("unread") {
+		query = query.Where("read_at IS NULL")
+	}
+	if cursor != nil {
+		query = query.Where("(created_at, id) < (?, ?)", cursor.At, cursor.ID)
+	}
+	items := []models.Notification{}
+	if err := query.Order("created_at DESC, id DESC").Limit(limit + 1).Find(&items).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load notifications"})
+	}
+	unread, err := notifications.Unread(config.DB, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load notifications"})
+	}
+
+	nextCursor := ""
+	if len(items) > limit {
+		items = items[:limit]
+		last := items[limit-1]
+		nextCursor = pageCursor{At: last.CreatedAt, ID: last.ID}.encode()
+	}
+
+	return c.JSON(fiber.Map{
+		"notifications": items,
+		"unread":        unread,
+		"next_cursor":   nextCursor,
+	})
+}
+
+// MarkNotificationRead marks one of the user's notifications as read.
+func MarkNotificationRead(c *fiber.Ctx) error {
+	userID, err := authUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+	}
+	notificationID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid notification id"})
+	}
+
+	var notification models.Notification
+	if err := config.DB.First(&notification, "id = ? AND user_id = ?", notificationID, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Notification not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load notification"})
+	}
+	if notification.ReadAt == nil {
+		now := time.Now()
+		if err := config.DB.Model(&notification).Where("read_at IS NULL").Update("read_at", now).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update notification"})
+		}
+		notification.ReadAt = &now
+	}
+	unread, err := notifications.Unread(config.DB, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update notification"})
+	}
+
+	return c.JSON(fiber.Map{"notification": notification, "unread": unread})
+}
+
+// MarkAllNotificationsRead marks every unread notification of the user as
+// read.
+func MarkAllNotificationsRead(c *fiber.Ctx) error {
+	userID, err := authUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+	}
+	result := config.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update notifications"})
+	}
+	return c.JSON(fiber.Map{"updated": result.RowsAffected, "unread": 0})
+}
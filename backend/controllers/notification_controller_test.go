@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestNotifications_ReactionsReadStateAndBlocks(t *testing.T) {
+	app, confession, users := setupReactionTest(t, 3)
+	author, reactor, blocked := users[0], users[1], users[2]
+	app.Get("/me/notifications", GetMyNotifications)
+	app.Post("/me/notifications/read-all", MarkAllNotificationsRead)
+	app.Post("/me/notifications/:id/read", MarkNotificationRead)
+
+	block := models.UserBlock{UserID: author.ID, BlockedUserID: blocked.ID}
+	if err := config.DB.Create(&block).Error; err != nil {
+		t.Fatalf("failed to block: %v", err)
+	}
+	t.Cleanup(func() { config.DB.Delete(&block) })
+
+	// Switching and re-adding a reaction notifies the author once; the
+	// author's own and a blocked user's reactions do not notify at all.
+	for _, step := range []struct {
+		user models.User
+		body string
+	}{
+		{reactor, `{"type":"like"}`},
+		{reactor, `{"type":"heart"}`},
+		{reactor, `{"type":"heart","mode":"toggle"}`},
+		{reactor, `{"type":"like"}`},
+		{author, `{"type":"like"}`},
+		{blocked, `{"type":"like"}`},
+	} {
+		if status := react(t, app, confession.ID, step.user.ID, step.body); status != fiber.StatusOK {
+			t.Fatalf("react %s: status %d", step.body, status)
+		}
+	}
+
+	call := func(method, path string) (int, map[string]json.RawMessage) {
+		t.Helper()
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("X-Test-User", author.ID.String())
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var body map[string]json.RawMessage
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+
+	status, body := call(http.MethodGet, "/me/notifications")
+	var listed []models.Notification
+	_ = json.Unmarshal(body["notifications"], &listed)
+	if status != fiber.StatusOK || string(body["unread"]) != "1" || len(listed) != 1 || listed[0].Kind != models.NotificationReaction {
+		t.Fatalf("expected one unread reaction notification, got %d %s", status, body["notifications"])
+	}
+
+	if status, body = call(http.MethodPost, "/me/notifications/"+listed[0].ID.String()+"/read"); status != fiber.StatusOK || string(body["unread"]) != "0" {
+		t.Fatalf("mark read: %d %v", status, body)
+	}
+	if _, body = call(http.MethodGet, "/me/notifications?unread=true"); string(body["notifications"]) != "[]" {
+		t.Fatalf("expected no unread notifications, got %s", body["notifications"])
+	}
+	if status, body = call(http.MethodPost, "/me/notifications/read-all"); status != fiber.StatusOK || string(body["updated"]) != "0" {
+		t.Fatalf("mark all read: %d %v", status, body)
+	}
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageLimit reads ?limit=, falling back to the default and capping at the
+// maximum page size.
+func pageLimit(c *fiber.Ctx) int {
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		return defaultPageSize
+	}
+	if limit > maxPageSize {
+		return maxPageSize
+	}
+	return limit
+}
+
+// pageCursor is the position after the last row of a page. Lists are ordered
+// by (At, ID), optionally grouped by Group first.
+type pageCursor struct {
+	Group string    `json:"g,omitempty"`
+	At    time.Time `json:"at"`
+	ID    uuid.UUID `json:"id"`
+}
+
+func (p pageCursor) encode() string {
+	raw, _ := json.Marshal(p)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// parseCursor decodes ?cursor=. An empty value means the first page.
+func parseCursor(value string) (*pageCursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
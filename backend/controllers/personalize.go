@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/Semkufu95/confessions/Backend/relations"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Cached reads are shared by every visitor, so per-viewer changes are applied
+// to the cached JSON afterwards: content from muted or blocked users is
+// dropped and the caller's own reactions are merged in. Anonymous requests get
+// the cached body unchanged.
+
+// optionalUserID returns the caller set by middleware.OptionalAuth, if any.
+func optionalUserID(c *fiber.Ctx) (uuid.UUID, bool) {
+	raw, _ := c.Locals("user_id").(string)
+	id, err := uuid.Parse(raw)
+	return id, err == nil
+}
+
+// myReactions maps target ids to the user's reaction type. column is
+// confession_id or comment_id.
+func myReactions(userID uuid.UUID, column string, ids []string) (map[string]string, error) {
+	reactions := map[string]string{}
+	if len(ids) == 0 {
+		return reactions, nil
+	}
+
+	query := config.DB.Model(&models.Reaction{}).
+		Select(column+" AS target_id, type").
+		Where("user_id = ? AND "+column+" IN ?", userID, ids)
+	if column == "confession_id" {
+		query = query.Where("comment_id IS NULL")
+	}
+	var rows []struct {
+		TargetID string
+		Type     string
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		reactions[row.TargetID] = row.Type
+	}
+	return reactions, nil
+}
+
+func itemID(item map[string]json.RawMessage, field string) string {
+	var id string
+	_ = json.Unmarshal(item[field], &id)
+	return id
+}
+
+// addMyReactions sets "my_reaction" on every object the user reacted to.
+func addMyReactions(userID uuid.UUID, column string, items []map[string]json.RawMessage) error {
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, itemID(item, "id"))
+	}
+	reactions, err := myReactions(userID, column, ids)
+	if err != nil {
+		return err
+	}
+	for i, item := range items {
+		if reactionType, ok := reactions[itemID(item, "id")]; ok {
+			items[i]["my_reaction"], _ = json.Marshal(reactionType)
+		}
+	}
+	return nil
+}
+
+// hideComments drops comments whose author is hidden from the viewer.
+func hideComments(items []map[string]json.RawMessage, hidden map[uuid.UUID]bool) []map[string]json.RawMessage {
+	if len(hidden) == 0 {
+		return items
+	}
+	visible := items[:0]
+	for _, item := range items {
+		author, err := uuid.Parse(itemID(item, "user_id"))
+		if err == nil && hidden[author] {
+			continue
+		}
+		visible = append(visible, item)
+	}
+	return visible
+}
+
+// hideConfessions drops confessions by hidden authors. Confession JSON never
+// carries the author, so it is looked up.
+func hideConfessions(items []map[string]json.RawMessage, hidden map[uuid.UUID]bool) ([]map[string]json.RawMessage, error) {
+	if len(hidden) == 0 || len(items) == 0 {
+		return items, nil
+	}
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, itemID(item, "id"))
+	}
+	authors := make([]uuid.UUID, 0, len(hidden))
+	for id := range hidden {
+		authors = append(authors, id)
+	}
+	var hiddenIDs []string
+	if err := config.DB.Model(&models.Confession{}).
+		Where("id IN ? AND user_id IN ?", ids, authors).
+		Pluck("id", &hiddenIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(hiddenIDs) == 0 {
+		return items, nil
+	}
+	skip := make(map[string]bool, len(hiddenIDs))
+	for _, id := range hiddenIDs {
+		skip[id] = true
+	}
+	visible := items[:0]
+	for _, item := range items {
+		if !skip[itemID(item, "id")] {
+			visible = append(visible, item)
+		}
+	}
+	return visible, nil
+}
+
+// personalizeFeed applies the viewer's mutes and blocks and own reactions to
+// a cached confession list.
+func personalizeFeed(ctx context.Context, userID uuid.UUID, body []byte) ([]byte, error) {
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, err
+	}
+	hidden, err := relations.CachedHidden(ctx, config.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	if items, err = hideConfessions(items, hidden); err != nil {
+		return nil, err
+	}
+	if err := addMyReactions(userID, "confession_id", items); err != nil {
+		return nil, err
+	}
+	return json.Marshal(items)
+}
+
+// personalizeComments does the same for a cached comment list.
+func personalizeComments(ctx context.Context, userID uuid.UUID, body []byte) ([]byte, error) {
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, err
+	}
+	hidden, err := relations.CachedHidden(ctx, config.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	items = hideComments(items, hidden)
+	if err := addMyReactions(userID, "comment_id", items); err != nil {
+		return nil, err
+	}
+	return json.Marshal(items)
+}
+
+// personalizeConfessionDetail personalizes the confession and comments
+// returned by GetConfessionWithComments. A confession by a hidden author is
+// not found.
+func personalizeConfessionDetail(ctx context.Context, userID uuid.UUID, body []byte) ([]byte, error) {
+	var detail struct {
+		Confession map[string]json.RawMessage   `json:"confession"`
+		Comments   []map[string]json.RawMessage `json:"comments"`
+	}
+	if err := json.Unmarshal(body, &detail); err != nil {
+		return nil, err
+	}
+	hidden, err := relations.CachedHidden(ctx, config.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	visible, err := hideConfessions([]map[string]json.RawMessage{detail.Confession}, hidden)
+	if err != nil {
+		return nil, err
+	}
+	if len(visible) == 0 {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Confession not found")
+	}
+	detail.Comments = hideComments(detail.Comments, hidden)
+	if err := addMyReactions(userID, "confession_id", []map[string]json.RawMessage{detail.Confession}); err != nil {
+		return nil, err
+	}
+	if err := addMyReactions(userID, "comment_id", detail.Comments); err != nil {
+		return nil, err
+	}
+	return json.Marshal(detail)
+}
@@ -0,0 +1,22 @@
+package controllers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestHideComments_DropsHiddenAuthors(t *testing.T) {
+	kept, blocked := uuid.New(), uuid.New()
+	var items []map[string]json.RawMessage
+	body := `[{"id":"1","user_id":"` + kept.String() + `"},{"id":"2","user_id":"` + blocked.String() + `"},{"id":"3"}]`
+	if err := json.Unmarshal([]byte(body), &items); err != nil {
+		t.Fatal(err)
+	}
+
+	visible := hideComments(items, map[uuid.UUID]bool{blocked: true})
+	if len(visible) != 2 || itemID(visible[0], "id") != "1" || itemID(visible[1], "id") != "3" {
+		t.Fatalf("expected comments 1 and 3, got %v", visible)
+	}
+}
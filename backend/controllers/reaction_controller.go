@@ -1,28 +1,146 @@
 package controllers
 
 import (
-	"encoding/json"
 	"errors"
 	"time"
 
 	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/counters"
+	"github.com/Semkufu95/confessions/Backend/events"
 	"github.com/Semkufu95/confessions/Backend/models"
-	"github.com/Semkufu95/confessions/Backend/redis"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ReactionInput struct {
-	Type string `json:"type"` // "like" or "boo"
+	Type string `json:"type"` // one of config.App.ReactionTypes
+	// Mode is "set" (default) or "toggle". In toggle mode repeating the
+	// current reaction type removes the reaction.
+	Mode string `json:"mode"`
 }
 
-// ReactToConfession allows a user to like/boo a confession
+func (input ReactionInput) valid() bool {
+	return config.App.IsReactionType(input.Type) &&
+		(input.Mode == "" || input.Mode == "set" || input.Mode == "toggle")
+}
+
+// GetReactionTypes lists the reaction types clients may send.
+func GetReactionTypes(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"types": config.App.ReactionTypes})
+}
+
+// reactionTarget identifies what a reaction points at. predicate must match
+// the partial unique index for column so it can be an ON CONFLICT target.
+type reactionTarget struct {
+	column    string
+	predicate string
+	id        uuid.UUID
+}
+
+func confessionReactionTarget(id uuid.UUID) reactionTarget {
+	return reactionTarget{column: "confession_id", predicate: "confession_id IS NOT NULL AND comment_id IS NULL", id: id}
+}
+
+func commentReactionTarget(id uuid.UUID) reactionTarget {
+	return reactionTarget{column: "comment_id", predicate: "comment_id IS NOT NULL", id: id}
+}
+
+// reactionChange describes what upsertReaction did. An empty Previous means
+// the reaction was created; an empty Next means it was removed.
+type reactionChange struct {
+	Reaction models.Reaction
+	Previous string
+	Next     string
+}
+
+var errReactionContention = errors.New("reaction changed concurrently")
+
+// upsertReaction creates, updates or (in toggle mode) removes the user's
+// reaction on target. The insert relies on the unique index, so parallel
+// requests never create duplicates, and an existing row is locked before it
+// is read so the returned change is exact for counter updates.
+func upsertReaction(tx *gorm.DB, userID uuid.UUID, target reactionTarget, reactionType string, toggle bool) (reactionChange, error) {
+	for attempt := 0; attempt < 3; attempt++ {
+		now := time.Now()
+		reaction := models.Reaction{UserID: userID, Type: reactionType, CreatedAt: now, UpdatedAt: now}
+		if target.column == "comment_id" {
+			reaction.CommentID = uuidPtr(target.id)
+		} else {
+			reaction.ConfessionID = uuidPtr(target.id)
+		}
+
+		result := tx.Clauses(clause.OnConflict{
+			Columns:     []clause.Column{{Name: "user_id"}, {Name: target.column}},
+			TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: target.predicate}}},
+			DoNothing:   true,
+		}).Create(&reaction)
+		if result.Error != nil {
+			return reactionChange{}, result.Error
+		}
+		if result.RowsAffected == 1 {
+			return reactionChange{Reaction: reaction, Next: reactionType}, nil
+		}
+
+		var existing models.Reaction
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND "+target.column+" = ? AND "+target.predicate, userID, target.id).
+			First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Removed by a concurrent toggle between the insert and the read.
+			continue
+		}
+		if err != nil {
+			return reactionChange{}, err
+		}
+
+		change := reactionChange{Reaction: existing, Previous: existing.Type, Next: reactionType}
+		switch {
+		case existing.Type == reactionType && toggle:
+			if err := tx.Delete(&existing).Error; err != nil {
+				return reactionChange{}, err
+			}
+			change.Next = ""
+		case existing.Type != reactionType:
+			if err := tx.Model(&existing).Updates(map[string]interface{}{"type": reactionType, "updated_at": now}).Error; err != nil {
+				return reactionChange{}, err
+			}
+			change.Reaction.Type = reactionType
+		}
+		return change, nil
+	}
+	return reactionChange{}, errReactionContention
+}
+
+// requirePublished fails with 404 unless the confession or comment with id
+// is published. Held and rejected posts take no reactions.
+func requirePublished(tx *gorm.DB, model interface{}, id uuid.UUID, notFound string) error {
+	var published int64
+	if err := tx.Model(model).Scopes(models.Published).Where("id = ?", id).Count(&published).Error; err != nil {
+		return err
+	}
+	if published == 0 {
+		return fiber.NewError(fiber.StatusNotFound, notFound)
+	}
+	return nil
+}
+
+// reactionEvent is the event for a change: removed reactions announce their
+// removal, everything else the current type.
+func reactionEvent(change reactionChange, confessionID uuid.UUID, commentID *uuid.UUID) events.Event {
+	if change.Next == "" {
+		return events.ReactionRemoved{ID: change.Reaction.ID, ConfessionID: uuidPtr(confessionID), CommentID: commentID, Type: change.Previous}
+	}
+	return events.ReactionUpdated{ConfessionID: uuidPtr(confessionID), CommentID: commentID, Type: change.Next, Previous: change.Previous}
+}
+
+// ReactToConfession sets the user's reaction on a confession
 func ReactToConfession(c *fiber.Ctx) error {
 	confessionID := c.Params("id")
 	var input ReactionInput
 
-	if err := c.BodyParser(&input); err != nil || (input.Type != "like" && input.Type != "boo") {
+	if err := c.BodyParser(&input); err != nil || !input.valid() {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid reaction type"})
 	}
 
@@ -39,76 +157,54 @@ func ReactToConfession(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid confession id"})
 	}
 
-	var reaction models.Reaction
-	err = config.DB.Where("user_id = ? AND confession_id = ?", userID, parsedConfessionID).First(&reaction).Error
-
-	if err == nil {
-		// Update existing reaction
-		reaction.Type = input.Type
-		reaction.UpdatedAt = time.Now()
-		if err := config.DB.Save(&reaction).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update reaction"})
-		}
-	} else {
-		if !errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to query reaction"})
-		}
-		// Create new reaction
-		newReaction := models.Reaction{
-			UserID:       userID,
-			ConfessionID: uuidPtr(parsedConfessionID),
-			Type:         input.Type,
-			CreatedAt:    time.Now(),
-			UpdatedAt:    time.Now(),
+	var updatedConfession models.Confession
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := requirePublished(tx, &models.Confession{}, parsedConfessionID, "Confession not found"); err != nil {
+			return err
 		}
-		if err := config.DB.Create(&newReaction).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create reaction"})
+		change, err := upsertReaction(tx, userID, confessionReactionTarget(parsedConfessionID), input.Type, input.Mode == "toggle")
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to save reaction")
 		}
-		reaction = newReaction
-	}
-
-	// Recalculate aggregate likes and boos for this confession
-	var likesCount int64
-	var boosCount int64
-
-	if err := config.DB.Model(&models.Reaction{}).
-		Where("confession_id = ? AND type = ?", confessionID, "like").
-		Count(&likesCount).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to count reactions"})
-	}
 
-	if err := config.DB.Model(&models.Reaction{}).
-		Where("confession_id = ? AND type = ?", confessionID, "boo").
-		Count(&boosCount).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to count reactions"})
-	}
+		if err := counters.ApplyConfessionReaction(tx, parsedConfessionID, change.Previous, change.Next); err != nil {
+			if errors.Is(err, counters.ErrNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, "Confession not found")
+			}
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to update confession totals")
+		}
 
-	// Update confession's likes and boos count
-	if err := config.DB.Model(&models.Confession{}).
-		Where("id = ?", confessionID).
-		Updates(map[string]interface{}{"likes": likesCount, "boos": boosCount}).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update confession totals"})
-	}
+		// Fetch updated confession to return
+		if err := tx.Where("id = ?", parsedConfessionID).First(&updatedConfession).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, "Confession not found")
+			}
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to load confession")
+		}
+		updatedConfession.MyReaction = change.Next
 
-	// Fetch updated confession to return
-	var updatedConfession models.Confession
-	if err := config.DB.Where("id = ?", confessionID).First(&updatedConfession).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load confession"})
+		if change.Previous == change.Next {
+			return nil
+		}
+		if change.Previous == "" {
+			if err := notifyReaction(tx, userID, updatedConfession.UserID, parsedConfessionID, nil, change.Next); err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Failed to save reaction")
+			}
+		}
+		return events.Enqueue(tx, userIDStr, reactionEvent(change, parsedConfessionID, nil))
+	}); err != nil {
+		return respondWithError(c, err, "Failed to save reaction")
 	}
 
-	// Publish to Redis
-	data, _ := json.Marshal(fiber.Map{"confession_id": confessionID})
-	redis.Client.Publish(redis.Ctx, "confessions:reaction:updated", data)
-
 	return c.JSON(updatedConfession)
 }
 
-// ReactToComment allows a user to like/boo a comment
+// ReactToComment sets the user's reaction on a comment
 func ReactToComment(c *fiber.Ctx) error {
 	commentID := c.Params("id")
 	var input ReactionInput
 
-	if err := c.BodyParser(&input); err != nil || (input.Type != "like" && input.Type != "boo") {
+	if err := c.BodyParser(&input); err != nil || !input.valid() {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid reaction type"})
 	}
 
@@ -125,67 +221,45 @@ func ReactToComment(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid comment id"})
 	}
 
-	var reaction models.Reaction
-	err = config.DB.Where("user_id = ? AND comment_id = ?", userID, parsedCommentID).First(&reaction).Error
-
-	if err == nil {
-		// Update existing reaction
-		reaction.Type = input.Type
-		reaction.UpdatedAt = time.Now()
-		if err := config.DB.Save(&reaction).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update reaction"})
-		}
-	} else {
-		if !errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to query reaction"})
-		}
-		// Create new reaction
-		newReaction := models.Reaction{
-			UserID:    userID,
-			CommentID: uuidPtr(parsedCommentID),
-			Type:      input.Type,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
+	var updatedComment models.Comment
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := requirePublished(tx, &models.Comment{}, parsedCommentID, "Comment not found"); err != nil {
+			return err
 		}
-		if err := config.DB.Create(&newReaction).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create reaction"})
+		change, err := upsertReaction(tx, userID, commentReactionTarget(parsedCommentID), input.Type, input.Mode == "toggle")
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to save reaction")
 		}
-		reaction = newReaction
-	}
 
-	// Recalculate aggregate likes and boos for this comment
-	var likesCount int64
-	var boosCount int64
-
-	if err := config.DB.Model(&models.Reaction{}).
-		Where("comment_id = ? AND type = ?", commentID, "like").
-		Count(&likesCount).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to count reactions"})
-	}
-
-	if err := config.DB.Model(&models.Reaction{}).
-		Where("comment_id = ? AND type = ?", commentID, "boo").
-		Count(&boosCount).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to count reactions"})
-	}
+		if err := counters.ApplyCommentReaction(tx, parsedCommentID, change.Previous, change.Next); err != nil {
+			if errors.Is(err, counters.ErrNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, "Comment not found")
+			}
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to update comment totals")
+		}
 
-	// Update comment's likes and boos count
-	if err := config.DB.Model(&models.Comment{}).
-		Where("id = ?", commentID).
-		Updates(map[string]interface{}{"likes": likesCount, "boos": boosCount}).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update comment totals"})
-	}
+		// Fetch updated comment to return
+		if err := tx.Where("id = ?", parsedCommentID).First(&updatedComment).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, "Comment not found")
+			}
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to load comment")
+		}
+		updatedComment.MyReaction = change.Next
 
-	// Fetch updated comment to return
-	var updatedComment models.Comment
-	if err := config.DB.Where("id = ?", commentID).First(&updatedComment).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load comment"})
+		if change.Previous == change.Next {
+			return nil
+		}
+		if change.Previous == "" {
+			if err := notifyReaction(tx, userID, updatedComment.UserID, updatedComment.ConfessionID, uuidPtr(parsedCommentID), change.Next); err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Failed to save reaction")
+			}
+		}
+		return events.Enqueue(tx, userIDStr, reactionEvent(change, updatedComment.ConfessionID, uuidPtr(parsedCommentID)))
+	}); err != nil {
+		return respondWithError(c, err, "Failed to save reaction")
 	}
 
-	// Publish to Redis
-	data, _ := json.Marshal(fiber.Map{"comment_id": commentID})
-	redis.Client.Publish(redis.Ctx, "confessions:reaction:updated", data)
-
 	return c.JSON(updatedComment)
 }
 
@@ -206,58 +280,95 @@ func RemoveReaction(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You cannot remove this reaction"})
 	}
 
-	if err := config.DB.Delete(&reaction).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to remove reaction"})
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return deleteReaction(tx, userID, reaction)
+	}); err != nil {
+		return respondWithError(c, err, "Failed to remove reaction")
 	}
 
-	// After removal, recalc counts if this reaction was on confession or comment
+	return c.JSON(fiber.Map{"message": "Reaction removed"})
+}
+
+// deleteReaction removes reaction and reverses its counter changes.
+func deleteReaction(tx *gorm.DB, actor string, reaction models.Reaction) error {
+	// Only the request that actually deletes the row adjusts counters.
+	result := tx.Delete(&reaction)
+	if result.Error != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to remove reaction")
+	}
+	if result.RowsAffected == 0 {
+		return fiber.NewError(fiber.StatusNotFound, "Reaction not found")
+	}
 
+	// A missing parent means it was deleted already; nothing to decrement.
 	if reaction.ConfessionID != nil {
-		confessionID := reaction.ConfessionID.String()
-		var likesCount int64
-		var boosCount int64
-
-		config.DB.Model(&models.Reaction{}).
-			Where("confession_id = ? AND type = ?", confessionID, "like").
-			Count(&likesCount)
-
-		config.DB.Model(&models.Reaction{}).
-			Where("confession_id = ? AND type = ?", confessionID, "boo").
-			Count(&boosCount)
-
-		if err := config.DB.Model(&models.Confession{}).
-			Where("id = ?", confessionID).
-			Updates(map[string]interface{}{"likes": likesCount, "boos": boosCount}).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update confession totals"})
+		err := counters.ApplyConfessionReaction(tx, *reaction.ConfessionID, reaction.Type, "")
+		if err != nil && !errors.Is(err, counters.ErrNotFound) {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to update confession totals")
 		}
 	}
 
 	if reaction.CommentID != nil {
-		commentID := reaction.CommentID.String()
-		var likesCount int64
-		var boosCount int64
-
-		config.DB.Model(&models.Reaction{}).
-			Where("comment_id = ? AND type = ?", commentID, "like").
-			Count(&likesCount)
-
-		config.DB.Model(&models.Reaction{}).
-			Where("comment_id = ? AND type = ?", commentID, "boo").
-			Count(&boosCount)
-
-		if err := config.DB.Model(&models.Comment{}).
-			Where("id = ?", commentID).
-			Updates(map[string]interface{}{"likes": likesCount, "boos": boosCount}).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update comment totals"})
+		err := counters.ApplyCommentReaction(tx, *reaction.CommentID, reaction.Type, "")
+		if err != nil && !errors.Is(err, counters.ErrNotFound) {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to update comment totals")
 		}
 	}
 
-	data, _ := json.Marshal(fiber.Map{
-		"id":            id,
-		"confession_id": reaction.ConfessionID,
-		"comment_id":    reaction.CommentID,
+	// Comment reactions carry the parent confession so its caches are dropped too.
+	confessionID := reaction.ConfessionID
+	if confessionID == nil && reaction.CommentID != nil {
+		var parent models.Comment
+		if err := tx.Select("confession_id").First(&parent, "id = ?", reaction.CommentID).Error; err == nil {
+			confessionID = uuidPtr(parent.ConfessionID)
+		}
+	}
+
+	return events.Enqueue(tx, actor, events.ReactionRemoved{
+		ID:           reaction.ID,
+		ConfessionID: confessionID,
+		CommentID:    reaction.CommentID,
+		Type:         reaction.Type,
 	})
-	redis.Client.Publish(redis.Ctx, "confessions:reaction:removed", data)
+}
+
+// RemoveConfessionReaction removes the user's reaction on a confession
+// without needing the reaction id.
+func RemoveConfessionReaction(c *fiber.Ctx) error {
+	return removeTargetReaction(c, "Invalid confession id", confessionReactionTarget)
+}
+
+// RemoveCommentReaction removes the user's reaction on a comment.
+func RemoveCommentReaction(c *fiber.Ctx) error {
+	return removeTargetReaction(c, "Invalid comment id", commentReactionTarget)
+}
+
+func removeTargetReaction(c *fiber.Ctx, invalidID string, newTarget func(uuid.UUID) reactionTarget) error {
+	userID, err := authUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+	}
+	targetID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": invalidID})
+	}
+	target := newTarget(targetID)
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var reaction models.Reaction
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND "+target.column+" = ? AND "+target.predicate, userID, target.id).
+			First(&reaction).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Reaction not found")
+		}
+		if err != nil {
+			return err
+		}
+		return deleteReaction(tx, userID.String(), reaction)
+	}); err != nil {
+		return respondWithError(c, err, "Failed to remove reaction")
+	}
 
 	return c.JSON(fiber.Map{"message": "Reaction removed"})
 }
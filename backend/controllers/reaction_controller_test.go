@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/migrations"
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// setupReactionTest needs a disposable PostgreSQL database in
+// TEST_DATABASE_URL; the test is skipped without one.
+func setupReactionTest(t *testing.T, users int) (*fiber.App, models.Confession, []models.User) {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := config.OpenDB(dsn)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get sql.DB: %v", err)
+	}
+	if _, err := migrations.Up(context.Background(), sqlDB); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	config.DB = db
+
+	created := make([]models.User, users)
+	for i := range created {
+		suffix := uuid.NewString()[:8]
+		created[i] = models.User{Username: "reactor-" + suffix, Email: suffix + "@example.test", PasswordHash: "x"}
+		if err := db.Create(&created[i]).Error; err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+	confession := models.Confession{UserID: created[0].ID, Content: "concurrency test", Category: "general"}
+	if err := db.Create(&confession).Error; err != nil {
+		t.Fatalf("failed to create confession: %v", err)
+	}
+
+	t.Cleanup(func() {
+		db.Where("confession_id = ?", confession.ID).Delete(&models.Reaction{})
+		db.Delete(&confession)
+		for _, user := range created {
+			db.Delete(&user)
+		}
+		sqlDB.Close()
+	})
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if user := c.Get("X-Test-User"); user != "" {
+			c.Locals("user_id", user)
+		}
+		return c.Next()
+	})
+	app.Post("/confessions/:id/react", ReactToConfession)
+	app.Delete("/confessions/:id/react", RemoveConfessionReaction)
+	app.Get("/confessions/:id/reactions", GetConfessionReactions)
+	return app, confession, created
+}
+
+func react(t *testing.T, app *fiber.App, confessionID uuid.UUID, userID uuid.UUID, body string) int {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, "/confessions/"+confessionID.String()+"/react", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Test-User", userID.String())
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Errorf("request failed: %v", err)
+		return 0
+	}
+	return resp.StatusCode
+}
+
+func assertReactionTotals(t *testing.T, confessionID uuid.UUID, rows, likes, boos int) {
+	t.Helper()
+	var count int64
+	config.DB.Model(&models.Reaction{}).Where("confession_id = ?", confessionID).Count(&count)
+	var confession models.Confession
+	if err := config.DB.First(&confession, "id = ?", confessionID).Error; err != nil {
+		t.Fatalf("failed to reload confession: %v", err)
+	}
+	if int(count) != rows || confession.Likes != likes || confession.Boos != boos {
+		t.Fatalf("expected %d rows, %d likes, %d boos; got %d rows, %d likes, %d boos",
+			rows, likes, boos, count, confession.Likes, confession.Boos)
+	}
+}
+
+func TestReactToConfession_ParallelRequestsKeepExactCounts(t *testing.T) {
+	const users, repeats = 8, 6
+	app, confession, reactors := setupReactionTest(t, users)
+
+	hammer := func(reactionType string) {
+		var wg sync.WaitGroup
+		for _, user := range reactors {
+			for i := 0; i < repeats; i++ {
+				wg.Add(1)
+				go func(userID uuid.UUID) {
+					defer wg.Done()
+					if status := react(t, app, confession.ID, userID, fmt.Sprintf(`{"type":%q}`, reactionType)); status != fiber.StatusOK {
+						t.Errorf("expected 200, got %d", status)
+					}
+				}(user.ID)
+			}
+		}
+		wg.Wait()
+	}
+
+	hammer("like")
+	assertReactionTotals(t, confession.ID, users, users, 0)
+
+	hammer("boo")
+	assertReactionTotals(t, confession.ID, users, 0, users)
+}
+
+func TestReactToConfession_ToggleRemovesSameType(t *testing.T) {
+	app, confession, reactors := setupReactionTest(t, 1)
+	userID := reactors[0].ID
+
+	if status := react(t, app, confession.ID, userID, `{"type":"like","mode":"toggle"}`); status != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	assertReactionTotals(t, confession.ID, 1, 1, 0)
+
+	if status := react(t, app, confession.ID, userID, `{"type":"like","mode":"toggle"}`); status != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	assertReactionTotals(t, confession.ID, 0, 0, 0)
+
+	// Without toggle, repeating a reaction is a no-op.
+	react(t, app, confession.ID, userID, `{"type":"boo"}`)
+	react(t, app, confession.ID, userID, `{"type":"boo"}`)
+	assertReactionTotals(t, confession.ID, 1, 0, 1)
+}
+
+func TestReactToConfession_CountsEmojiTypes(t *testing.T) {
+	app, confession, reactors := setupReactionTest(t, 2)
+
+	react(t, app, confession.ID, reactors[0].ID, `{"type":"heart"}`)
+	react(t, app, confession.ID, reactors[1].ID, `{"type":"heart"}`)
+	react(t, app, confession.ID, reactors[1].ID, `{"type":"like"}`)
+	if status := react(t, app, confession.ID, reactors[0].ID, `{"type":"thumbs"}`); status != fiber.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown type, got %d", status)
+	}
+
+	assertReactionTotals(t, confession.ID, 2, 1, 0)
+	var reloaded models.Confession
+	if err := config.DB.First(&reloaded, "id = ?", confession.ID).Error; err != nil {
+		t.Fatalf("failed to reload confession: %v", err)
+	}
+	if len(reloaded.Reactions) != 2 || reloaded.Reactions["heart"] != 1 || reloaded.Reactions["like"] != 1 {
+		t.Fatalf("expected heart=1 like=1, got %v", reloaded.Reactions)
+	}
+}
+
+func TestReactToConfession_HeldConfessionIsNotFound(t *testing.T) {
+	app, confession, reactors := setupReactionTest(t, 1)
+	if err := config.DB.Model(&confession).Update("status", models.StatusPending).Error; err != nil {
+		t.Fatalf("failed to hold confession: %v", err)
+	}
+
+	if status := react(t, app, confession.ID, reactors[0].ID, `{"type":"like"}`); status != fiber.StatusNotFound {
+		t.Fatalf("expected 404, got %d", status)
+	}
+	assertReactionTotals(t, confession.ID, 0, 0, 0)
+
+	req, _ := http.NewRequest(http.MethodGet, "/confessions/"+confession.ID.String()+"/reactions", nil)
+	resp, err := app.Test(req, -1)
+	if err != nil || resp.StatusCode != fiber.StatusNotFound {
+		t.Fatalf("expected 404 listing a held confession's reactions: %v %v", err, resp)
+	}
+}
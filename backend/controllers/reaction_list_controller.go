@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Reactions are anonymous like confessions: listings never include user ids
+// or usernames. Each reactor gets a pseudonym that is stable on one target but
+// differs between targets, so reactions cannot be linked across posts. Only
+// the caller's own reaction carries its id.
+
+type reactionListItem struct {
+	ID        *uuid.UUID `json:"id,omitempty"`
+	Type      string     `json:"type"`
+	Reactor   string     `json:"reactor"`
+	Mine      bool       `json:"mine"`
+	ReactedAt time.Time  `json:"reacted_at"`
+}
+
+// reactorPseudonym is keyed with the server secret so it cannot be recomputed
+// from the public user ids.
+func reactorPseudonym(targetID, userID uuid.UUID) string {
+	mac := hmac.New(sha256.New, []byte(config.App.JWTSecret))
+	mac.Write(targetID[:])
+	mac.Write(userID[:])
+	return "anon-" + hex.EncodeToString(mac.Sum(nil))[:8]
+}
+
+// GetConfessionReactions lists reactions on a confession grouped by type.
+func GetConfessionReactions(c *fiber.Ctx) error {
+	return listTargetReactions(c, &models.Confession{}, "Invalid confession id", "Confession not found", confessionReactionTarget)
+}
+
+// GetCommentReactions lists reactions on a comment grouped by type.
+func GetCommentReactions(c *fiber.Ctx) error {
+	return listTargetReactions(c, &models.Comment{}, "Invalid comment id", "Comment not found", commentReactionTarget)
+}
+
+// listTargetReactions returns the per-type counts and one page of reactions
+// ordered by type, then newest first. ?type= narrows the page to one type.
+// Held and rejected targets are not found.
+func listTargetReactions(c *fiber.Ctx, model interface{}, invalidID, notFound string, newTarget func(uuid.UUID) reactionTarget) error {
+	targetID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": invalidID})
+	}
+	reactionType := c.Query("type")
+	if reactionType != "" && !config.App.IsReactionType(reactionType) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid reaction type"})
+	}
+	cursor, err := parseCursor(c.Query("cursor"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid cursor"})
+	}
+	limit := pageLimit(c)
+
+	var counts struct {
+		ReactionCounts models.ReactionCounts
+	}
+	if err := config.DB.Model(model).Scopes(models.Published).Select("reaction_counts").Where("id = ?", targetID).Take(&counts).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": notFound})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load reactions"})
+	}
+
+	target := newTarget(targetID)
+	query := config.DB.Where(target.column+" = ? AND "+target.predicate, target.id)
+	if reactionType != "" {
+		query = query.Where("type = ?", reactionType)
+	}
+	if cursor != nil {
+		query = query.Where("type > ? OR (type = ? AND (updated_at, id) < (?, ?))", cursor.Group, cursor.Group, cursor.At, cursor.ID)
+	}
+	var reactions []models.Reaction
+	if err := query.Order("type ASC, updated_at DESC, id DESC").Limit(limit + 1).Find(&reactions).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load reactions"})
+	}
+
+	nextCursor := ""
+	if len(reactions) > limit {
+		reactions = reactions[:limit]
+		last := reactions[limit-1]
+		nextCursor = pageCursor{Group: last.Type, At: last.UpdatedAt, ID: last.ID}.encode()
+	}
+
+	viewerID, signedIn := optionalUserID(c)
+	items := make([]reactionListItem, 0, len(reactions))
+	for _, reaction := range reactions {
+		item := reactionListItem{
+			Type:      reaction.Type,
+			Reactor:   reactorPseudonym(targetID, reaction.UserID),
+			ReactedAt: reaction.UpdatedAt,
+		}
+		if signedIn && reaction.UserID == viewerID {
+			item.ID = uuidPtr(reaction.ID)
+			item.Mine = true
+		}
+		items = append(items, item)
+	}
+
+	return c.JSON(fiber.Map{
+		"counts":      counts.ReactionCounts,
+		"reactions":   items,
+		"next_cursor": nextCursor,
+	})
+}
+
+// GetMyReactions lists the caller's reactions, newest first.
+func GetMyReactions(c *fiber.Ctx) error {
+	userID, err := authUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+	}
+	reactionType := c.Query("type")
+	if reactionType != "" && !config.App.IsReactionType(reactionType) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid reaction type"})
+	}
+	cursor, err := parseCursor(c.Query("cursor"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid cursor"})
+	}
+	limit := pageLimit(c)
+
+	query := config.DB.Where("user_id = ?", userID)
+	if reactionType != "" {
+		query = query.Where("type = ?", reactionType)
+	}
+	if cursor != nil {
+		query = query.Where("(updated_at, id) < (?, ?)", cursor.At, cursor.ID)
+	}
+	var reactions []models.Reaction
+	if err := query.Order("updated_at DESC, id DESC").Limit(limit + 1).Find(&reactions).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load reactions"})
+	}
+
+	nextCursor := ""
+	if len(reactions) > limit {
+		reactions = reactions[:limit]
+		last := reactions[limit-1]
+		nextCursor = pageCursor{At: last.UpdatedAt, ID: last.ID}.encode()
+	}
+
+	return c.JSON(fiber.Map{
+		"reactions":   reactions,
+		"next_cursor": nextCursor,
+	})
+}
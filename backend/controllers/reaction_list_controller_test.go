@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestPageCursor_RoundTrip(t *testing.T) {
+	want := pageCursor{Group: "heart", At: time.Date(2024, 5, 1, 12, 0, 0, 123456000, time.UTC), ID: uuid.New()}
+	got, err := parseCursor(want.encode())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Group != want.Group || !got.At.Equal(want.At) || got.ID != want.ID {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	if _, err := parseCursor("not a cursor"); err == nil {
+		t.Fatal("expected an error for a malformed cursor")
+	}
+}
+
+func TestReactorPseudonym_DiffersPerTarget(t *testing.T) {
+	config.App.JWTSecret = "test-secret"
+	user, first, second := uuid.New(), uuid.New(), uuid.New()
+	if reactorPseudonym(first, user) != reactorPseudonym(first, user) {
+		t.Fatal("expected a stable pseudonym on one target")
+	}
+	if reactorPseudonym(first, user) == reactorPseudonym(second, user) {
+		t.Fatal("expected pseudonyms to differ between targets")
+	}
+}
+
+func TestListReactions_MalformedIDIsBadRequest(t *testing.T) {
+	app := fiber.New()
+	app.Get("/confessions/:id/reactions", GetConfessionReactions)
+	app.Get("/comments/:id/reactions", GetCommentReactions)
+
+	for path, want := range map[string]string{
+		"/confessions/not-a-uuid/reactions": "Invalid confession id",
+		"/comments/not-a-uuid/reactions":    "Invalid comment id",
+	} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var body struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		if resp.StatusCode != fiber.StatusBadRequest || body.Error != want {
+			t.Fatalf("%s: expected 400 %q, got %d %q", path, want, resp.StatusCode, body.Error)
+		}
+	}
+}
+
+func TestConfessionReactions_ListAndRemoveByTarget(t *testing.T) {
+	app, confession, reactors := setupReactionTest(t, 3)
+	react(t, app, confession.ID, reactors[0].ID, `{"type":"heart"}`)
+	react(t, app, confession.ID, reactors[1].ID, `{"type":"heart"}`)
+	react(t, app, confession.ID, reactors[2].ID, `{"type":"wow"}`)
+
+	var page struct {
+		Counts    map[string]int     `json:"counts"`
+		Reactions []reactionListItem `json:"reactions"`
+		Next      string             `json:"next_cursor"`
+	}
+	seen := 0
+	cursor := ""
+	for {
+		req, _ := http.NewRequest(http.MethodGet, "/confessions/"+confession.ID.String()+"/reactions?limit=2&cursor="+cursor, nil)
+		req.Header.Set("X-Test-User", reactors[0].ID.String())
+		resp, err := app.Test(req, -1)
+		if err != nil || resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("list failed: %v %v", err, resp)
+		}
+		page.Next = ""
+		if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+			t.Fatalf("decode failed: %v", err)
+		}
+		for _, item := range page.Reactions {
+			if item.Mine != (item.ID != nil) {
+				t.Fatalf("only the caller's reaction should carry an id: %+v", item)
+			}
+		}
+		seen += len(page.Reactions)
+		if page.Next == "" {
+			break
+		}
+		cursor = page.Next
+	}
+	if seen != 3 || page.Counts["heart"] != 2 || page.Counts["wow"] != 1 {
+		t.Fatalf("expected 3 reactions (heart=2, wow=1), saw %d with counts %v", seen, page.Counts)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, "/confessions/"+confession.ID.String()+"/react", nil)
+	req.Header.Set("X-Test-User", reactors[2].ID.String())
+	resp, err := app.Test(req, -1)
+	if err != nil || resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("remove failed: %v %v", err, resp)
+	}
+	resp, _ = app.Test(req, -1)
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Fatalf("expected 404 on second remove, got %d", resp.StatusCode)
+	}
+}
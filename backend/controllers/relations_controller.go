@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/Semkufu95/confessions/Backend/redis"
+	"github.com/Semkufu95/confessions/Backend/relations"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// relationKind describes the blocks and mutes tables, which share one set of
+// handlers.
+type relationKind struct {
+	verb   string
+	past   string
+	model  interface{}
+	table  string
+	column string // the other user's column
+	// mutual relations also change what the other user sees.
+	mutual bool
+	newRow func(userID, otherID uuid.UUID) interface{}
+}
+
+var (
+	blockRelation = relationKind{
+		verb:   "block",
+		past:   "blocked",
+		model:  &models.UserBlock{},
+		table:  "user_blocks",
+		column: "blocked_user_id",
+		mutual: true,
+		newRow: func(userID, otherID uuid.UUID) interface{} {
+			return &models.UserBlock{UserID: userID, BlockedUserID: otherID, CreatedAt: time.Now()}
+		},
+	}
+	muteRelation = relationKind{
+		verb:   "mute",
+		past:   "muted",
+		model:  &models.UserMute{},
+		table:  "user_mutes",
+		column: "muted_user_id",
+		newRow: func(userID, otherID uuid.UUID) interface{} {
+			return &models.UserMute{UserID: userID, MutedUserID: otherID, CreatedAt: time.Now()}
+		},
+	}
+)
+
+type relatedUserResponse struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func GetMyBlocks(c *fiber.Ctx) error { return listRelations(c, blockRelation) }
+func BlockUser(c *fiber.Ctx) error   { return addRelation(c, blockRelation) }
+func UnblockUser(c *fiber.Ctx) error { return removeRelation(c, blockRelation) }
+func GetMyMutes(c *fiber.Ctx) error  { return listRelations(c, muteRelation) }
+func MuteUser(c *fiber.Ctx) error    { return addRelation(c, muteRelation) }
+func UnmuteUser(c *fiber.Ctx) error  { return removeRelation(c, muteRelation) }
+
+func listRelations(c *fiber.Ctx, kind relationKind) error {
+	userID, err := authUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+	}
+
+	response := []relatedUserResponse{}
+	if err := config.DB.Table(kind.table+" AS r").
+		Select("r."+kind.column+" AS user_id, u.username, r.created_at").
+		Joins("JOIN users u ON u.id = r."+kind.column).
+		Where("r.user_id = ?", userID).
+		Order("r.created_at DESC").
+		Scan(&response).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load " + kind.past + " users"})
+	}
+	return c.JSON(response)
+}
+
+func addRelation(c *fiber.Ctx, kind relationKind) error {
+	userID, err := authUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+	}
+	var input struct {
+		UserID string `json:"user_id"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	otherID, err := uuid.Parse(input.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user id"})
+	}
+	if otherID == userID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "You cannot " + kind.verb + " yourself"})
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var other models.User
+		if err := tx.Select("id").First(&other, "id = ?", otherID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, "User not found")
+			}
+			return err
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(kind.newRow(userID, otherID)).Error; err != nil {
+			return err
+		}
+		if !kind.mutual {
+			return nil
+		}
+		// Pending requests between the two would otherwise stay actionable.
+		return tx.Model(&models.ConnectionRequest{}).
+			Where("status = ? AND ((sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?))",
+				"pending", userID, otherID, otherID, userID).
+			Update("status", "declined").Error
+	}); err != nil {
+		return respondWithError(c, err, "Failed to "+kind.verb+" user")
+	}
+
+	relationsChanged(c, kind, userID, otherID)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"user_id": otherID})
+}
+
+func removeRelation(c *fiber.Ctx, kind relationKind) error {
+	userID, err := authUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+	}
+	otherID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user id"})
+	}
+
+	result := config.DB.Where("user_id = ? AND "+kind.column+" = ?", userID, otherID).Delete(kind.model)
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to un" + kind.verb + " user"})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User is not " + kind.past})
+	}
+
+	relationsChanged(c, kind, userID, otherID)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// relationsChanged drops cached views that depend on the relation.
+func relationsChanged(c *fiber.Ctx, kind relationKind, userID, otherID uuid.UUID) {
+	relations.Invalidate(userID, otherID)
+	redis.InvalidateForYou(c.UserContext(), userID.String())
+	if kind.mutual {
+		redis.InvalidateForYou(c.UserContext(), otherID.String())
+	}
+}
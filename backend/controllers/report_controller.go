@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReportConfession flags a published confession for the moderators.
+func ReportConfession(c *fiber.Ctx) error {
+	return reportPost(c, "confessions", "confession", "Confession not found", func(report *models.Report, id uuid.UUID) {
+		report.ConfessionID = &id
+	})
+}
+
+// ReportComment flags a published comment for the moderators.
+func ReportComment(c *fiber.Ctx) error {
+	return reportPost(c, "comments", "comment", "Comment not found", func(report *models.Report, id uuid.UUID) {
+		report.CommentID = &id
+	})
+}
+
+// reportPost stores one report per reporter and post; repeating it is a
+// no-op. Reports count against the author in low-trust pre-moderation.
+func reportPost(c *fiber.Ctx, table, name, notFound string, attach func(*models.Report, uuid.UUID)) error {
+	reporterID, err := authUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+	}
+	targetID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": notFound})
+	}
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	input.Reason = strings.TrimSpace(input.Reason)
+	if input.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Reason is required"})
+	}
+	if len(input.Reason) > 500 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Reason must be 500 characters or less"})
+	}
+
+	var post struct {
+		UserID uuid.UUID
+	}
+	if err := config.DB.Table(table).Select("user_id").
+		Where("id = ? AND status = ?", targetID, models.StatusPublished).
+		Take(&post).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": notFound})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to report " + name})
+	}
+	if post.UserID == reporterID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "You cannot report your own " + name})
+	}
+
+	report := models.Report{ReporterID: reporterID, UserID: post.UserID, Reason: input.Reason, CreatedAt: time.Now()}
+	attach(&report, targetID)
+	if err := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&report).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to report " + name})
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"message": "Report received"})
+}
@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/reputation"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// GetMyReputation returns the caller's reputation score, tier and the
+// signals behind them.
+func GetMyReputation(c *fiber.Ctx) error {
+	userID, err := authUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+	}
+	mine, err := reputation.Get(c.UserContext(), config.DB, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load reputation"})
+	}
+
+	signals := mine.Signals
+	return c.JSON(fiber.Map{
+		"score": mine.Score,
+		"tier":  mine.Tier,
+		"signals": fiber.Map{
+			"account_age_days":   int(signals.AccountAge.Hours() / 24),
+			"email_verified":     signals.EmailVerified,
+			"reactions_received": signals.ReactionsReceived,
+			"upheld_reports":     signals.UpheldReports,
+			"requests_answered":  signals.RequestsAnswered,
+			"requests_accepted":  signals.RequestsAccepted,
+			"acceptance_rate":    reputation.AcceptanceRate(signals),
+		},
+	})
+}
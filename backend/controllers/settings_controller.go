@@ -5,6 +5,7 @@ import (
 
 	"github.com/Semkufu95/confessions/Backend/config"
 	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/Semkufu95/confessions/Backend/redis"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -15,6 +16,8 @@ type updateSettingsInput struct {
 	EmailNotifications *bool `json:"emailNotifications"`
 	CommentReplies     *bool `json:"commentReplies"`
 	NewFollowers       *bool `json:"newFollowers"`
+	// MutedCategories replaces the muted list when present.
+	MutedCategories *[]string `json:"mutedCategories"`
 }
 
 func GetMySettings(c *fiber.Ctx) error {
@@ -59,10 +62,28 @@ func UpdateMySettings(c *fiber.Ctx) error {
 	if input.NewFollowers != nil {
 		settings.NewFollowers = *input.NewFollowers
 	}
+	if input.MutedCategories != nil {
+		muted := models.StringList{}
+		seen := map[string]bool{}
+		for _, raw := range *input.MutedCategories {
+			category, ok := normalizeConfessionCategory(raw)
+			if !ok || category == "" {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid category"})
+			}
+			if !seen[category] {
+				seen[category] = true
+				muted = append(muted, category)
+			}
+		}
+		settings.MutedCategories = muted
+	}
 
 	if err := config.DB.Save(&settings).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update settings"})
 	}
+	if input.MutedCategories != nil {
+		redis.InvalidateForYou(c.UserContext(), userID.String())
+	}
 
 	return c.JSON(settingsResponse(settings))
 }
@@ -77,13 +98,7 @@ func ensureUserSettings(userID uuid.UUID) (models.UserSettings, error) {
 		return models.UserSettings{}, err
 	}
 
-	settings = models.UserSettings{
-		UserID:             userID,
-		PushNotifications:  true,
-		EmailNotifications: false,
-		CommentReplies:     true,
-		NewFollowers:       false,
-	}
+	settings = models.DefaultUserSettings(userID)
 	if createErr := config.DB.Create(&settings).Error; createErr != nil {
 		return models.UserSettings{}, createErr
 	}
@@ -98,12 +113,20 @@ func authUserID(c *fiber.Ctx) (uuid.UUID, error) {
 	return uuid.Parse(userIDStr)
 }
 
+// actorID returns the authenticated user for event envelopes, or "" on
+// public routes.
+func actorID(c *fiber.Ctx) string {
+	userID, _ := c.Locals("user_id").(string)
+	return userID
+}
+
 func settingsResponse(settings models.UserSettings) fiber.Map {
 	return fiber.Map{
 		"pushNotifications":  settings.PushNotifications,
 		"emailNotifications": settings.EmailNotifications,
 		"commentReplies":     settings.CommentReplies,
 		"newFollowers":       settings.NewFollowers,
+		"mutedCategories":    settings.MutedCategories,
 		"updatedAt":          settings.UpdatedAt,
 	}
 }
@@ -0,0 +1,312 @@
+package controllers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/counters"
+	"github.com/Semkufu95/confessions/Backend/events"
+	"github.com/Semkufu95/confessions/Backend/middleware"
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// shareChannels are the channels clients can attribute a share to.
+var shareChannels = []string{"copy_link", "whatsapp", "x", "facebook", "instagram", "telegram", "email", "sms", "other"}
+
+func validShareChannel(channel string) bool {
+	for _, known := range shareChannels {
+		if channel == known {
+			return true
+		}
+	}
+	return false
+}
+
+// clientFingerprint identifies who shared or opened a link without storing
+// it: a keyed hash of the user id or, for anonymous callers, of the client
+// id in their cookie. Clients that have not sent one back yet fall back to
+// their network. Headers are left out because clients can change them
+// freely.
+func clientFingerprint(c *fiber.Ctx) string {
+	subject := "ip:" + clientNetwork(middleware.ClientIP(c))
+	if userID, ok := optionalUserID(c); ok {
+		subject = "user:" + userID.String()
+	} else if clientID := anonymousClientID(c); clientID != "" {
+		subject = "client:" + clientID
+	}
+	key := sha256.Sum256([]byte("share-fingerprint:" + config.App.JWTSecret))
+	mac := hmac.New(sha256.New, key[:])
+	mac.Write([]byte(subject))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// shareClientCookie tells anonymous clients behind one address, such as a
+// carrier NAT, apart. It holds a random id signed with the server secret so
+// clients cannot pick ids, only collect ones they were issued.
+const shareClientCookie = "share_client"
+
+func signClientID(id string) string {
+	key := sha256.Sum256([]byte("share-client:" + config.App.JWTSecret))
+	mac := hmac.New(sha256.New, key[:])
+	mac.Write([]byte(id))
+	return id + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+// anonymousClientID returns the client id from a correctly signed cookie.
+// Without one it issues a cookie for later requests and returns "", so a
+// client that never sends cookies back keeps one fingerprint.
+func anonymousClientID(c *fiber.Ctx) string {
+	if cookie := c.Cookies(shareClientCookie); cookie != "" {
+		id, _, _ := strings.Cut(cookie, ".")
+		if hmac.Equal([]byte(signClientID(id)), []byte(cookie)) {
+			return id
+		}
+	}
+
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return ""
+	}
+	secure := c.Protocol() == "https"
+	sameSite := fiber.CookieSameSiteLaxMode
+	if secure {
+		// The web client calls the API cross-site.
+		sameSite = fiber.CookieSameSiteNoneMode
+	}
+	c.Cookie(&fiber.Cookie{
+		Name:     shareClientCookie,
+		Value:    signClientID(hex.EncodeToString(raw)),
+		Path:     "/",
+		MaxAge:   int((365 * 24 * time.Hour).Seconds()),
+		Secure:   secure,
+		HTTPOnly: true,
+		SameSite: sameSite,
+	})
+	return ""
+}
+
+// clientNetwork is an IPv4 address as is, or the /64 of an IPv6 address:
+// one IPv6 client usually holds a whole /64.
+func clientNetwork(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || addr.Unmap().Is4() {
+		return ip
+	}
+	prefix, err := addr.Prefix(64)
+	if err != nil {
+		return ip
+	}
+	return prefix.String()
+}
+
+const shareCodeAlphabet = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// newShareCode returns an unused 8-character code, avoiding look-alike
+// characters.
+func newShareCode(tx *gorm.DB) (string, error) {
+	for attempt := 0; attempt < 5; attempt++ {
+		code := make([]byte, 8)
+		for i := range code {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(shareCodeAlphabet))))
+			if err != nil {
+				return "", err
+			}
+			code[i] = shareCodeAlphabet[n.Int64()]
+		}
+		var taken int64
+		if err := tx.Model(&models.Share{}).Where("code = ?", string(code)).Count(&taken).Error; err != nil {
+			return "", err
+		}
+		if taken == 0 {
+			return string(code), nil
+		}
+	}
+	return "", errors.New("no free share code")
+}
+
+// ShareConfession records a share of a published confession on the channel
+// given as {"channel": "whatsapp"} and returns that channel's link: each
+// person gets one code per channel, so clicks are attributed to the channel
+// they came from. The share counter moves at most once per person and
+// confession within config.App.ShareDedupWindow, whatever the channel.
+func ShareConfession(c *fiber.Ctx) error {
+	var input struct {
+		Channel string `json:"channel"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&input); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+		}
+	}
+	channel := strings.ToLower(strings.TrimSpace(input.Channel))
+	if channel == "" {
+		channel = "other"
+	}
+	if !validShareChannel(channel) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "channel must be one of " + strings.Join(shareChannels, ", ")})
+	}
+
+	var confession models.Confession
+	if err := config.DB.Scopes(models.Published).First(&confession, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Confession not found"})
+	}
+
+	fingerprint := clientFingerprint(c)
+	var share models.Share
+	counted := false
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Concurrent shares by the same person must not both count.
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "share:"+confession.ID.String()+":"+fingerprint).Error; err != nil {
+			return err
+		}
+		err := tx.Where("confession_id = ? AND fingerprint = ? AND channel = ?", confession.ID, fingerprint, channel).
+			First(&share).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err != nil {
+			code, err := newShareCode(tx)
+			if err != nil {
+				return err
+			}
+			share = models.Share{ConfessionID: confession.ID, Channel: channel, Fingerprint: fingerprint, Code: code}
+			if userID, ok := optionalUserID(c); ok {
+				share.UserID = &userID
+			}
+			if err := tx.Create(&share).Error; err != nil {
+				return err
+			}
+		}
+
+		// Count the person once per window, whatever the channel.
+
+		now := time.Now()
+		var recent int64
+		if err := tx.Model(&models.Share{}).
+			Where("confession_id = ? AND fingerprint = ? AND counted_at > ?", confession.ID, fingerprint, now.Add(-config.App.ShareDedupWindow)).
+			Count(&recent).Error; err != nil {
+			return err
+		}
+		if recent > 0 {
+			return nil
+		}
+		if err := tx.Model(&share).UpdateColumn("counted_at", now).Error; err != nil {
+			return err
+		}
+		if err := counters.IncrementShares(tx, confession.ID); err != nil {
+			return err
+		}
+		if err := tx.First(&confession, "id = ?", confession.ID).Error; err != nil {
+			return err
+		}
+		counted = true
+		return events.Enqueue(tx, actorID(c), events.ConfessionUpdated{ConfessionPayload: events.NewConfessionPayload(confession)})
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to share confession"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":    "Confession shared",
+		"share_url":  shortLink(c, share.Code),
+		"code":       share.Code,
+		"channel":    share.Channel,
+		"counted":    counted,
+		"confession": confession,
+	})
+}
+
+// resolveShare looks up a share link of a published confession and, when
+// count allows it, counts the click once per person. count is only called
+// once the link is found.
+func resolveShare(c *fiber.Ctx, code string, count func() bool) (models.Share, error) {
+	var share models.Share
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Joins("JOIN confessions ON confessions.id = shares.confession_id AND confessions.status = ?", models.StatusPublished).
+			First(&share, "shares.code = ?", code).Error; err != nil {
+			return err
+		}
+		if !count() {
+			return nil
+		}
+		click := models.ShareClick{ShareID: share.ID, Fingerprint: clientFingerprint(c)}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&click)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(&share).UpdateColumn("clicks", gorm.Expr("clicks + 1")).Error
+	})
+	return share, err
+}
+
+// ResolveShare counts a click on a share link and returns where it leads.
+// Its route is rate limited (ratelimit.ShareClicks).
+func ResolveShare(c *fiber.Ctx) error {
+	share, err := resolveShare(c, c.Params("code"), func() bool { return true })
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Share link not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to resolve share link"})
+	}
+	return c.JSON(fiber.Map{
+		"code":          share.Code,
+		"channel":       share.Channel,
+		"confession_id": share.ConfessionID,
+		"url":           resolveFrontendBaseURL(c) + "/confession/" + share.ConfessionID.String(),
+	})
+}
+
+type shareChannelStats struct {
+	Channel string `json:"channel"`
+	Shares  int64  `json:"shares"`
+	Clicks  int64  `json:"clicks"`
+}
+
+// GetConfessionShares breaks the author's confession's shares and link
+// clicks down by channel.
+func GetConfessionShares(c *fiber.Ctx) error {
+	userID, err := authUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+	}
+	var confession models.Confession
+	if err := config.DB.First(&confession, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Confession not found"})
+	}
+	if confession.UserID != userID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You cannot view shares of this confession"})
+	}
+
+	channels := []shareChannelStats{}
+	if err := config.DB.Model(&models.Share{}).
+		Select("channel, count(*) AS shares, COALESCE(sum(clicks), 0) AS clicks").
+		Where("confession_id = ?", confession.ID).
+		Group("channel").Order("shares DESC, channel ASC").
+		Scan(&channels).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load shares"})
+	}
+
+	var tracked, clicks int64
+	for _, channel := range channels {
+		tracked += channel.Shares
+		clicks += channel.Clicks
+	}
+	return c.JSON(fiber.Map{
+		"confession_id":  confession.ID,
+		"shares":         confession.Shares,
+		"tracked_shares": tracked,
+		"clicks":         clicks,
+		"channels":       channels,
+	})
+}
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestShareConfession_DedupClicksAndBreakdown(t *testing.T) {
+	_, confession, users := setupReactionTest(t, 2)
+	author, other := users[0], users[1]
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if user := c.Get("X-Test-User"); user != "" {
+			c.Locals("user_id", user)
+		}
+		return c.Next()
+	})
+	app.Post("/confessions/:id/share", ShareConfession)
+	app.Get("/shares/:code", ResolveShare)
+	app.Get("/confessions/:id/shares", GetConfessionShares)
+
+	call := func(method, path, user, body string) (int, map[string]interface{}) {
+		t.Helper()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if user != "" {
+			req.Header.Set("X-Test-User", user)
+		}
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Errorf("request failed: %v", err)
+			return 0, nil
+		}
+		var decoded map[string]interface{}
+		_ = json.NewDecoder(resp.Body).Decode(&decoded)
+		return resp.StatusCode, decoded
+	}
+	sharePath := "/confessions/" + confession.ID.String() + "/share"
+
+	// Hammering the endpoint from one client counts one share.
+	var wg sync.WaitGroup
+	codes := make([]string, 10)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if status, body := call(http.MethodPost, sharePath, "", ""); status == fiber.StatusOK {
+				codes[i], _ = body["code"].(string)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, code := range codes {
+		if code == "" || code != codes[0] {
+			t.Fatalf("expected every repeated share to return one link, got %v", codes)
+		}
+	}
+	status, body := call(http.MethodPost, sharePath, "", `{"channel":"WhatsApp"}`)
+	whatsapp, _ := body["code"].(string)
+	if status != fiber.StatusOK || body["counted"] != false || body["channel"] != "whatsapp" || whatsapp == "" || whatsapp == codes[0] {
+		t.Fatalf("expected a share on another channel to get its own link without counting, got %d %v", status, body)
+	}
+	if _, body := call(http.MethodPost, sharePath, "", `{"channel":"whatsapp"}`); body["code"] != whatsapp {
+		t.Fatalf("expected a repeat on a channel to return its link, got %v", body)
+	}
+	if status, body := call(http.MethodPost, sharePath, other.ID.String(), `{"channel":"WhatsApp"}`); status != fiber.StatusOK || body["counted"] != true {
+		t.Fatalf("expected another person's share to count, got %d %v", status, body)
+	}
+	if status, _ := call(http.MethodPost, sharePath, "", `{"channel":"carrier-pigeon"}`); status != fiber.StatusBadRequest {
+		t.Fatalf("expected an unknown channel to be rejected, got %d", status)
+	}
+	var reloaded models.Confession
+	config.DB.First(&reloaded, "id = ?", confession.ID)
+	if reloaded.Shares != 2 {
+		t.Fatalf("expected 2 shares, got %d", reloaded.Shares)
+	}
+
+	// Clicks count once per person.
+	for _, user := range []string{"", "", other.ID.String()} {
+		if status, body := call(http.MethodGet, "/shares/"+codes[0], user, ""); status != fiber.StatusOK || body["confession_id"] != confession.ID.String() {
+			t.Fatalf("expected the link to resolve, got %d %v", status, body)
+		}
+	}
+	if status, _ := call(http.MethodGet, "/shares/nope", "", ""); status != fiber.StatusNotFound {
+		t.Fatalf("expected an unknown code to 404, got %d", status)
+	}
+
+	statsPath := "/confessions/" + confession.ID.String() + "/shares"
+	if status, _ := call(http.MethodGet, statsPath, other.ID.String(), ""); status != fiber.StatusForbidden {
+		t.Fatalf("expected other users to be refused, got %d", status)
+	}
+	status, stats := call(http.MethodGet, statsPath, author.ID.String(), "")
+	if status != fiber.StatusOK || stats["tracked_shares"] != float64(3) || stats["clicks"] != float64(2) {
+		t.Fatalf("unexpected breakdown %d %v", status, stats)
+	}
+	channels, _ := stats["channels"].([]interface{})
+	if len(channels) != 2 {
+		t.Fatalf("expected two channels, got %v", stats["channels"])
+	}
+}
+
+func TestClientNetwork(t *testing.T) {
+	cases := map[string]string{
+		"203.0.113.7":          "203.0.113.7",
+		"2001:db8:1:2:3:4:5:6": "2001:db8:1:2::/64",
+		"2001:db8:1:2:ffff::1": "2001:db8:1:2::/64",
+		"::ffff:203.0.113.7":   "::ffff:203.0.113.7",
+		"not an address":       "not an address",
+	}
+	for ip, want := range cases {
+		if got := clientNetwork(ip); got != want {
+			t.Errorf("clientNetwork(%q) = %q, want %q", ip, got, want)
+		}
+	}
+}
+
+func TestClientFingerprint_TellsAnonymousClientsApart(t *testing.T) {
+	config.App.JWTSecret = "test-secret"
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(clientFingerprint(c))
+	})
+	fingerprint := func(cookie string) (string, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		if cookie != "" {
+			req.Header.Set("Cookie", shareClientCookie+"="+cookie)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		issued := ""
+		for _, c := range resp.Cookies() {
+			if c.Name == shareClientCookie {
+				issued = c.Value
+			}
+		}
+		return string(body), issued
+	}
+
+	// Without a cookie both clients fall back to their shared address and
+	// are issued their own cookies.
+	network, first := fingerprint("")
+	again, second := fingerprint("")
+	if network != again || first == "" || second == "" || first == second {
+		t.Fatalf("expected one network fingerprint and two cookies, got %q %q / %q %q", network, again, first, second)
+	}
+
+	one, reissued := fingerprint(first)
+	two, _ := fingerprint(second)
+	if one == network || two == network || one == two || reissued != "" {
+		t.Fatalf("expected cookies to tell the clients apart, got %q %q (network %q)", one, two, network)
+	}
+	if repeat, _ := fingerprint(first); repeat != one {
+		t.Fatal("expected a client's fingerprint to be stable")
+	}
+	if forged, _ := fingerprint("chosen.0123"); forged != network {
+		t.Fatalf("expected a forged cookie to fall back to the network, got %q", forged)
+	}
+}
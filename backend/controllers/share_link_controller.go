@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Semkufu95/confessions/Backend/cards"
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/middleware"
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/Semkufu95/confessions/Backend/ratelimit"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// linkPreviewAgents are substrings of the user agents chat apps and social
+// networks use to fetch link previews. They get the preview page instead of
+// a redirect, and their fetches are not counted as clicks.
+var linkPreviewAgents = []string{
+	"facebookexternalhit", "facebot", "twitterbot", "whatsapp", "telegrambot",
+	"slackbot", "discordbot", "linkedinbot", "skypeuripreview", "pinterest",
+	"redditbot", "applebot", "googlebot", "bingbot", "embedly", "iframely",
+	"vkshare", "snapchat", "viber", "signal",
+}
+
+func isLinkPreviewAgent(userAgent string) bool {
+	userAgent = strings.ToLower(userAgent)
+	for _, agent := range linkPreviewAgents {
+		if strings.Contains(userAgent, agent) {
+			return true
+		}
+	}
+	return false
+}
+
+// shortLink is the public short URL of a share.
+func shortLink(c *fiber.Ctx, code string) string {
+	return resolveFrontendBaseURL(c) + "/s/" + code
+}
+
+// sharedConfession loads the published confession behind a share code
+// without counting a click.
+func sharedConfession(code string) (models.Share, models.Confession, error) {
+	var share models.Share
+	var confession models.Confession
+	if err := config.DB.First(&share, "code = ?", code).Error; err != nil {
+		return share, confession, err
+	}
+	err := config.DB.Scopes(models.Published).First(&confession, "id = ?", share.ConfessionID).Error
+	return share, confession, err
+}
+
+var sharePreviewPage = template.Must(template.New("share").Parse(`<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="description" content="{{.Description}}">
+{{- if .Found}}
+<meta property="og:type" content="article">
+<meta property="og:site_name" content="Confessions Africa">
+<meta property="og:title" content="{{.Title}}">
+<meta property="og:description" content="{{.Description}}">
+<meta property="og:url" content="{{.URL}}">
+<meta property="og:image" content="{{.Image}}">
+<meta property="og:image:type" content="image/png">
+<meta property="og:image:width" content="{{.Width}}">
+<meta property="og:image:height" content="{{.Height}}">
+<meta name="twitter:card" content="summary_large_image">
+<meta name="twitter:title" content="{{.Title}}">
+<meta name="twitter:description" content="{{.Description}}">
+<meta name="twitter:image" content="{{.Image}}">
+<link rel="canonical" href="{{.Target}}">
+<meta http-equiv="refresh" content="0; url={{.Target}}">
+{{- else}}
+<meta name="robots" content="noindex">
+{{- end}}
+</head>
+<body>
+{{- if .Found}}
+<p>{{.Description}}</p>
+<p><a href="{{.Target}}">Read the confession</a></p>
+{{- else}}
+<p>This confession is no longer available.</p>
+<p><a href="{{.Target}}">Browse confessions</a></p>
+{{- end}}
+</body>
+</html>
+`))
+
+type sharePreview struct {
+	Found       bool
+	Title       string
+	Description string
+	URL         string
+	Target      string
+	Image       string
+	Width       int
+	Height      int
+}
+
+// excerpt collapses whitespace in content and shortens it to at most limit
+// characters, cutting at a word where possible.
+func excerpt(content string, limit int) string {
+	content = strings.Join(strings.Fields(content), " ")
+	if utf8.RuneCountInString(content) <= limit {
+		return content
+	}
+	runes := []rune(content)[:limit]
+	if cut := strings.LastIndexByte(string(runes), ' '); cut > limit/2 {
+		return string(runes)[:cut] + "…"
+	}
+	return string(runes) + "…"
+}
+
+func renderSharePreview(c *fiber.Ctx, status int, preview sharePreview) error {
+	var body bytes.Buffer
+	if err := sharePreviewPage.Execute(&body, preview); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to render preview")
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Status(status).Send(body.Bytes())
+}
+
+// FollowShortLink sends people who open a /s/:code link on to the
+// confession, counting the click, and answers link preview crawlers with a
+// page of Open Graph tags instead. Links to hidden or deleted confessions
+// get a 404 page. Clicks beyond the caller's ratelimit.ShareClicks budget
+// are still redirected but not counted; only clicks on a link that exists
+// spend the budget.
+func FollowShortLink(c *fiber.Ctx) error {
+	code := c.Params("code")
+	base := resolveFrontendBaseURL(c)
+	c.Set(fiber.HeaderCacheControl, "no-store")
+
+	if !isLinkPreviewAgent(c.Get(fiber.HeaderUserAgent)) {
+		share, err := resolveShare(c, code, func() bool {
+			return middleware.WithinBudget(c, ratelimit.ShareClicks)
+		})
+		if err == nil {
+			return c.Redirect(base+"/confession/"+share.ConfessionID.String(), fiber.StatusFound)
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusInternalServerError).SendString("Failed to resolve share link")
+		}
+		return renderSharePreview(c, fiber.StatusNotFound, sharePreview{Title: "Confession not found", Target: base})
+	}
+
+	share, confession, err := sharedConfession(code)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusInternalServerError).SendString("Failed to resolve share link")
+		}
+		return renderSharePreview(c, fiber.StatusNotFound, sharePreview{Title: "Confession not found", Target: base})
+	}
+
+	title := "An anonymous confession"
+	if confession.Category != "" {
+		title = "An anonymous #" + strings.ToLower(confession.Category) + " confession"
+	}
+	link := shortLink(c, share.Code)
+	return renderSharePreview(c, fiber.StatusOK, sharePreview{
+		Found:       true,
+		Title:       title,
+		Description: excerpt(confession.Content, 200),
+		URL:         link,
+		Target:      base + "/confession/" + confession.ID.String(),
+		Image:       link + "/image.png",
+		Width:       cards.OpenGraph.Width,
+		Height:      cards.OpenGraph.Height,
+	})
+}
+
+// GetShortLinkImage renders the og:image card of a share link.
+func GetShortLinkImage(c *fiber.Ctx) error {
+	_, confession, err := sharedConfession(c.Params("code"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Share link not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to resolve share link"})
+	}
+	return sendCard(c, confession, cards.OpenGraph)
+}
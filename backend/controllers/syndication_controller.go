@@ -0,0 +1,366 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+const feedTitle = "Confessions Africa"
+
+// syndication is a feed of public confessions before it is written in one
+// of the formats.
+type syndication struct {
+	Title       string
+	Description string
+	HomeURL     string
+	FeedURL     string
+	Updated     time.Time
+	Items       []syndicationItem
+}
+
+type syndicationItem struct {
+	ID        string
+	URL       string
+	Title     string
+	Content   string
+	Category  string
+	Published time.Time
+	Updated   time.Time
+}
+
+// feedFormat writes a syndication into a response body.
+type feedFormat struct {
+	Extension   string
+	ContentType string
+	Write       func(feed syndication) ([]byte, error)
+}
+
+var (
+	atomFormat = feedFormat{Extension: "atom", ContentType: "application/atom+xml; charset=utf-8", Write: writeAtom}
+	rssFormat  = feedFormat{Extension: "rss", ContentType: "application/rss+xml; charset=utf-8", Write: writeRSS}
+	jsonFormat = feedFormat{Extension: "json", ContentType: "application/feed+json; charset=utf-8", Write: writeJSONFeed}
+)
+
+// GetAtomFeed serves the public feed as Atom 1.0.
+func GetAtomFeed(c *fiber.Ctx) error {
+	return serveConfessionsFeed(c, atomFormat)
+}
+
+// GetRSSFeed serves the public feed as RSS 2.0.
+func GetRSSFeed(c *fiber.Ctx) error {
+	return serveConfessionsFeed(c, rssFormat)
+}
+
+// GetJSONFeed serves the public feed as JSON Feed 1.1.
+func GetJSONFeed(c *fiber.Ctx) error {
+	return serveConfessionsFeed(c, jsonFormat)
+}
+
+// serveConfessionsFeed runs the public feed query, narrowed by ?category=
+// and ?trending=true and cut to ?limit=, and writes it in format. Clients
+// that already hold the same body get 304.
+func serveConfessionsFeed(c *fiber.Ctx, format feedFormat) error {
+	category, ok := normalizeConfessionCategory(c.Query("category"))
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid category"})
+	}
+	trending := false
+	if raw := c.Query("trending"); raw != "" {
+		var err error
+		if trending, err = strconv.ParseBool(raw); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "trending must be true or false"})
+		}
+	}
+
+	query := config.DB.Scopes(confessionFeed).Limit(pageLimit(c))
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+	if trending {
+		query = query.Where("trending = ?", true)
+	}
+	var confessions []models.Confession
+	if err := query.Find(&confessions).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch confessions"})
+	}
+
+	filters := url.Values{}
+	if category != "" {
+		filters.Set("category", category)
+	}
+	if trending {
+		filters.Set("trending", "true")
+	}
+	feed := newSyndication(resolveFrontendBaseURL(c), format.Extension, filters, confessions)
+	body, err := format.Write(feed)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to write feed"})
+	}
+
+	etag := bodyETag(body)
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	if !feed.Updated.IsZero() {
+		c.Set(fiber.HeaderLastModified, feed.Updated.UTC().Format(http.TimeFormat))
+	}
+	if feedNotModified(c, etag, feed.Updated) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	c.Set(fiber.HeaderContentType, format.ContentType)
+	return c.Send(body)
+}
+
+// feedNotModified applies If-None-Match, or If-Modified-Since when no ETag
+// was sent. Last-Modified only moves when a confession is published, so
+// edits and removals are only noticed through the ETag.
+func feedNotModified(c *fiber.Ctx, etag string, updated time.Time) bool {
+	if header := c.Get(fiber.HeaderIfNoneMatch); header != "" {
+		return etagMatches(header, etag)
+	}
+	since, err := http.ParseTime(c.Get(fiber.HeaderIfModifiedSince))
+	if err != nil || updated.IsZero() {
+		return false
+	}
+	return !updated.Truncate(time.Second).After(since)
+}
+
+func newSyndication(base, extension string, filters url.Values, confessions []models.Confession) syndication {
+	feed := syndication{
+		Title:       feedTitle,
+		Description: "Anonymous confessions from across Africa",
+		HomeURL:     base,
+		FeedURL:     base + "/feeds/confessions." + extension,
+		Items:       make([]syndicationItem, 0, len(confessions)),
+	}
+	if category := filters.Get("category"); category != "" {
+		feed.Title += " #" + category
+	}
+	if filters.Get("trending") != "" {
+		feed.Title += " (trending)"
+	}
+	if len(filters) > 0 {
+		feed.FeedURL += "?" + filters.Encode()
+	}
+
+	for _, confession := range confessions {
+		item := syndicationItem{
+			ID:        confession.ID.String(),
+			URL:       base + "/confession/" + confession.ID.String(),
+			Title:     excerpt(confession.Content, 80),
+			Content:   confession.Content,
+			Category:  confession.Category,
+			Published: confession.CreatedAt.UTC(),
+			Updated:   confession.CreatedAt.UTC(),
+		}
+		// Held posts appear in the feed when they are approved.
+		if confession.ModeratedAt != nil && confession.ModeratedAt.After(item.Updated) {
+			item.Updated = confession.ModeratedAt.UTC()
+		}
+		if item.Updated.After(feed.Updated) {
+			feed.Updated = item.Updated
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	return feed
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title     string        `xml:"title"`
+	ID        string        `xml:"id"`
+	Link      atomLink      `xml:"link"`
+	Published string        `xml:"published"`
+	Updated   string        `xml:"updated"`
+	Category  *atomCategory `xml:"category,omitempty"`
+	Content   atomContent   `xml:"content"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+func writeAtom(feed syndication) ([]byte, error) {
+	out := atomFeed{
+		Title:   feed.Title,
+		ID:      feed.FeedURL,
+		Updated: feed.Updated.Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: feed.FeedURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: feed.HomeURL, Rel: "alternate", Type: "text/html"},
+		},
+		// Confessions are anonymous; Atom still requires an author.
+		Author: atomAuthor{Name: feedTitle},
+	}
+	for _, item := range feed.Items {
+		entry := atomEntry{
+			Title:     item.Title,
+			ID:        "urn:uuid:" + item.ID,
+			Link:      atomLink{Href: item.URL, Rel: "alternate", Type: "text/html"},
+			Published: item.Published.Format(time.RFC3339),
+			Updated:   item.Updated.Format(time.RFC3339),
+			Content:   atomContent{Type: "text", Body: item.Content},
+		}
+		if item.Category != "" {
+			entry.Category = &atomCategory{Term: item.Category}
+		}
+		out.Entries = append(out.Entries, entry)
+	}
+	return marshalXML(out)
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Category    string  `xml:"category,omitempty"`
+	Description string  `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func writeRSS(feed syndication) ([]byte, error) {
+	out := rssFeed{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       feed.Title,
+			Link:        feed.HomeURL,
+			Description: feed.Description,
+			Self:        atomLink{Href: feed.FeedURL, Rel: "self", Type: "application/rss+xml"},
+		},
+	}
+	if !feed.Updated.IsZero() {
+		out.Channel.LastBuildDate = feed.Updated.Format(time.RFC1123Z)
+	}
+	for _, item := range feed.Items {
+		out.Channel.Items = append(out.Channel.Items, rssItem{
+			Title:    item.Title,
+			Link:     item.URL,
+			GUID:     rssGUID{IsPermaLink: true, Value: item.URL},
+			PubDate:  item.Published.Format(time.RFC1123Z),
+			Category: item.Category,
+			// Readers treat descriptions as HTML.
+			Description: strings.ReplaceAll(html.EscapeString(item.Content), "\n", "<br>"),
+		})
+	}
+	return marshalXML(out)
+}
+
+func marshalXML(v interface{}) ([]byte, error) {
+	var body bytes.Buffer
+	body.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&body)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return body.Bytes(), nil
+}
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description"`
+	Language    string         `json:"language"`
+	Authors     []jsonAuthor   `json:"authors"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedItem struct {
+	ID            string   `json:"id"`
+	URL           string   `json:"url"`
+	Title         string   `json:"title"`
+	ContentText   string   `json:"content_text"`
+	DatePublished string   `json:"date_published"`
+	DateModified  string   `json:"date_modified"`
+	Tags          []string `json:"tags,omitempty"`
+}
+
+func writeJSONFeed(feed syndication) ([]byte, error) {
+	out := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       feed.Title,
+		HomePageURL: feed.HomeURL,
+		FeedURL:     feed.FeedURL,
+		Description: feed.Description,
+		Language:    "en",
+		Authors:     []jsonAuthor{{Name: feedTitle}},
+		Items:       []jsonFeedItem{},
+	}
+	for _, item := range feed.Items {
+		entry := jsonFeedItem{
+			ID:            item.ID,
+			URL:           item.URL,
+			Title:         item.Title,
+			ContentText:   item.Content,
+			DatePublished: item.Published.Format(time.RFC3339),
+			DateModified:  item.Updated.Format(time.RFC3339),
+		}
+		if item.Category != "" {
+			entry.Tags = []string{item.Category}
+		}
+		out.Items = append(out.Items, entry)
+	}
+	return json.Marshal(out)
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func testSyndication() syndication {
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	approved := created.Add(2 * time.Hour)
+	confessions := []models.Confession{
+		{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), Content: "I ate <the> last   mango & lied", Category: "family", CreatedAt: created, ModeratedAt: &approved},
+		{ID: uuid.MustParse("00000000-0000-0000-0000-000000000002"), Content: "Second", CreatedAt: created.Add(-time.Hour)},
+	}
+	filters := url.Values{"category": {"family"}}
+	return newSyndication("https://example.com", "atom", filters, confessions)
+}
+
+func TestNewSyndication_UsesLatestPublication(t *testing.T) {
+	feed := testSyndication()
+	if want := time.Date(2026, 3, 1, 14, 0, 0, 0, time.UTC); !feed.Updated.Equal(want) {
+		t.Fatalf("updated = %v, want %v", feed.Updated, want)
+	}
+	if feed.FeedURL != "https://example.com/feeds/confessions.atom?category=family" || feed.Title != "Confessions Africa #family" {
+		t.Fatalf("unexpected feed url %q or title %q", feed.FeedURL, feed.Title)
+	}
+	if item := feed.Items[0]; item.Title != "I ate <the> last mango & lied" || item.URL != "https://example.com/confession/00000000-0000-0000-0000-000000000001" {
+		t.Fatalf("unexpected item %+v", item)
+	}
+}
+
+func TestFeedFormats_RoundTrip(t *testing.T) {
+	feed := testSyndication()
+
+	body, err := writeAtom(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var atom atomFeed
+	if err := xml.Unmarshal(body, &atom); err != nil {
+		t.Fatalf("atom does not parse: %v\n%s", err, body)
+	}
+	if len(atom.Entries) != 2 || atom.Entries[0].Content.Body != feed.Items[0].Content || atom.Entries[0].Category.Term != "family" || atom.Updated != "2026-03-01T14:00:00Z" {
+		t.Fatalf("unexpected atom feed %+v", atom)
+	}
+
+	body, err = writeRSS(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), `<atom:link href="https://example.com/feeds/confessions.atom?category=family" rel="self"`) {
+		t.Fatalf("rss has no self link:\n%s", body)
+	}
+	var rss struct {
+		Items []rssItem `xml:"channel>item"`
+	}
+	if err := xml.Unmarshal(body, &rss); err != nil || len(rss.Items) != 2 || rss.Items[1].PubDate != "Sun, 01 Mar 2026 11:00:00 +0000" ||
+		rss.Items[0].Description != "I ate &lt;the&gt; last   mango &amp; lied" {
+		t.Fatalf("unexpected rss %+v (%v)", rss, err)
+	}
+
+	body, err = writeJSONFeed(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var jf jsonFeed
+	if err := json.Unmarshal(body, &jf); err != nil || jf.Version != "https://jsonfeed.org/version/1.1" || len(jf.Items) != 2 || jf.Items[1].Tags != nil {
+		t.Fatalf("unexpected json feed %+v (%v)", jf, err)
+	}
+}
+
+func TestFeedNotModified(t *testing.T) {
+	updated := time.Date(2026, 3, 1, 14, 0, 0, 500, time.UTC)
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		if feedNotModified(c, `"abc"`, updated) {
+			return c.SendStatus(fiber.StatusNotModified)
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	cases := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{"no validators", nil, fiber.StatusOK},
+		{"matching etag", map[string]string{"If-None-Match": `"abc"`}, fiber.StatusNotModified},
+		{"etag wins over date", map[string]string{"If-None-Match": `"old"`, "If-Modified-Since": updated.Add(time.Hour).Format(http.TimeFormat)}, fiber.StatusOK},
+		{"same second", map[string]string{"If-Modified-Since": updated.Format(http.TimeFormat)}, fiber.StatusNotModified},
+		{"older copy", map[string]string{"If-Modified-Since": updated.Add(-time.Second).Format(http.TimeFormat)}, fiber.StatusOK},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", "/", nil)
+		for key, value := range tc.headers {
+			req.Header.Set(key, value)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, resp.StatusCode, tc.want)
+		}
+	}
+}
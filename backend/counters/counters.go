@@ -0,0 +1,155 @@
+// Package counters owns the denormalized totals stored on confessions and
+// comments. Writers adjust them with atomic increments inside their own
+// transaction; a periodic reconciler repairs any drift from the source rows.
+package counters
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNotFound is returned when the row whose counter should change does not
+// exist.
+var ErrNotFound = errors.New("counter target not found")
+
+// Column is a counter column on confessions or comments.
+type Column string
+
+const (
+	Likes    Column = "likes"
+	Boos     Column = "boos"
+	Comments Column = "comments"
+	Shares   Column = "shares"
+	Stars    Column = "stars"
+)
+
+// ReactionColumn maps a reaction type to the legacy column it also feeds.
+// Only like and boo have one; every type is counted in reaction_counts.
+func ReactionColumn(reactionType string) (Column, bool) {
+	switch reactionType {
+	case "like":
+		return Likes, true
+	case "boo":
+		return Boos, true
+	default:
+		return "", false
+	}
+}
+
+// ReactionDeltas returns the counter changes for a reaction going from
+// previous to next. An empty string means no reaction.
+func ReactionDeltas(previous, next string) map[Column]int {
+	deltas := map[Column]int{}
+	if previous == next {
+		return deltas
+	}
+	if column, ok := ReactionColumn(previous); ok {
+		deltas[column]--
+	}
+	if column, ok := ReactionColumn(next); ok {
+		deltas[column]++
+	}
+	return deltas
+}
+
+// TypeDeltas returns the per-type reaction count changes for a reaction going
+// from previous to next.
+func TypeDeltas(previous, next string) map[string]int {
+	deltas := map[string]int{}
+	if previous == next {
+		return deltas
+	}
+	if previous != "" {
+		deltas[previous]--
+	}
+	if next != "" {
+		deltas[next]++
+	}
+	return deltas
+}
+
+// ApplyConfessionReaction adjusts a confession's counters for a reaction
+// change from previous to next.
+func ApplyConfessionReaction(tx *gorm.DB, confessionID uuid.UUID, previous, next string) error {
+	return add(tx, &models.Confession{}, confessionID, ReactionDeltas(previous, next), TypeDeltas(previous, next))
+}
+
+// ApplyCommentReaction adjusts a comment's counters for a reaction change from
+// previous to next.
+func ApplyCommentReaction(tx *gorm.DB, commentID uuid.UUID, previous, next string) error {
+	return add(tx, &models.Comment{}, commentID, ReactionDeltas(previous, next), TypeDeltas(previous, next))
+}
+
+// AddComments changes a confession's comment count by delta.
+func AddComments(tx *gorm.DB, confessionID uuid.UUID, delta int) error {
+	return add(tx, &models.Confession{}, confessionID, map[Column]int{Comments: delta}, nil)
+}
+
+// IncrementShares records one more share of a confession.
+func IncrementShares(tx *gorm.DB, confessionID uuid.UUID) error {
+	return add(tx, &models.Confession{}, confessionID, map[Column]int{Shares: 1}, nil)
+}
+
+// IncrementStars records one more star on a confession.
+func IncrementStars(tx *gorm.DB, confessionID uuid.UUID) error {
+	return add(tx, &models.Confession{}, confessionID, map[Column]int{Stars: 1}, nil)
+}
+
+// add applies every delta in a single UPDATE so concurrent writers never
+// overwrite each other. Counters are clamped at zero, and reaction types whose
+// count reaches zero are dropped from reaction_counts.
+func add(tx *gorm.DB, model interface{}, id uuid.UUID, deltas map[Column]int, types map[string]int) error {
+	updates := map[string]interface{}{}
+	for column, delta := range deltas {
+		if delta == 0 {
+			continue
+		}
+		updates[string(column)] = gorm.Expr(fmt.Sprintf("GREATEST(%s + ?, 0)", column), delta)
+	}
+	if expr, ok := reactionCountsExpr(types); ok {
+		updates["reaction_counts"] = expr
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+
+	result := tx.Model(model).Where("id = ?", id).UpdateColumns(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// reactionCountsExpr merges the new per-type totals into the jsonb column.
+// Totals that drop to zero become null and are stripped.
+func reactionCountsExpr(types map[string]int) (clause.Expr, bool) {
+	keys := make([]string, 0, len(types))
+	for reactionType, delta := range types {
+		if delta != 0 {
+			keys = append(keys, reactionType)
+		}
+	}
+	if len(keys) == 0 {
+		return clause.Expr{}, false
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	args := make([]interface{}, 0, 3*len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, "?::text, NULLIF(GREATEST(COALESCE((reaction_counts->>?)::int, 0) + ?, 0), 0)")
+		args = append(args, key, key, types[key])
+	}
+	sql := "jsonb_strip_nulls(reaction_counts || jsonb_build_object(" + strings.Join(pairs, ", ") + "))"
+	return gorm.Expr(sql, args...), true
+}
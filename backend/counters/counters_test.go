@@ -0,0 +1,56 @@
+package counters
+
+import "testing"
+
+func TestReactionDeltas(t *testing.T) {
+	cases := []struct {
+		previous, next string
+		want           map[Column]int
+	}{
+		{"", "like", map[Column]int{Likes: 1}},
+		{"like", "boo", map[Column]int{Likes: -1, Boos: 1}},
+		{"boo", "", map[Column]int{Boos: -1}},
+		{"like", "like", map[Column]int{}},
+		{"", "unknown", map[Column]int{}},
+	}
+	for _, tc := range cases {
+		got := ReactionDeltas(tc.previous, tc.next)
+		if len(got) != len(tc.want) {
+			t.Fatalf("ReactionDeltas(%q, %q) = %v, want %v", tc.previous, tc.next, got, tc.want)
+		}
+		for column, delta := range tc.want {
+			if got[column] != delta {
+				t.Fatalf("ReactionDeltas(%q, %q) = %v, want %v", tc.previous, tc.next, got, tc.want)
+			}
+		}
+	}
+}
+
+func TestTypeDeltas(t *testing.T) {
+	got := TypeDeltas("heart", "laugh")
+	if len(got) != 2 || got["heart"] != -1 || got["laugh"] != 1 {
+		t.Fatalf("TypeDeltas(heart, laugh) = %v", got)
+	}
+	if got := TypeDeltas("wow", "wow"); len(got) != 0 {
+		t.Fatalf("TypeDeltas(wow, wow) = %v, want no change", got)
+	}
+}
+
+func TestReactionCountsExpr_BindsTypesAsParameters(t *testing.T) {
+	expr, ok := reactionCountsExpr(map[string]int{"wow": 1, "heart": -1, "sad": 0})
+	if !ok {
+		t.Fatal("expected an expression")
+	}
+	want := []interface{}{"heart", "heart", -1, "wow", "wow", 1}
+	if len(expr.Vars) != len(want) {
+		t.Fatalf("vars = %v, want %v", expr.Vars, want)
+	}
+	for i := range want {
+		if expr.Vars[i] != want[i] {
+			t.Fatalf("vars = %v, want %v", expr.Vars, want)
+		}
+	}
+	if _, ok := reactionCountsExpr(map[string]int{"sad": 0}); ok {
+		t.Fatal("expected no expression without changes")
+	}
+}
@@ -0,0 +1,259 @@
+package counters
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/events"
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// reconcileLockID keeps replicas from reconciling at the same time.
+const reconcileLockID int64 = 7_311_042_034
+
+var metrics = expvar.NewMap("counters")
+
+// Report counts the rows whose counters were repaired.
+type Report struct {
+	Confessions int64
+	Comments    int64
+	Skipped     bool
+}
+
+// Total is the number of repaired rows.
+func (r Report) Total() int64 {
+	return r.Confessions + r.Comments
+}
+
+// Likes, boos, per-type reaction totals and published comment counts are recomputed
+// from their source rows and only rows that differ are written. Stars have no source table and shares
+// from before share tracking have no rows, so both are left alone.
+//
+// actualConfessionsSQL is a CTE of the recomputed totals; %s narrows it to
+// some confessions.
+const actualConfessionsSQL = `
+WITH actual AS (
+	SELECT c.id,
+		COALESCE(r.likes, 0) AS likes,
+		COALESCE(r.boos, 0) AS boos,
+		COALESCE(m.total, 0) AS comments,
+		COALESCE(t.counts, '{}'::jsonb) AS reaction_counts
+	FROM confessions c
+	LEFT JOIN (
+		SELECT confession_id,
+			count(*) FILTER (WHERE type = 'like') AS likes,
+			count(*) FILTER (WHERE type = 'boo') AS boos
+		FROM reactions
+		WHERE confession_id IS NOT NULL AND comment_id IS NULL
+		GROUP BY confession_id
+	) r ON r.confession_id = c.id
+	LEFT JOIN (
+		SELECT confession_id, count(*) AS total
+		FROM comments
+		WHERE status = 'published'
+		GROUP BY confession_id
+	) m ON m.confession_id = c.id
+	LEFT JOIN (
+		SELECT confession_id, jsonb_object_agg(type, total) AS counts
+		FROM (
+			SELECT confession_id, type, count(*) AS total
+			FROM reactions
+			WHERE confession_id IS NOT NULL AND comment_id IS NULL
+			GROUP BY confession_id, type
+		) per_type
+		GROUP BY confession_id
+	) t ON t.confession_id = c.id
+	%s
+)`
+
+const confessionDrift = `(c.likes <> a.likes OR c.boos <> a.boos OR c.comments <> a.comments
+		OR c.reaction_counts <> a.reaction_counts)`
+
+const actualCommentsSQL = `
+WITH actual AS (
+	SELECT m.id,
+		COALESCE(r.likes, 0) AS likes,
+		COALESCE(r.boos, 0) AS boos,
+		COALESCE(t.counts, '{}'::jsonb) AS reaction_counts
+	FROM comments m
+	LEFT JOIN (
+		SELECT comment_id,
+			count(*) FILTER (WHERE type = 'like') AS likes,
+			count(*) FILTER (WHERE type = 'boo') AS boos
+		FROM reactions
+		WHERE comment_id IS NOT NULL
+		GROUP BY comment_id
+	) r ON r.comment_id = m.id
+	LEFT JOIN (
+		SELECT comment_id, jsonb_object_agg(type, total) AS counts
+		FROM (
+			SELECT comment_id, type, count(*) AS total
+			FROM reactions
+			WHERE comment_id IS NOT NULL
+			GROUP BY comment_id, type
+		) per_type
+		GROUP BY comment_id
+	) t ON t.comment_id = m.id
+	%s
+)`
+
+const commentDrift = `(m.likes <> a.likes OR m.boos <> a.boos OR m.reaction_counts <> a.reaction_counts)`
+
+var (
+	lockDriftedConfessionsSQL = fmt.Sprintf(actualConfessionsSQL, "") + `
+SELECT c.id FROM confessions c JOIN actual a ON a.id = c.id
+WHERE ` + confessionDrift + `
+ORDER BY c.id
+FOR UPDATE OF c`
+
+	repairConfessionsSQL = fmt.Sprintf(actualConfessionsSQL, "WHERE c.id IN @ids") + `
+UPDATE confessions c
+SET likes = a.likes, boos = a.boos, comments = a.comments, reaction_counts = a.reaction_counts
+FROM actual a
+WHERE c.id = a.id AND ` + confessionDrift + `
+RETURNING c.id`
+
+	lockDriftedCommentsSQL = fmt.Sprintf(actualCommentsSQL, "") + `
+SELECT m.id FROM comments m JOIN actual a ON a.id = m.id
+WHERE ` + commentDrift + `
+ORDER BY m.id
+FOR UPDATE OF m`
+
+	repairCommentsSQL = fmt.Sprintf(actualCommentsSQL, "WHERE m.id IN @ids") + `
+UPDATE comments m
+SET likes = a.likes, boos = a.boos, reaction_counts = a.reaction_counts
+FROM actual a
+WHERE m.id = a.id AND ` + commentDrift + `
+RETURNING m.id`
+)
+
+// repair locks the rows lockSQL finds drifted, then recounts and writes
+// them with repairSQL. Writers hold the row lock from their increment until
+// they commit, so once the lock is held every committed change is visible
+// to the recount (a new statement, so a new snapshot) and every later one
+// applies on top of the repaired value. It returns the repaired ids.
+func repair(tx *gorm.DB, lockSQL, repairSQL string) ([]uuid.UUID, error) {
+	var drifted []uuid.UUID
+	if err := tx.Raw(lockSQL).Scan(&drifted).Error; err != nil {
+		return nil, err
+	}
+	if len(drifted) == 0 {
+		return nil, nil
+	}
+	var repaired []uuid.UUID
+	err := tx.Raw(repairSQL, map[string]interface{}{"ids": drifted}).Scan(&repaired).Error
+	return repaired, err
+}
+
+// publishRepairs announces the repaired published rows as updates, which
+// drops their cached reads and sends the new totals to connected clients.
+func publishRepairs(tx *gorm.DB, confessionIDs, commentIDs []uuid.UUID) error {
+	if len(confessionIDs) > 0 {
+		var confessions []models.Confession
+		if err := tx.Scopes(models.Published).Find(&confessions, "id IN ?", confessionIDs).Error; err != nil {
+			return err
+		}
+		for _, confession := range confessions {
+			if err := events.Enqueue(tx, "", events.ConfessionUpdated{ConfessionPayload: events.NewConfessionPayload(confession)}); err != nil {
+				return err
+			}
+		}
+	}
+	if len(commentIDs) > 0 {
+		var comments []models.Comment
+		if err := tx.Scopes(models.Published).Preload("Author").Find(&comments, "id IN ?", commentIDs).Error; err != nil {
+			return err
+		}
+		for _, comment := range comments {
+			if err := events.Enqueue(tx, "", events.CommentUpdated{CommentPayload: events.NewCommentPayload(comment)}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Reconcile repairs drifted counters in one transaction and publishes the
+// repaired rows. If another replica is already reconciling, it returns
+// immediately with Skipped set.
+func Reconcile(ctx context.Context, db *gorm.DB) (Report, error) {
+	var report Report
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", reconcileLockID).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			report.Skipped = true
+			return nil
+		}
+
+		confessions, err := repair(tx, lockDriftedConfessionsSQL, repairConfessionsSQL)
+		if err != nil {
+			return err
+		}
+		report.Confessions = int64(len(confessions))
+
+		comments, err := repair(tx, lockDriftedCommentsSQL, repairCommentsSQL)
+		if err != nil {
+			return err
+		}
+		report.Comments = int64(len(comments))
+		return publishRepairs(tx, confessions, comments)
+	})
+	if err != nil {
+		metrics.Add("reconcile_errors", 1)
+		return Report{}, err
+	}
+
+	metrics.Add("reconcile_runs", 1)
+	metrics.Add("drift_fixed", report.Total())
+	metrics.Add("drift_fixed:confessions", report.Confessions)
+	metrics.Add("drift_fixed:comments", report.Comments)
+	return report, nil
+}
+
+// StartReconciler runs Reconcile at startup and then every interval until
+// ctx is cancelled, and logs whenever it repaired drift.
+func StartReconciler(ctx context.Context, wg *sync.WaitGroup, db *gorm.DB, interval time.Duration) {
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		run := func() {
+			report, err := Reconcile(ctx, db)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("counter reconciliation error: %v", err)
+				}
+				return
+			}
+			if report.Total() > 0 {
+				log.Printf("counter reconciliation repaired %d confession(s) and %d comment(s)", report.Confessions, report.Comments)
+			}
+		}
+
+		run()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				run()
+			}
+		}
+	}()
+}
@@ -0,0 +1,82 @@
+package counters
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/migrations"
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/google/uuid"
+)
+
+// TestReconcile_KeepsCommentReactionsOffTheConfession needs a disposable
+// PostgreSQL database in TEST_DATABASE_URL; it is skipped without one.
+func TestReconcile_KeepsCommentReactionsOffTheConfession(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := config.OpenDB(dsn)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get sql.DB: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	if _, err := migrations.Up(context.Background(), sqlDB); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	suffix := uuid.NewString()[:8]
+	user := models.User{Username: "reconcile-" + suffix, Email: suffix + "@example.test", PasswordHash: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	t.Cleanup(func() { db.Delete(&user) })
+
+	// The confession's like counter has drifted; the comment's is right.
+	confession := models.Confession{UserID: user.ID, Content: "reconcile test", Category: "general", Likes: 5, Comments: 1, Reactions: models.ReactionCounts{"like": 1}}
+	if err := db.Create(&confession).Error; err != nil {
+		t.Fatalf("failed to create confession: %v", err)
+	}
+	comment := models.Comment{ConfessionID: confession.ID, UserID: user.ID, Content: "reconcile test", Likes: 1, Reactions: models.ReactionCounts{"like": 1}}
+	if err := db.Create(&comment).Error; err != nil {
+		t.Fatalf("failed to create comment: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Where("confession_id = ?", confession.ID).Delete(&models.Reaction{})
+		db.Delete(&comment)
+		db.Delete(&confession)
+	})
+	reactions := []models.Reaction{
+		{UserID: user.ID, ConfessionID: &confession.ID, Type: "like"},
+		// A comment reaction that also names its confession.
+		{UserID: user.ID, ConfessionID: &confession.ID, CommentID: &comment.ID, Type: "like"},
+	}
+	if err := db.Create(&reactions).Error; err != nil {
+		t.Fatalf("failed to create reactions: %v", err)
+	}
+
+	if _, err := Reconcile(context.Background(), db); err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+
+	var repaired models.Confession
+	if err := db.First(&repaired, "id = ?", confession.ID).Error; err != nil {
+		t.Fatalf("failed to reload confession: %v", err)
+	}
+	if repaired.Likes != 1 || repaired.Boos != 0 || repaired.Reactions["like"] != 1 || repaired.Comments != 1 {
+		t.Fatalf("expected 1 like and 1 comment on the confession, got %+v", repaired)
+	}
+	var kept models.Comment
+	if err := db.First(&kept, "id = ?", comment.ID).Error; err != nil {
+		t.Fatalf("failed to reload comment: %v", err)
+	}
+	if kept.Likes != 1 || kept.Reactions["like"] != 1 {
+		t.Fatalf("expected the comment to keep 1 like, got %+v", kept)
+	}
+}
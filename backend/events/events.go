@@ -0,0 +1,82 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Redis channels events are published on. The websocket broadcaster relays
+// everything under these namespaces, so names are part of the client API.
+const (
+	ChannelConfessionCreated    = "confessions:confession:created"
+	ChannelConfessionUpdated    = "confessions:confession:updated"
+	ChannelConfessionDeleted    = "confessions:confession:deleted"
+	ChannelConfessionStarred    = "confessions:confession:starred"
+	ChannelCommentCreated       = "confessions:comment:created"
+	ChannelCommentUpdated       = "confessions:comment:updated"
+	ChannelCommentDeleted       = "confessions:comment:deleted"
+	ChannelReactionUpdated      = "confessions:reaction:updated"
+	ChannelReactionRemoved      = "confessions:reaction:removed"
+	ChannelFriendAdded          = "connections:friend:added"
+	ChannelFriendRequestUpdated = "connections:friend:request:updated"
+	// ChannelNotificationCreated is outside the broadcast namespaces: its
+	// events are only delivered to the recipient.
+	ChannelNotificationCreated = "notifications:notification:created"
+)
+
+// SchemaVersion is bumped whenever an existing payload changes shape in a way
+// consumers have to know about. Adding fields does not require a bump.
+const SchemaVersion = 1
+
+var ErrInvalidEnvelope = errors.New("invalid event envelope")
+
+// Event is implemented by every typed payload that can be published.
+type Event interface {
+	Channel() string
+}
+
+// Envelope wraps every payload published to Redis.
+type Envelope struct {
+	ID         string          `json:"event_id"`
+	Type       string          `json:"type"`
+	Version    int             `json:"version"`
+	ActorID    string          `json:"actor_id,omitempty"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// NewEnvelope assigns an event ID and timestamp and serializes the payload.
+func NewEnvelope(actorID string, event Event) (Envelope, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return Envelope{}, err
+	}
+	return Envelope{
+		ID:         uuid.NewString(),
+		Type:       event.Channel(),
+		Version:    SchemaVersion,
+		ActorID:    actorID,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}, nil
+}
+
+// Decode parses a raw Redis payload into an envelope.
+func Decode(raw string) (Envelope, error) {
+	var envelope Envelope
+	if err := json.Unmarshal([]byte(raw), &envelope); err != nil {
+		return Envelope{}, err
+	}
+	if envelope.ID == "" || envelope.Type == "" || len(envelope.Data) == 0 {
+		return Envelope{}, ErrInvalidEnvelope
+	}
+	return envelope, nil
+}
+
+// Unmarshal decodes the envelope data into a typed payload.
+func (e Envelope) Unmarshal(v interface{}) error {
+	return json.Unmarshal(e.Data, v)
+}
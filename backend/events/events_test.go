@@ -0,0 +1,81 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	commentID := uuid.New()
+	envelope, err := NewEnvelope("user-1", CommentDeleted{ID: commentID})
+	if err != nil {
+		t.Fatalf("NewEnvelope failed: %v", err)
+	}
+	if envelope.ID == "" || envelope.Type != ChannelCommentDeleted || envelope.Version != SchemaVersion {
+		t.Fatalf("unexpected envelope header: %+v", envelope)
+	}
+
+	raw, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	decoded, err := Decode(string(raw))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if decoded.ActorID != "user-1" {
+		t.Fatalf("expected actor user-1, got %q", decoded.ActorID)
+	}
+
+	var payload CommentDeleted
+	if err := decoded.Unmarshal(&payload); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if payload.ID != commentID {
+		t.Fatalf("expected comment id %s, got %s", commentID, payload.ID)
+	}
+}
+
+func TestDecode_RejectsBarePayload(t *testing.T) {
+	if _, err := Decode(`{"id":"abc-123"}`); err == nil {
+		t.Fatalf("expected bare payload to be rejected")
+	}
+	if _, err := Decode(`{"id":`); err == nil {
+		t.Fatalf("expected invalid json to be rejected")
+	}
+}
+
+func TestPublish_UsesConfiguredPublisher(t *testing.T) {
+	memory := NewMemoryPublisher()
+	SetPublisher(memory)
+	defer SetPublisher(nil)
+
+	Publish(context.Background(), "user-2", ConfessionDeleted{ID: uuid.New()})
+
+	published := memory.Published()
+	if len(published) != 1 {
+		t.Fatalf("expected 1 published event, got %d", len(published))
+	}
+	if published[0].Type != ChannelConfessionDeleted || published[0].ActorID != "user-2" {
+		t.Fatalf("unexpected envelope: %+v", published[0])
+	}
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		0:  0,
+		1:  time.Second,
+		2:  2 * time.Second,
+		4:  8 * time.Second,
+		20: outboxMaxBackoff,
+	}
+	for attempts, want := range cases {
+		if got := outboxBackoff(attempts); got != want {
+			t.Fatalf("outboxBackoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	outboxBatchSize      = 100
+	outboxMaxBackoff     = 5 * time.Minute
+	outboxCleanupEvery   = time.Minute
+	maxOutboxErrorLength = 500
+)
+
+// Enqueue stores the event in the outbox using tx, so it is only relayed if
+// the surrounding transaction commits.
+func Enqueue(tx *gorm.DB, actorID string, event Event) error {
+	envelope, err := NewEnvelope(actorID, event)
+	if err != nil {
+		return err
+	}
+	id, err := uuid.Parse(envelope.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&models.OutboxEvent{
+		ID:          id,
+		Channel:     envelope.Type,
+		Payload:     string(data),
+		AvailableAt: envelope.OccurredAt,
+	}).Error
+}
+
+// RelayConfig controls the outbox relay worker. When Ready is set and
+// returns false the relay leaves rows pending without counting attempts, so
+// an outage does not push every event to the maximum backoff.
+type RelayConfig struct {
+	PollInterval time.Duration
+	Retention    time.Duration
+	Ready        func() bool
+}
+
+// StartOutboxRelay publishes pending outbox rows until ctx is cancelled.
+// Rows are claimed with SKIP LOCKED so several replicas can relay in
+// parallel; a row is marked delivered only after a successful publish, which
+// gives at-least-once delivery. Consumers can dedupe on the envelope ID.
+func StartOutboxRelay(ctx context.Context, wg *sync.WaitGroup, db *gorm.DB, publisher Publisher, cfg RelayConfig) {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 500 * time.Millisecond
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = 24 * time.Hour
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(cfg.PollInterval)
+		defer ticker.Stop()
+		lastCleanup := time.Now()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for cfg.Ready == nil || cfg.Ready() {
+					relayed, err := relayOutboxBatch(ctx, db, publisher)
+					if err != nil {
+						log.Printf("outbox relay error: %v", err)
+						break
+					}
+					if relayed < outboxBatchSize || ctx.Err() != nil {
+						break
+					}
+				}
+
+				if time.Since(lastCleanup) >= outboxCleanupEvery {
+					lastCleanup = time.Now()
+					cutoff := time.Now().Add(-cfg.Retention)
+					if err := db.Where("delivered_at IS NOT NULL AND delivered_at < ?", cutoff).
+						Delete(&models.OutboxEvent{}).Error; err != nil {
+						log.Printf("outbox cleanup error: %v", err)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// relayOutboxBatch publishes one batch of due rows and returns how many rows
+// it claimed.
+func relayOutboxBatch(ctx context.Context, db *gorm.DB, publisher Publisher) (int, error) {
+	claimed := 0
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rows []models.OutboxEvent
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("delivered_at IS NULL AND available_at <= ?", time.Now()).
+			Order("created_at asc").
+			Limit(outboxBatchSize).
+			Find(&rows).Error; err != nil {
+			return err
+		}
+		claimed = len(rows)
+
+		for _, row := range rows {
+			var envelope Envelope
+			publishErr := json.Unmarshal([]byte(row.Payload), &envelope)
+			if publishErr == nil {
+				publishErr = publisher.Publish(ctx, envelope)
+			}
+
+			if publishErr == nil {
+				now := time.Now()
+				if err := tx.Model(&models.OutboxEvent{}).
+					Where("id = ?", row.ID).
+					Updates(map[string]interface{}{"delivered_at": now, "attempts": row.Attempts + 1, "last_error": ""}).Error; err != nil {
+					return err
+				}
+				metrics.Add("outbox_delivered", 1)
+				metrics.Add("published", 1)
+				metrics.Add("published:"+row.Channel, 1)
+				continue
+			}
+
+			attempts := row.Attempts + 1
+			message := publishErr.Error()
+			if len(message) > maxOutboxErrorLength {
+				message = message[:maxOutboxErrorLength]
+			}
+			if err := tx.Model(&models.OutboxEvent{}).
+				Where("id = ?", row.ID).
+				Updates(map[string]interface{}{
+					"attempts":     attempts,
+					"last_error":   message,
+					"available_at": time.Now().Add(outboxBackoff(attempts)),
+				}).Error; err != nil {
+				return err
+			}
+			metrics.Add("publish_errors", 1)
+			metrics.Add("publish_errors:"+row.Channel, 1)
+			if attempts == 1 || attempts%10 == 0 {
+				log.Printf("outbox event %s (%s) publish failed after %d attempts: %v", row.ID, row.Channel, attempts, publishErr)
+			}
+		}
+		return nil
+	})
+	return claimed, err
+}
+
+// outboxBackoff doubles from one second up to outboxMaxBackoff.
+func outboxBackoff(attempts int) time.Duration {
+	if attempts <= 0 {
+		return 0
+	}
+	backoff := time.Second
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= outboxMaxBackoff {
+			return outboxMaxBackoff
+		}
+	}
+	return backoff
+}
@@ -0,0 +1,162 @@
+package events
+
+import (
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/google/uuid"
+)
+
+// ConfessionPayload mirrors the public JSON shape of models.Confession.
+type ConfessionPayload struct {
+	ID        uuid.UUID             `json:"id"`
+	Content   string                `json:"content"`
+	Category  string                `json:"category"`
+	Likes     int                   `json:"likes"`
+	Boos      int                   `json:"boos"`
+	Stars     int                   `json:"stars"`
+	Reactions models.ReactionCounts `json:"reactions"`
+	Shares    int                   `json:"shares"`
+	Comments  int                   `json:"comments"`
+	Trending  bool                  `json:"trending"`
+	CreatedAt time.Time             `json:"created_at"`
+}
+
+func NewConfessionPayload(confession models.Confession) ConfessionPayload {
+	return ConfessionPayload{
+		ID:        confession.ID,
+		Content:   confession.Content,
+		Category:  confession.Category,
+		Likes:     confession.Likes,
+		Boos:      confession.Boos,
+		Reactions: confession.Reactions,
+		Stars:     confession.Stars,
+		Shares:    confession.Shares,
+		Comments:  confession.Comments,
+		Trending:  confession.Trending,
+		CreatedAt: confession.CreatedAt,
+	}
+}
+
+type ConfessionCreated struct{ ConfessionPayload }
+
+func (ConfessionCreated) Channel() string { return ChannelConfessionCreated }
+
+type ConfessionUpdated struct{ ConfessionPayload }
+
+func (ConfessionUpdated) Channel() string { return ChannelConfessionUpdated }
+
+type ConfessionStarred struct{ ConfessionPayload }
+
+func (ConfessionStarred) Channel() string { return ChannelConfessionStarred }
+
+type ConfessionDeleted struct {
+	ID uuid.UUID `json:"id"`
+}
+
+func (ConfessionDeleted) Channel() string { return ChannelConfessionDeleted }
+
+type CommentAuthor struct {
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+}
+
+// CommentPayload is a comment with only the public author fields.
+type CommentPayload struct {
+	ID           uuid.UUID             `json:"id"`
+	ConfessionID uuid.UUID             `json:"confession_id"`
+	UserID       uuid.UUID             `json:"user_id"`
+	Content      string                `json:"content"`
+	Likes        int                   `json:"likes"`
+	Boos         int                   `json:"boos"`
+	Reactions    models.ReactionCounts `json:"reactions"`
+	CreatedAt    time.Time             `json:"created_at"`
+	UpdatedAt    time.Time             `json:"updated_at"`
+	Author       CommentAuthor         `json:"author"`
+}
+
+func NewCommentPayload(comment models.Comment) CommentPayload {
+	return CommentPayload{
+		ID:           comment.ID,
+		ConfessionID: comment.ConfessionID,
+		UserID:       comment.UserID,
+		Content:      comment.Content,
+		Likes:        comment.Likes,
+		Boos:         comment.Boos,
+		Reactions:    comment.Reactions,
+		CreatedAt:    comment.CreatedAt,
+		UpdatedAt:    comment.UpdatedAt,
+		Author: CommentAuthor{
+			ID:       comment.Author.ID,
+			Username: comment.Author.Username,
+		},
+	}
+}
+
+type CommentCreated struct{ CommentPayload }
+
+func (CommentCreated) Channel() string { return ChannelCommentCreated }
+
+type CommentUpdated struct{ CommentPayload }
+
+func (CommentUpdated) Channel() string { return ChannelCommentUpdated }
+
+type CommentDeleted struct {
+	ID           uuid.UUID `json:"id"`
+	ConfessionID uuid.UUID `json:"confession_id"`
+}
+
+func (CommentDeleted) Channel() string { return ChannelCommentDeleted }
+
+// ReactionUpdated targets either a confession or a comment. ConfessionID is
+// also set for comment reactions when the parent confession is known.
+// Previous is the type the reaction replaced, empty for a new reaction.
+type ReactionUpdated struct {
+	ConfessionID *uuid.UUID `json:"confession_id,omitempty"`
+	CommentID    *uuid.UUID `json:"comment_id,omitempty"`
+	Type         string     `json:"type"`
+	Previous     string     `json:"previous,omitempty"`
+}
+
+func (ReactionUpdated) Channel() string { return ChannelReactionUpdated }
+
+// ReactionRemoved carries the type the removed reaction had.
+type ReactionRemoved struct {
+	ID           uuid.UUID  `json:"id"`
+	ConfessionID *uuid.UUID `json:"confession_id,omitempty"`
+	CommentID    *uuid.UUID `json:"comment_id,omitempty"`
+	Type         string     `json:"type,omitempty"`
+}
+
+func (ReactionRemoved) Channel() string { return ChannelReactionRemoved }
+
+type FriendRequestPayload struct {
+	RequestID       uuid.UUID `json:"request_id"`
+	ConnectionID    uuid.UUID `json:"connection_id"`
+	ConnectionTitle string    `json:"connection_title"`
+	SenderID        uuid.UUID `json:"sender_id"`
+	SenderUsername  string    `json:"sender_username"`
+	ReceiverID      uuid.UUID `json:"receiver_id"`
+	Status          string    `json:"status"`
+	// PreviousStatus is the request's status before this event, empty for
+	// a new request.
+	PreviousStatus string `json:"previous_status,omitempty"`
+}
+
+type FriendAdded struct{ FriendRequestPayload }
+
+func (FriendAdded) Channel() string { return ChannelFriendAdded }
+
+type FriendRequestUpdated struct{ FriendRequestPayload }
+
+func (FriendRequestUpdated) Channel() string { return ChannelFriendRequestUpdated }
+
+// NotificationCreated carries a new notification and the recipient's unread
+// count after it.
+type NotificationCreated struct {
+	UserID       uuid.UUID           `json:"user_id"`
+	Notification models.Notification `json:"notification"`
+	Unread       int64               `json:"unread"`
+}
+
+func (NotificationCreated) Channel() string { return ChannelNotificationCreated }
@@ -0,0 +1,125 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"log"
+	"sync"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrNoPublisher          = errors.New("event publisher is not configured")
+	ErrPublisherUnavailable = errors.New("event publisher is unavailable")
+)
+
+// Publisher delivers an envelope to its channel.
+type Publisher interface {
+	Publish(ctx context.Context, envelope Envelope) error
+}
+
+var (
+	metrics          = expvar.NewMap("events")
+	defaultPublisher Publisher
+	defaultMu        sync.RWMutex
+)
+
+// SetPublisher replaces the publisher used by Publish.
+func SetPublisher(p Publisher) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultPublisher = p
+}
+
+func currentPublisher() Publisher {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultPublisher
+}
+
+// Publish wraps the event in an envelope and hands it to the configured
+// publisher. Failures are logged and counted rather than returned, because a
+// missed realtime event must never fail the request that caused it.
+func Publish(ctx context.Context, actorID string, event Event) {
+	envelope, err := NewEnvelope(actorID, event)
+	if err != nil {
+		metrics.Add("encode_errors", 1)
+		log.Printf("event %s encode error: %v", event.Channel(), err)
+		return
+	}
+
+	publisher := currentPublisher()
+	if publisher == nil {
+		err = ErrNoPublisher
+	} else {
+		err = publisher.Publish(ctx, envelope)
+	}
+	if errors.Is(err, ErrPublisherUnavailable) {
+		// Degraded mode: realtime delivery is best effort, drop quietly.
+		metrics.Add("dropped", 1)
+		return
+	}
+	if err != nil {
+		metrics.Add("publish_errors", 1)
+		metrics.Add("publish_errors:"+envelope.Type, 1)
+		log.Printf("event %s (%s) publish error: %v", envelope.Type, envelope.ID, err)
+		return
+	}
+	metrics.Add("published", 1)
+	metrics.Add("published:"+envelope.Type, 1)
+}
+
+// RedisPublisher publishes envelopes with Redis PUBLISH. When Available
+// reports false it fails fast with ErrPublisherUnavailable instead of
+// waiting on a dial timeout.
+type RedisPublisher struct {
+	Client    *goredis.Client
+	Available func() bool
+}
+
+func NewRedisPublisher(client *goredis.Client, available func() bool) *RedisPublisher {
+	return &RedisPublisher{Client: client, Available: available}
+}
+
+func (p *RedisPublisher) Publish(ctx context.Context, envelope Envelope) error {
+	if p.Client == nil {
+		return ErrNoPublisher
+	}
+	if p.Available != nil && !p.Available() {
+		return ErrPublisherUnavailable
+	}
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	return p.Client.Publish(ctx, envelope.Type, data).Err()
+}
+
+// MemoryPublisher records envelopes in memory. It is meant for tests.
+type MemoryPublisher struct {
+	mu        sync.Mutex
+	envelopes []Envelope
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+func (p *MemoryPublisher) Publish(_ context.Context, envelope Envelope) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.envelopes = append(p.envelopes, envelope)
+	return nil
+}
+
+// Published returns a copy of every envelope recorded so far.
+func (p *MemoryPublisher) Published() []Envelope {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]Envelope, len(p.envelopes))
+	copy(out, p.envelopes)
+	return out
+}
@@ -0,0 +1,127 @@
+package feed
+
+import (
+	"context"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/Semkufu95/confessions/Backend/relations"
+	"github.com/Semkufu95/confessions/Backend/reputation"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// candidateLimit is how many of the newest confessions are ranked.
+	candidateLimit = 500
+	// engagementLookback bounds the history used for category affinity.
+	engagementLookback = 90 * 24 * time.Hour
+)
+
+// LoadCandidates returns the newest published confessions with their counters.
+func LoadCandidates(ctx context.Context, db *gorm.DB) ([]Candidate, error) {
+	var confessions []models.Confession
+	if err := db.WithContext(ctx).
+		Select("id", "user_id", "category", "created_at", "reaction_counts", "comments", "shares", "stars").
+		Scopes(models.Published).
+		Order("created_at DESC").
+		Limit(candidateLimit).
+		Find(&confessions).Error; err != nil {
+		return nil, err
+	}
+
+	candidates := make([]Candidate, len(confessions))
+	for i, confession := range confessions {
+		reactions := 0
+		for _, count := range confession.Reactions {
+			reactions += count
+		}
+		candidates[i] = Candidate{
+			ID:        confession.ID,
+			AuthorID:  confession.UserID,
+			Category:  confession.Category,
+			CreatedAt: confession.CreatedAt,
+			Reactions: reactions,
+			Comments:  confession.Comments,
+			Shares:    confession.Shares,
+			Stars:     confession.Stars,
+		}
+	}
+	if err := addAuthorTrust(ctx, db, candidates); err != nil {
+		return nil, err
+	}
+	return candidates, nil
+}
+
+// addAuthorTrust fills AuthorTrust from the authors' reputations.
+func addAuthorTrust(ctx context.Context, db *gorm.DB, candidates []Candidate) error {
+	seen := map[uuid.UUID]bool{}
+	authorIDs := make([]uuid.UUID, 0, len(candidates))
+	for _, candidate := range candidates {
+		if !seen[candidate.AuthorID] {
+			seen[candidate.AuthorID] = true
+			authorIDs = append(authorIDs, candidate.AuthorID)
+		}
+	}
+	reputations, err := reputation.Lookup(ctx, db, authorIDs)
+	if err != nil {
+		return err
+	}
+	for i := range candidates {
+		if author, ok := reputations[candidates[i].AuthorID]; ok {
+			candidates[i].AuthorTrust = author.Trust()
+		}
+	}
+	return nil
+}
+
+const engagementSQL = `
+SELECT c.category, count(*) AS total
+FROM (
+	SELECT confession_id FROM reactions
+	WHERE user_id = ? AND confession_id IS NOT NULL AND comment_id IS NULL AND updated_at > ?
+	UNION ALL
+	SELECT confession_id FROM comments
+	WHERE user_id = ? AND created_at > ?
+) e
+JOIN confessions c ON c.id = e.confession_id
+GROUP BY c.category`
+
+// LoadProfile collects the viewer's category engagement, the users hidden from
+// them by mutes and blocks, and their muted categories.
+func LoadProfile(ctx context.Context, db *gorm.DB, userID uuid.UUID, now time.Time) (Profile, error) {
+	db = db.WithContext(ctx)
+	profile := Profile{
+		Engagement:      map[string]int{},
+		MutedUsers:      map[uuid.UUID]bool{},
+		MutedCategories: map[string]bool{},
+	}
+
+	since := now.Add(-engagementLookback)
+	var rows []struct {
+		Category string
+		Total    int
+	}
+	if err := db.Raw(engagementSQL, userID, since, userID, since).Scan(&rows).Error; err != nil {
+		return Profile{}, err
+	}
+	for _, row := range rows {
+		profile.Engagement[row.Category] = row.Total
+	}
+
+	hidden, err := relations.CachedHidden(ctx, db, userID)
+	if err != nil {
+		return Profile{}, err
+	}
+	profile.MutedUsers = hidden
+
+	var settings models.UserSettings
+	err = db.Select("muted_categories").Where("user_id = ?", userID).Limit(1).Find(&settings).Error
+	if err != nil {
+		return Profile{}, err
+	}
+	for _, category := range settings.MutedCategories {
+		profile.MutedCategories[category] = true
+	}
+	return profile, nil
+}
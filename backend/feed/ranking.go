@@ -0,0 +1,122 @@
+// Package feed ranks confessions for the personalized "for you" feed. Scoring
+// is a pure function of a candidate, the viewer's profile and the current
+// time, so the same inputs always give the same order.
+package feed
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Candidate is a confession considered for the feed.
+type Candidate struct {
+	ID        uuid.UUID
+	AuthorID  uuid.UUID
+	Category  string
+	CreatedAt time.Time
+	Reactions int
+	Comments  int
+	Shares    int
+	Stars     int
+	// AuthorTrust is the author's reputation mapped to -1..1; 0 is neutral.
+	AuthorTrust float64
+}
+
+// Profile is what the ranking knows about the viewer. The zero value is an
+// anonymous viewer with no history and nothing muted.
+type Profile struct {
+	// Engagement counts the viewer's recent reactions and comments per
+	// category.
+	Engagement map[string]int
+	// MutedUsers are hidden by a mute or a block in either direction.
+	MutedUsers      map[uuid.UUID]bool
+	MutedCategories map[string]bool
+}
+
+// Weights tune how recency, engagement and category affinity combine.
+type Weights struct {
+	// HalfLife is the age at which a confession's score halves.
+	HalfLife time.Duration
+	// Engagement scales log(1 + weighted interactions).
+	Engagement float64
+	// Affinity scales the viewer's relative interest in the category (0-1).
+	Affinity float64
+	// Reputation scales the author's trust; 0.2 moves a score by at most 20%.
+	Reputation float64
+}
+
+var DefaultWeights = Weights{
+	HalfLife:   18 * time.Hour,
+	Engagement: 0.4,
+	Affinity:   1.5,
+	Reputation: 0.2,
+}
+
+// Hidden reports whether the viewer muted the candidate's author or category.
+func (p Profile) Hidden(c Candidate) bool {
+	return p.MutedUsers[c.AuthorID] || p.MutedCategories[c.Category]
+}
+
+// Affinity is the viewer's interest in category relative to their favourite
+// one, between 0 and 1.
+func (p Profile) Affinity(category string) float64 {
+	top := 0
+	for _, count := range p.Engagement {
+		if count > top {
+			top = count
+		}
+	}
+	if top == 0 {
+		return 0
+	}
+	return float64(p.Engagement[category]) / float64(top)
+}
+
+// Score decays with age and grows with interactions and the viewer's affinity
+// for the category. Comments, stars and shares count for more than reactions.
+// The author's reputation nudges the result up or down.
+func Score(c Candidate, p Profile, w Weights, now time.Time) float64 {
+	age := now.Sub(c.CreatedAt)
+	if age < 0 {
+		age = 0
+	}
+	recency := math.Exp2(-age.Hours() / w.HalfLife.Hours())
+	interactions := float64(c.Reactions + 2*c.Comments + 2*c.Stars + 3*c.Shares)
+	base := recency * (1 + w.Engagement*math.Log1p(interactions) + w.Affinity*p.Affinity(c.Category))
+	return base * (1 + w.Reputation*c.AuthorTrust)
+}
+
+// Rank drops hidden candidates and orders the rest by score. Ties go to the
+// newer confession, then to the smaller id.
+func Rank(candidates []Candidate, p Profile, w Weights, now time.Time) []Candidate {
+	type scored struct {
+		Candidate
+		score float64
+	}
+	ranked := make([]scored, 0, len(candidates))
+	for _, c := range candidates {
+		if p.Hidden(c) {
+			continue
+		}
+		ranked = append(ranked, scored{c, Score(c, p, w, now)})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID.String() < b.ID.String()
+	})
+
+	result := make([]Candidate, len(ranked))
+	for i, r := range ranked {
+		result[i] = r.Candidate
+	}
+	return result
+}
@@ -0,0 +1,81 @@
+package feed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func candidate(id byte, age time.Duration, category string, reactions, comments int) Candidate {
+	return Candidate{
+		ID:        uuid.UUID{id},
+		AuthorID:  uuid.UUID{0xa0 + id},
+		Category:  category,
+		CreatedAt: now.Add(-age),
+		Reactions: reactions,
+		Comments:  comments,
+	}
+}
+
+func TestScore_DecaysWithAge(t *testing.T) {
+	fresh := candidate(1, 0, "general", 10, 0)
+	old := candidate(2, DefaultWeights.HalfLife, "general", 10, 0)
+
+	ratio := Score(old, Profile{}, DefaultWeights, now) / Score(fresh, Profile{}, DefaultWeights, now)
+	if ratio < 0.499 || ratio > 0.501 {
+		t.Fatalf("expected a confession one half-life old to score half as much, got ratio %.4f", ratio)
+	}
+}
+
+func TestScore_EngagementAndAffinityRaiseScore(t *testing.T) {
+	quiet := candidate(1, time.Hour, "work", 0, 0)
+	busy := candidate(2, time.Hour, "work", 20, 5)
+	if Score(busy, Profile{}, DefaultWeights, now) <= Score(quiet, Profile{}, DefaultWeights, now) {
+		t.Fatal("expected engagement to raise the score")
+	}
+
+	fan := Profile{Engagement: map[string]int{"love": 4, "work": 1}}
+	love := candidate(3, time.Hour, "love", 0, 0)
+	if Score(love, fan, DefaultWeights, now) <= Score(quiet, fan, DefaultWeights, now) {
+		t.Fatal("expected the favourite category to score higher")
+	}
+}
+
+func TestRank_IsDeterministicAndHidesMuted(t *testing.T) {
+	muted := candidate(1, time.Hour, "general", 50, 10)
+	mutedCategory := candidate(2, time.Hour, "family", 50, 10)
+	twinA := candidate(3, 2*time.Hour, "general", 1, 0)
+	twinB := candidate(4, 2*time.Hour, "general", 1, 0)
+	newest := candidate(5, 0, "general", 1, 0)
+	profile := Profile{
+		MutedUsers:      map[uuid.UUID]bool{muted.AuthorID: true},
+		MutedCategories: map[string]bool{"family": true},
+	}
+
+	for i := 0; i < 3; i++ {
+		ranked := Rank([]Candidate{twinB, muted, twinA, mutedCategory, newest}, profile, DefaultWeights, now)
+		if len(ranked) != 3 {
+			t.Fatalf("expected muted confessions to be hidden, got %d", len(ranked))
+		}
+		if ranked[0].ID != newest.ID || ranked[1].ID != twinA.ID || ranked[2].ID != twinB.ID {
+			t.Fatalf("unexpected order: %v, %v, %v", ranked[0].ID, ranked[1].ID, ranked[2].ID)
+		}
+	}
+}
+
+func TestScore_AuthorTrustNudgesScore(t *testing.T) {
+	neutral := candidate(1, time.Hour, "general", 5, 1)
+	trusted, distrusted := neutral, neutral
+	trusted.AuthorTrust, distrusted.AuthorTrust = 1, -1
+
+	base := Score(neutral, Profile{}, DefaultWeights, now)
+	if ratio := Score(trusted, Profile{}, DefaultWeights, now) / base; ratio < 1.199 || ratio > 1.201 {
+		t.Fatalf("expected full trust to add 20%%, got ratio %.4f", ratio)
+	}
+	if ratio := Score(distrusted, Profile{}, DefaultWeights, now) / base; ratio < 0.799 || ratio > 0.801 {
+		t.Fatalf("expected no trust to remove 20%%, got ratio %.4f", ratio)
+	}
+}
@@ -5,128 +5,119 @@ import (
 	"log"
 	"os"
 	"os/signal"
-	"strconv"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/Semkufu95/confessions/Backend/challenge"
 	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/contentfilter"
+	"github.com/Semkufu95/confessions/Backend/controllers"
+	"github.com/Semkufu95/confessions/Backend/counters"
+	"github.com/Semkufu95/confessions/Backend/events"
+	"github.com/Semkufu95/confessions/Backend/middleware"
 	"github.com/Semkufu95/confessions/Backend/redis"
+	"github.com/Semkufu95/confessions/Backend/relations"
+	"github.com/Semkufu95/confessions/Backend/reputation"
 	"github.com/Semkufu95/confessions/Backend/routes"
 	"github.com/Semkufu95/confessions/Backend/websockets"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/limiter"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/websocket/v2"
-	"github.com/joho/godotenv"
+	"github.com/google/uuid"
 )
 
 func main() {
-	// Load .env
-	if err := godotenv.Load(); err != nil {
-		log.Println("⚠️  No .env file found, using system environment")
+	// Subcommands such as `migrate up` run and exit without starting the server
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
 	}
 
-	if os.Getenv("JWT_SECRET") == "" {
-		log.Fatal("JWT_SECRET is not set")
+	// Load and validate configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
 	}
+	config.App = cfg
 
 	// Connect to Database
-	config.InitDB()
+	config.InitDB(cfg.DatabaseURL, cfg.MigrateOnStart)
 
 	// Connect to Redis
-	redisAddr := os.Getenv("REDIS_ADDR")
-	if redisAddr == "" {
-		redisAddr = "redis:6379"
-	}
-	redis.ConnectRedis(redisAddr)
+	redis.ConnectRedis(cfg.RedisAddr)
+	events.SetPublisher(events.NewRedisPublisher(redis.Client, redis.Available))
 
 	shutdownCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
 	var workers sync.WaitGroup
 
+	// Watch Redis availability; the app keeps serving while it is down
+	redis.StartHealthMonitor(shutdownCtx, &workers)
+
 	// Start Redis subscriber (background worker)
 	redis.StartSubscriber(shutdownCtx, &workers)
 
-	// Start Fiber
-	bodyLimit := 1024 * 1024 // 1MB default
-	if value := os.Getenv("API_BODY_LIMIT_BYTES"); value != "" {
-		parsed, err := strconv.Atoi(value)
-		if err != nil || parsed <= 0 {
-			log.Printf("invalid API_BODY_LIMIT_BYTES=%q, falling back to %d", value, bodyLimit)
-		} else {
-			bodyLimit = parsed
+	// Apply the reputation signal changes of events, once across replicas (background worker)
+	redis.StartEventWorker(shutdownCtx, &workers, "reputation", reputation.Channels, func(envelope events.Envelope) {
+		if err := reputation.Apply(shutdownCtx, config.DB, envelope); err != nil && shutdownCtx.Err() == nil {
+			log.Printf("reputation: failed to apply %s (%s): %v", envelope.Type, envelope.ID, err)
 		}
-	}
+	})
+
+	// Relay committed outbox events to Redis (background worker)
+	relayConfig := events.RelayConfig{PollInterval: cfg.OutboxPollInterval, Retention: cfg.OutboxRetention, Ready: redis.Available}
+	events.StartOutboxRelay(shutdownCtx, &workers, config.DB, events.NewRedisPublisher(redis.Client, redis.Available), relayConfig)
+
+	// Repair drifted likes, boos and comment counts (background worker)
+	counters.StartReconciler(shutdownCtx, &workers, config.DB, cfg.ReconcileInterval)
 
+	// Load the content filter rules and pick up edits without a restart
+	contentfilter.Watch(shutdownCtx, &workers, cfg.ContentFilterFile, cfg.ContentFilterReloadInterval)
+
+	// Proof-of-work challenges for public endpoints that are easy to script
+	challenge.Use(challenge.NewProofOfWork(cfg.JWTSecret, cfg.ChallengeDifficulty, cfg.ChallengeTTL))
+
+	// Start Fiber
 	app := fiber.New(fiber.Config{
-		BodyLimit: bodyLimit,
+		BodyLimit: cfg.BodyLimit,
 	})
-	app.Use(logger.New())
-
-	// Request rate limiting
-	maxRequests := 100
-	if value := os.Getenv("RATE_LIMIT_MAX"); value != "" {
-		parsed, err := strconv.Atoi(value)
-		if err != nil || parsed <= 0 {
-			log.Printf("invalid RATE_LIMIT_MAX=%q, falling back to %d", value, maxRequests)
-		} else {
-			maxRequests = parsed
-		}
-	}
-
-	window := time.Minute
-	if value := os.Getenv("RATE_LIMIT_WINDOW"); value != "" {
-		parsed, err := time.ParseDuration(value)
-		if err != nil || parsed <= 0 {
-			log.Printf("invalid RATE_LIMIT_WINDOW=%q, falling back to %s", value, window)
-		} else {
-			window = parsed
-		}
-	}
-	app.Use(limiter.New(limiter.Config{
-		Max:        maxRequests,
-		Expiration: window,
-		LimitReached: func(c *fiber.Ctx) error {
-			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
-				"error": "Rate limit exceeded",
-			})
-		},
+	app.Use(logger.New(logger.Config{
+		Next: isProbeRequest,
 	}))
 
+	// Request rate limiting, per user for signed-in callers and per client IP
+	// otherwise, shared by every replica through Redis; writes also have
+	// per-action budgets (see routes)
+	app.Use(middleware.RequestRateLimit(isProbeRequest))
+
 	// WebSocket endpoint
-	app.Get("/ws", websocket.New(func(c *websocket.Conn) {
+	app.Get("/ws", middleware.OptionalTicketAuth, websocket.New(func(c *websocket.Conn) {
 		defer c.Close()
 
-		websockets.Mu.Lock()
-		websockets.Clients[c] = true
-		websockets.Mu.Unlock()
+		userID, _ := c.Locals("user_id").(string)
+		websockets.Register(c, userID)
 
 		for {
 			if _, _, err := c.ReadMessage(); err != nil {
-				websockets.Mu.Lock()
-				delete(websockets.Clients, c)
-				websockets.Mu.Unlock()
+				websockets.Unregister(c)
 				break
 			}
 		}
 	}))
+	websockets.HideFrom = hideRealtimeEvent
 
 	// Redis PubSub -> Broadcast to WebSocket clients
 	redis.StartWebsocketBroadcaster(shutdownCtx, &workers, websockets.Broadcast)
+	// Redis PubSub -> the recipient's WebSocket clients
+	redis.StartNotificationDelivery(shutdownCtx, &workers, websockets.SendTo)
 
 	// Enable CORS for frontend
-	allowOrigins := os.Getenv("CORS_ALLOW_ORIGINS")
-	if allowOrigins == "" {
-		allowOrigins = "http://localhost:5173"
-	}
-
 	app.Use(cors.New(cors.Config{
-		AllowOrigins:     allowOrigins,
+		AllowOrigins:     cfg.CORSAllowOrigins,
 		AllowCredentials: true,
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-Challenge-Token, X-Challenge-Solution",
 		AllowMethods:     "GET, POST, PUT, DELETE, OPTIONS",
 	}))
 
@@ -148,15 +139,9 @@ func main() {
 	// API Routes
 	routes.SetupRoutes(app)
 
-	// Server port
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "5000"
-	}
-
 	serverErr := make(chan error, 1)
 	go func() {
-		serverErr <- app.Listen(":" + port)
+		serverErr <- app.Listen(":" + cfg.Port)
 	}()
 
 	select {
@@ -165,7 +150,11 @@ func main() {
 			log.Fatal(err)
 		}
 	case <-shutdownCtx.Done():
-		log.Println("shutdown signal received, stopping server...")
+		// Fail readiness first so the load balancer stops sending traffic,
+		// then give in-flight requests time to drain before closing listeners.
+		controllers.MarkShuttingDown()
+		log.Printf("shutdown signal received, draining for %s before stopping server...", cfg.ShutdownDrainDelay)
+		time.Sleep(cfg.ShutdownDrainDelay)
 	}
 
 	shutdownDone := make(chan struct{})
@@ -193,3 +182,27 @@ func main() {
 	}
 }
 
+// isProbeRequest keeps health probes out of access logs and rate limits.
+func isProbeRequest(c *fiber.Ctx) bool {
+	path := c.Path()
+	return path == "/healthz" || path == "/readyz"
+}
+
+// hideRealtimeEvent keeps websocket events caused by actorID away from a
+// recipient who muted or blocked them, or whom they blocked.
+func hideRealtimeEvent(recipientID, actorID string) bool {
+	recipient, err := uuid.Parse(recipientID)
+	if err != nil {
+		return false
+	}
+	actor, err := uuid.Parse(actorID)
+	if err != nil {
+		return false
+	}
+	hidden, err := relations.CachedHidden(context.Background(), config.DB, recipient)
+	if err != nil {
+		log.Printf("websocket: cannot load hidden users for %s: %v", recipientID, err)
+		return false
+	}
+	return hidden[actor]
+}
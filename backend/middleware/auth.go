@@ -2,7 +2,6 @@ package middleware
 
 import (
 	"errors"
-	"os"
 	"time"
 
 	"github.com/Semkufu95/confessions/Backend/config"
@@ -13,14 +12,15 @@ import (
 	"gorm.io/gorm"
 )
 
-func RequireAuth(c *fiber.Ctx) error {
-	tokenString := c.Get("Authorization")
+// parseToken checks a bearer token's signature and returns the ids it
+// carries, without looking up the session.
+func parseToken(tokenString string) (userID, sessionID string, ferr *fiber.Error) {
 	if tokenString == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing token"})
+		return "", "", fiber.NewError(fiber.StatusUnauthorized, "Missing token")
 	}
-	secret := os.Getenv("JWT_SECRET")
+	secret := config.App.JWTSecret
 	if secret == "" {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Server auth is not configured"})
+		return "", "", fiber.NewError(fiber.StatusInternalServerError, "Server auth is not configured")
 	}
 
 	// Strip Bearer
@@ -36,42 +36,52 @@ func RequireAuth(c *fiber.Ctx) error {
 		return []byte(secret), nil
 	})
 	if err != nil || !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+		return "", "", fiber.NewError(fiber.StatusUnauthorized, "Invalid token")
 	}
 
 	userID, ok := claims["user_id"].(string)
 	if !ok || userID == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+		return "", "", fiber.NewError(fiber.StatusUnauthorized, "Invalid token claims")
 	}
 
-	sessionID, ok := claims["session_id"].(string)
+	sessionID, ok = claims["session_id"].(string)
 	if !ok || sessionID == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid session claims"})
+		return "", "", fiber.NewError(fiber.StatusUnauthorized, "Invalid session claims")
+	}
+	return userID, sessionID, nil
+}
+
+// authenticate validates a bearer token and its session and returns the ids
+// it carries.
+func authenticate(tokenString string) (userID, sessionID string, ferr *fiber.Error) {
+	userID, sessionID, ferr = parseToken(tokenString)
+	if ferr != nil {
+		return "", "", ferr
 	}
 
 	if config.DB != nil {
 		var session models.Session
 		if err := config.DB.Where("id = ?", sessionID).First(&session).Error; err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Session not found"})
+				return "", "", fiber.NewError(fiber.StatusUnauthorized, "Session not found")
 			}
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify session"})
+			return "", "", fiber.NewError(fiber.StatusInternalServerError, "Failed to verify session")
 		}
 
 		now := time.Now()
 		if session.UserID.String() != userID {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Session mismatch"})
+			return "", "", fiber.NewError(fiber.StatusUnauthorized, "Session mismatch")
 		}
 		if session.RevokedAt != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Session revoked"})
+			return "", "", fiber.NewError(fiber.StatusUnauthorized, "Session revoked")
 		}
 		if now.After(session.ExpiresAt) {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Session expired"})
+			return "", "", fiber.NewError(fiber.StatusUnauthorized, "Session expired")
 		}
 
 		if now.Sub(session.LastActivity) > utils.SessionInactivityTimeout() {
 			_ = config.DB.Model(&models.Session{}).Where("id = ?", sessionID).Update("revoked_at", now).Error
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Session expired due to inactivity"})
+			return "", "", fiber.NewError(fiber.StatusUnauthorized, "Session expired due to inactivity")
 		}
 
 		if now.Sub(session.LastActivity) > utils.SessionActivityUpdateInterval() {
@@ -81,7 +91,47 @@ func RequireAuth(c *fiber.Ctx) error {
 		}
 	}
 
+	return userID, sessionID, nil
+}
+
+func RequireAuth(c *fiber.Ctx) error {
+	userID, sessionID, err := authenticate(c.Get("Authorization"))
+	if err != nil {
+		return c.Status(err.Code).JSON(fiber.Map{"error": err.Message})
+	}
+
 	c.Locals("user_id", userID)
 	c.Locals("session_id", sessionID)
 	return c.Next()
 }
+
+// OptionalAuth identifies the caller on public routes when a valid token is
+// sent. Missing or invalid tokens are ignored and the request continues
+// anonymously.
+func OptionalAuth(c *fiber.Ctx) error {
+	if c.Get("Authorization") == "" {
+		return c.Next()
+	}
+	if userID, sessionID, err := authenticate(c.Get("Authorization")); err == nil {
+		c.Locals("user_id", userID)
+		c.Locals("session_id", sessionID)
+	}
+	return c.Next()
+}
+
+// RequireAdmin lets only admins through. It runs after RequireAuth and
+// checks the database, so revoking admin rights takes effect immediately.
+func RequireAdmin(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(string)
+	var user models.User
+	if err := config.DB.Select("is_admin").First(&user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Admin access required"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify admin access"})
+	}
+	if !user.IsAdmin {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Admin access required"})
+	}
+	return c.Next()
+}
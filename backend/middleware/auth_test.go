@@ -2,11 +2,12 @@ package middleware
 
 import (
 	"fmt"
+	"io"
 	"net/http"
-	"os"
 	"testing"
 	"time"
 
+	"github.com/Semkufu95/confessions/Backend/config"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -31,7 +32,7 @@ func setupAuthTestApp() *fiber.App {
 }
 
 func TestRequireAuth_MissingToken(t *testing.T) {
-	os.Setenv("JWT_SECRET", "test-secret")
+	config.App.JWTSecret = "test-secret"
 	app := setupAuthTestApp()
 
 	req, _ := http.NewRequest(http.MethodGet, "/protected", nil)
@@ -46,7 +47,7 @@ func TestRequireAuth_MissingToken(t *testing.T) {
 
 func TestRequireAuth_ValidBearerToken(t *testing.T) {
 	secret := "test-secret"
-	os.Setenv("JWT_SECRET", secret)
+	config.App.JWTSecret = secret
 	app := setupAuthTestApp()
 
 	token := makeToken(t, secret, jwt.SigningMethodHS256, jwt.MapClaims{
@@ -69,7 +70,7 @@ func TestRequireAuth_ValidBearerToken(t *testing.T) {
 
 func TestRequireAuth_RejectsWrongSigningMethod(t *testing.T) {
 	secret := "test-secret"
-	os.Setenv("JWT_SECRET", secret)
+	config.App.JWTSecret = secret
 	app := setupAuthTestApp()
 
 	token := makeToken(t, secret, jwt.SigningMethodHS384, jwt.MapClaims{
@@ -92,7 +93,7 @@ func TestRequireAuth_RejectsWrongSigningMethod(t *testing.T) {
 
 func TestRequireAuth_RejectsMissingUserIDClaim(t *testing.T) {
 	secret := "test-secret"
-	os.Setenv("JWT_SECRET", secret)
+	config.App.JWTSecret = secret
 	app := setupAuthTestApp()
 
 	token := makeToken(t, secret, jwt.SigningMethodHS256, jwt.MapClaims{
@@ -114,7 +115,7 @@ func TestRequireAuth_RejectsMissingUserIDClaim(t *testing.T) {
 
 func TestRequireAuth_RejectsMissingSessionIDClaim(t *testing.T) {
 	secret := "test-secret"
-	os.Setenv("JWT_SECRET", secret)
+	config.App.JWTSecret = secret
 	app := setupAuthTestApp()
 
 	token := makeToken(t, secret, jwt.SigningMethodHS256, jwt.MapClaims{
@@ -133,3 +134,37 @@ func TestRequireAuth_RejectsMissingSessionIDClaim(t *testing.T) {
 		t.Fatalf("expected status %d, got %d", fiber.StatusUnauthorized, resp.StatusCode)
 	}
 }
+
+func TestOptionalAuth_IgnoresInvalidToken(t *testing.T) {
+	secret := "test-secret"
+	config.App.JWTSecret = secret
+	app := fiber.New()
+	app.Get("/public", OptionalAuth, func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("user_id").(string)
+		return c.Status(fiber.StatusOK).SendString(userID)
+	})
+
+	valid := makeToken(t, secret, jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id":    "user-123",
+		"session_id": "session-123",
+		"exp":        time.Now().Add(time.Hour).Unix(),
+	})
+	for header, want := range map[string]string{
+		"":                   "",
+		"Bearer not-a-token": "",
+		"Bearer " + valid:    "user-123",
+	} {
+		req, _ := http.NewRequest(http.MethodGet, "/public", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != fiber.StatusOK || string(body) != want {
+			t.Fatalf("header %q: expected 200 %q, got %d %q", header, want, resp.StatusCode, body)
+		}
+	}
+}
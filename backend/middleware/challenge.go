@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/challenge"
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/ratelimit"
+	"github.com/Semkufu95/confessions/Backend/reputation"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// RequireChallenge asks for a solved challenge, sent as X-Challenge-Token
+// and X-Challenge-Solution, once the client IP made more than
+// config.App.ChallengeThreshold requests to scope within an hour. Signed-in
+// users of the trusted reputation tier never have to solve one; it runs
+// after OptionalAuth to recognise them.
+func RequireChallenge(scope string) fiber.Handler {
+	risk := ratelimit.Action{Name: "challenge_" + scope, Window: time.Hour, Limits: func() config.TierLimits {
+		threshold := config.App.ChallengeThreshold
+		return config.TierLimits{New: threshold, Member: threshold, Trusted: threshold}
+	}}
+	return func(c *fiber.Ctx) error {
+		verifier := challenge.Current()
+		if verifier == nil {
+			return c.Next()
+		}
+		if raw, _ := c.Locals("user_id").(string); raw != "" {
+			if userID, err := uuid.Parse(raw); err == nil && reputation.CachedTier(c.UserContext(), config.DB, userID) == reputation.TierTrusted {
+				return c.Next()
+			}
+		}
+
+		token := c.Get("X-Challenge-Token")
+		if token == "" {
+			if ratelimit.Allow(c.UserContext(), risk, "ip:"+ClientIP(c), reputation.TierNew).Allowed {
+				return c.Next()
+			}
+			return challengeRequired(c, verifier, scope, "Challenge required")
+		}
+
+		err := verifier.Verify(c.UserContext(), scope, token, c.Get("X-Challenge-Solution"))
+		switch {
+		case err == nil:
+			return c.Next()
+		case errors.Is(err, challenge.ErrExpired):
+			return challengeRequired(c, verifier, scope, "Challenge expired")
+		case errors.Is(err, challenge.ErrUsed):
+			return challengeRequired(c, verifier, scope, "Challenge already used")
+		case errors.Is(err, challenge.ErrInvalid):
+			return challengeRequired(c, verifier, scope, "Invalid challenge solution")
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify challenge"})
+	}
+}
+
+// challengeRequired answers 428 with a fresh challenge, saving the client a
+// round trip to GET /challenge.
+func challengeRequired(c *fiber.Ctx, verifier challenge.Verifier, scope, message string) error {
+	issued, err := verifier.Issue(c.UserContext(), scope)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to issue challenge"})
+	}
+	return c.Status(fiber.StatusPreconditionRequired).JSON(fiber.Map{"error": message, "challenge": issued})
+}
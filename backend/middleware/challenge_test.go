@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/challenge"
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRequireChallenge_AboveThreshold(t *testing.T) {
+	previous := config.App.ChallengeThreshold
+	config.App.ChallengeThreshold = 1
+	challenge.Use(challenge.NewProofOfWork("secret", 8, time.Minute))
+	defer func() {
+		config.App.ChallengeThreshold = previous
+		challenge.Use(nil)
+	}()
+
+	app := fiber.New()
+	app.Post("/", RequireChallenge("test"), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	post := func(token, solution string) (*http.Response, challenge.Challenge) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, "/", nil)
+		if token != "" {
+			req.Header.Set("X-Challenge-Token", token)
+			req.Header.Set("X-Challenge-Solution", solution)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var body struct {
+			Challenge challenge.Challenge `json:"challenge"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return resp, body.Challenge
+	}
+
+	if resp, _ := post("", ""); resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("expected the first request to pass without a challenge, got %d", resp.StatusCode)
+	}
+	resp, issued := post("", "")
+	if resp.StatusCode != fiber.StatusPreconditionRequired || issued.Token == "" || issued.Scope != "test" {
+		t.Fatalf("expected 428 with a challenge, got %d %+v", resp.StatusCode, issued)
+	}
+
+	solution := challenge.Solve(issued)
+	if resp, _ := post(issued.Token, solution); resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("expected a solved challenge to pass, got %d", resp.StatusCode)
+	}
+	if resp, next := post(issued.Token, solution); resp.StatusCode != fiber.StatusPreconditionRequired || next.Token == issued.Token {
+		t.Fatalf("expected a replayed solution to get a new challenge, got %d", resp.StatusCode)
+	}
+}
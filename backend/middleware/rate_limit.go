@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"errors"
+	"math"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/ratelimit"
+	"github.com/Semkufu95/confessions/Backend/reputation"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// ClientIP returns the address of the client. Requests from a trusted proxy
+// (config.App.TrustedProxies) are attributed to the right-most address in
+// X-Forwarded-For that is not itself a trusted proxy: nginx appends the
+// address it saw, so anything to the left of it may be forged.
+func ClientIP(c *fiber.Ctx) string {
+	remote, ok := netip.AddrFromSlice(c.Context().RemoteIP())
+	if !ok {
+		return c.IP()
+	}
+	client := remote.Unmap()
+	if !trustedProxy(client) {
+		return client.String()
+	}
+
+	hops := strings.Split(c.Get(fiber.HeaderXForwardedFor), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client = hop.Unmap()
+		if !trustedProxy(client) {
+			break
+		}
+	}
+	return client.String()
+}
+
+func trustedProxy(addr netip.Addr) bool {
+	for _, prefix := range config.App.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientKey identifies the caller for rate limiting: the user id of a
+// correctly signed token, otherwise the client IP. Sessions are not checked,
+// so this costs no query; a revoked token still gets its own budget and is
+// turned away by RequireAuth.
+func ClientKey(c *fiber.Ctx) string {
+	if userID, _, err := parseToken(c.Get("Authorization")); err == nil {
+		return "user:" + userID
+	}
+	return "ip:" + ClientIP(c)
+}
+
+// RequestRateLimit enforces config.App.RateLimitMax requests per
+// RateLimitWindow on every request skip does not exempt, keyed by ClientKey.
+func RequestRateLimit(skip func(*fiber.Ctx) bool) fiber.Handler {
+	limit := config.TierLimits{New: config.App.RateLimitMax, Member: config.App.RateLimitMax, Trusted: config.App.RateLimitMax}
+	action := ratelimit.Action{Name: "requests", Window: config.App.RateLimitWindow, Limits: func() config.TierLimits {
+		return limit
+	}}
+	return func(c *fiber.Ctx) error {
+		if skip != nil && skip(c) {
+			return c.Next()
+		}
+		_, err := enforce(c, action, ClientKey(c), reputation.TierNew)
+		return err
+	}
+}
+
+// RateLimit enforces action's budget. It runs after RequireAuth or
+// OptionalAuth: signed-in users are counted by id with their reputation
+// tier's budget, anonymous callers by IP with the new tier's budget. Hits
+// the handler answers with a client error other than 429, such as a
+// malformed body or an unknown id, are refunded.
+func RateLimit(action ratelimit.Action) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		subject, tier := budgetSubject(c)
+		decision, err := enforce(c, action, subject, tier)
+		if decision.Allowed && refundable(c, err) {
+			ratelimit.Refund(c.UserContext(), decision)
+		}
+		return err
+	}
+}
+
+// refundable reports whether the handler's response, or the error it
+// returned for the error handler to render, is a client error other than
+// 429.
+func refundable(c *fiber.Ctx, err error) bool {
+	status := c.Response().StatusCode()
+	if err != nil {
+		status = fiber.StatusInternalServerError
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			status = fiberErr.Code
+		}
+	}
+	return status >= 400 && status < 500 && status != fiber.StatusTooManyRequests
+}
+
+// WithinBudget counts a hit against action's budget like RateLimit, for
+// handlers that degrade instead of refusing the request.
+func WithinBudget(c *fiber.Ctx, action ratelimit.Action) bool {
+	subject, tier := budgetSubject(c)
+	return ratelimit.Allow(c.UserContext(), action, subject, tier).Allowed
+}
+
+func budgetSubject(c *fiber.Ctx) (string, string) {
+	if raw, _ := c.Locals("user_id").(string); raw != "" {
+		if userID, err := uuid.Parse(raw); err == nil {
+			return "user:" + raw, reputation.CachedTier(c.UserContext(), config.DB, userID)
+		}
+	}
+	return "ip:" + ClientIP(c), reputation.TierNew
+}
+
+// enforce counts a hit and either answers 429 or runs the rest of the chain.
+func enforce(c *fiber.Ctx, action ratelimit.Action, subject, tier string) (ratelimit.Decision, error) {
+	decision := ratelimit.Allow(c.UserContext(), action, subject, tier)
+	c.Set("X-RateLimit-Limit", strconv.Itoa(decision.Limit))
+	c.Set("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+	c.Set("X-RateLimit-Reset", seconds(decision.Reset))
+	if !decision.Allowed {
+		c.Set(fiber.HeaderRetryAfter, seconds(decision.RetryAfter))
+		return decision, c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "Rate limit exceeded"})
+	}
+	return decision, c.Next()
+}
+
+// seconds rounds d up to whole seconds, and to at least one.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(max(int(math.Ceil(d.Seconds())), 1))
+}
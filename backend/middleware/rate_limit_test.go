@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/ratelimit"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRateLimit_HeadersAndBudgets(t *testing.T) {
+	action := ratelimit.Action{Name: "test_middleware", Window: time.Hour, Limits: func() config.TierLimits {
+		return config.TierLimits{New: 2, Member: 5, Trusted: 10}
+	}}
+	app := fiber.New()
+	app.Post("/", func(c *fiber.Ctx) error {
+		if user := c.Get("X-Test-User"); user != "" {
+			c.Locals("user_id", user)
+		}
+		return c.Next()
+	}, RateLimit(action), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	post := func(user string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, "/", nil)
+		if user != "" {
+			req.Header.Set("X-Test-User", user)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp
+	}
+
+	for i, remaining := range []string{"1", "0"} {
+		resp := post("")
+		if resp.StatusCode != fiber.StatusNoContent {
+			t.Fatalf("request %d: expected 204, got %d", i+1, resp.StatusCode)
+		}
+		if resp.Header.Get("X-RateLimit-Limit") != "2" || resp.Header.Get("X-RateLimit-Remaining") != remaining {
+			t.Fatalf("request %d: unexpected headers %v", i+1, resp.Header)
+		}
+		if reset, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Reset")); reset < 1 || reset > 3600 {
+			t.Fatalf("request %d: expected a reset within the hour, got %q", i+1, resp.Header.Get("X-RateLimit-Reset"))
+		}
+	}
+
+	resp := post("")
+	if resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", resp.StatusCode)
+	}
+	if retry, _ := strconv.Atoi(resp.Header.Get("Retry-After")); retry < 1 || retry > 7200 || resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		t.Fatalf("unexpected headers on 429: %v", resp.Header)
+	}
+
+	// A signed-in user behind the same IP has their own budget.
+	if resp := post("00000000-0000-0000-0000-000000000001"); resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("expected a signed-in user to be counted separately, got %d", resp.StatusCode)
+	}
+}
+
+func TestRateLimit_RefundsClientErrors(t *testing.T) {
+	action := ratelimit.Action{Name: "test_refund", Window: time.Hour, Limits: func() config.TierLimits {
+		return config.TierLimits{New: 1, Member: 1, Trusted: 1}
+	}}
+	app := fiber.New()
+	app.Post("/:status", RateLimit(action), func(c *fiber.Ctx) error {
+		status, _ := strconv.Atoi(c.Params("status"))
+		if c.Query("error") != "" {
+			return fiber.NewError(status, "failed")
+		}
+		return c.SendStatus(status)
+	})
+
+	post := func(path string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, path, nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	// Invalid requests, answered directly or through the error handler,
+	// leave the single hit in the budget.
+	for _, path := range []string{"/400", "/404", "/404?error=1", "/422?error=1"} {
+		if status := post(path); status == fiber.StatusTooManyRequests {
+			t.Fatalf("%s: expected the hit to be refunded", path)
+		}
+	}
+	if status := post("/500?error=1"); status != fiber.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", status)
+	}
+	if status := post("/204"); status != fiber.StatusTooManyRequests {
+		t.Fatalf("expected a server error to spend the budget, got %d", status)
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	config.App.JWTSecret = "test-secret"
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(ClientKey(c))
+	})
+
+	key := func(authorization string) string {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		body := make([]byte, 64)
+		n, _ := resp.Body.Read(body)
+		return string(body[:n])
+	}
+
+	token := makeToken(t, "test-secret", jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id":    "user-123",
+		"session_id": "session-123",
+		"exp":        time.Now().Add(time.Hour).Unix(),
+	})
+	if got := key("Bearer " + token); got != "user:user-123" {
+		t.Fatalf("expected the token's user, got %q", got)
+	}
+	forged := makeToken(t, "other-secret", jwt.SigningMethodHS256, jwt.MapClaims{"user_id": "user-123", "session_id": "s"})
+	if got := key("Bearer " + forged); got != "ip:0.0.0.0" {
+		t.Fatalf("expected a forged token to fall back to the IP, got %q", got)
+	}
+	if got := key(""); got != "ip:0.0.0.0" {
+		t.Fatalf("expected the IP without a token, got %q", got)
+	}
+}
+
+func TestClientIP_TrustedProxies(t *testing.T) {
+	previous := config.App.TrustedProxies
+	defer func() { config.App.TrustedProxies = previous }()
+
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(ClientIP(c))
+	})
+	clientIP := func(forwardedFor string) string {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	// app.Test connects from 0.0.0.0.
+	config.App.TrustedProxies = nil
+	if got := clientIP("203.0.113.9"); got != "0.0.0.0" {
+		t.Fatalf("expected X-Forwarded-For to be ignored from an untrusted peer, got %q", got)
+	}
+
+	config.App.TrustedProxies = []netip.Prefix{netip.MustParsePrefix("0.0.0.0/32"), netip.MustParsePrefix("10.0.0.0/8")}
+	for forwardedFor, want := range map[string]string{
+		"203.0.113.9":                         "203.0.113.9",
+		"198.51.100.1, 203.0.113.9":           "203.0.113.9",
+		"198.51.100.1, 203.0.113.9, 10.0.0.3": "203.0.113.9",
+		"10.0.0.4, 10.0.0.3":                  "10.0.0.4",
+		"forged, 203.0.113.9":                 "203.0.113.9",
+		"203.0.113.9, forged":                 "0.0.0.0",
+		"":                                    "0.0.0.0",
+	} {
+		if got := clientIP(forwardedFor); got != want {
+			t.Fatalf("X-Forwarded-For %q: expected %s, got %s", forwardedFor, want, got)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/redis"
+	"github.com/gofiber/fiber/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// WebsocketTicketTTL is how long a websocket ticket can be redeemed.
+const WebsocketTicketTTL = 30 * time.Second
+
+// ErrTicketsUnavailable is returned while Redis, which holds the tickets so
+// any replica can redeem them, is unavailable.
+var ErrTicketsUnavailable = errors.New("websocket tickets are unavailable")
+
+func websocketTicketKey(ticket string) string {
+	sum := sha256.Sum256([]byte(ticket))
+	return "ws:ticket:" + hex.EncodeToString(sum[:])
+}
+
+// IssueWebsocketTicket returns a random single-use ticket that signs a
+// websocket upgrade in as userID for WebsocketTicketTTL. Browsers cannot set
+// headers on upgrades, and a ticket in the query string is harmless in
+// access logs where a token would not be.
+func IssueWebsocketTicket(ctx context.Context, userID, sessionID string) (string, error) {
+	if !redis.Available() {
+		return "", ErrTicketsUnavailable
+	}
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	ticket := base64.RawURLEncoding.EncodeToString(bytes)
+	if err := redis.Client.Set(ctx, websocketTicketKey(ticket), userID+" "+sessionID, WebsocketTicketTTL).Err(); err != nil {
+		return "", err
+	}
+	return ticket, nil
+}
+
+// redeemWebsocketTicket consumes ticket and returns the ids it was issued
+// for. Unknown, expired and already used tickets return ok false.
+func redeemWebsocketTicket(ctx context.Context, ticket string) (userID, sessionID string, ok bool) {
+	if !redis.Available() {
+		return "", "", false
+	}
+	stored, err := redis.Client.GetDel(ctx, websocketTicketKey(ticket)).Result()
+	if err != nil {
+		if !errors.Is(err, goredis.Nil) {
+			log.Printf("websocket ticket redeem error: %v", err)
+		}
+		return "", "", false
+	}
+	userID, sessionID, ok = strings.Cut(stored, " ")
+	return userID, sessionID, ok
+}
+
+// OptionalTicketAuth is OptionalAuth for websocket upgrades, which carry a
+// ticket from IssueWebsocketTicket as ?ticket= instead of a token. Missing
+// or invalid tickets connect anonymously.
+func OptionalTicketAuth(c *fiber.Ctx) error {
+	if ticket := c.Query("ticket"); ticket != "" {
+		if userID, sessionID, ok := redeemWebsocketTicket(c.UserContext(), ticket); ok {
+			c.Locals("user_id", userID)
+			c.Locals("session_id", sessionID)
+		}
+	}
+	return c.Next()
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/Semkufu95/confessions/Backend/redis"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestOptionalTicketAuth_RedeemsTicketOnce(t *testing.T) {
+	server := miniredis.RunT(t)
+	redis.ConnectRedis(server.Addr())
+	t.Cleanup(func() {
+		redis.Client.Close()
+		redis.Client = nil
+	})
+
+	app := fiber.New()
+	app.Get("/ws", OptionalTicketAuth, func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("user_id").(string)
+		return c.SendString(userID)
+	})
+	connect := func(ticket string) string {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, "/ws?ticket="+ticket, nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	ticket, err := IssueWebsocketTicket(context.Background(), "user-123", "session-123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ttl := server.TTL(websocketTicketKey(ticket)); ttl != WebsocketTicketTTL {
+		t.Fatalf("expected the ticket to expire in %v, got %v", WebsocketTicketTTL, ttl)
+	}
+	if got := connect(ticket); got != "user-123" {
+		t.Fatalf("expected the ticket to sign in user-123, got %q", got)
+	}
+	if got := connect(ticket); got != "" {
+		t.Fatalf("expected a used ticket to connect anonymously, got %q", got)
+	}
+	if got := connect("forged"); got != "" {
+		t.Fatalf("expected an unknown ticket to connect anonymously, got %q", got)
+	}
+}
+
+func TestIssueWebsocketTicket_WithoutRedis(t *testing.T) {
+	if _, err := IssueWebsocketTicket(context.Background(), "user-123", "session-123"); err != ErrTicketsUnavailable {
+		t.Fatalf("expected ErrTicketsUnavailable, got %v", err)
+	}
+}
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS outbox_events;
+DROP TABLE IF EXISTS stats_observations;
+DROP TABLE IF EXISTS user_settings;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS connection_requests;
+DROP TABLE IF EXISTS connections;
+DROP TABLE IF EXISTS reactions;
+DROP TABLE IF EXISTS replies;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS confessions;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,138 @@
+-- Baseline schema. Every statement is idempotent so databases that were
+-- created by the old GORM AutoMigrate can adopt versioned migrations.
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+CREATE TABLE IF NOT EXISTS users (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    username text NOT NULL,
+    email text NOT NULL,
+    password_hash text NOT NULL,
+    is_admin boolean DEFAULT false,
+    email_verified boolean NOT NULL DEFAULT false,
+    email_verification_token_hash text,
+    email_verification_expires_at timestamptz,
+    created_at timestamptz,
+    CONSTRAINT uni_users_username UNIQUE (username),
+    CONSTRAINT uni_users_email UNIQUE (email)
+);
+
+CREATE TABLE IF NOT EXISTS confessions (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id uuid NOT NULL,
+    content text NOT NULL,
+    likes int NOT NULL DEFAULT 0,
+    boos int NOT NULL DEFAULT 0,
+    stars int NOT NULL DEFAULT 0,
+    shares int NOT NULL DEFAULT 0,
+    comments int NOT NULL DEFAULT 0,
+    category text NOT NULL,
+    trending boolean NOT NULL DEFAULT false,
+    created_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS comments (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    confession_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    content text NOT NULL,
+    likes int NOT NULL DEFAULT 0,
+    boos int NOT NULL DEFAULT 0,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_comments_author FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE TABLE IF NOT EXISTS replies (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    comment_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    content text NOT NULL,
+    likes int NOT NULL DEFAULT 0,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_comments_replies FOREIGN KEY (comment_id) REFERENCES comments (id),
+    CONSTRAINT fk_replies_author FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_replies_comment_id ON replies (comment_id);
+
+CREATE TABLE IF NOT EXISTS reactions (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id uuid NOT NULL,
+    confession_id uuid,
+    comment_id uuid,
+    type varchar(10) NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS connections (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id uuid NOT NULL,
+    title text NOT NULL,
+    description text NOT NULL,
+    category text NOT NULL DEFAULT 'friendship',
+    location text,
+    age int,
+    interests text NOT NULL DEFAULT '[]',
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_connections_author FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE TABLE IF NOT EXISTS connection_requests (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    connection_id uuid NOT NULL,
+    sender_id uuid NOT NULL,
+    receiver_id uuid NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_connection_requests_sender FOREIGN KEY (sender_id) REFERENCES users (id),
+    CONSTRAINT fk_connection_requests_connection FOREIGN KEY (connection_id) REFERENCES connections (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_connection_sender ON connection_requests (connection_id, sender_id);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id uuid NOT NULL,
+    last_activity timestamptz NOT NULL,
+    expires_at timestamptz NOT NULL,
+    revoked_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_last_activity ON sessions (last_activity);
+CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions (expires_at);
+CREATE INDEX IF NOT EXISTS idx_sessions_revoked_at ON sessions (revoked_at);
+
+CREATE TABLE IF NOT EXISTS user_settings (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id uuid NOT NULL,
+    push_notifications boolean NOT NULL DEFAULT true,
+    email_notifications boolean NOT NULL DEFAULT false,
+    comment_replies boolean NOT NULL DEFAULT true,
+    new_followers boolean NOT NULL DEFAULT false,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_settings_user_id ON user_settings (user_id);
+
+CREATE TABLE IF NOT EXISTS stats_observations (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    observed_at timestamptz NOT NULL,
+    online int NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_stats_observations_observed_at ON stats_observations (observed_at);
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id uuid PRIMARY KEY,
+    channel text NOT NULL,
+    payload text NOT NULL,
+    attempts int NOT NULL DEFAULT 0,
+    last_error text,
+    available_at timestamptz NOT NULL,
+    delivered_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox_events (delivered_at, available_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_reactions_user_comment;
+DROP INDEX IF EXISTS idx_reactions_user_confession;
@@ -0,0 +1,35 @@
+-- Keep only the most recent reaction per user and target before enforcing
+-- uniqueness. Counters are repaired by the counter reconciler.
+DELETE FROM reactions
+WHERE id IN (
+    SELECT id FROM (
+        SELECT id, row_number() OVER (
+            PARTITION BY user_id, confession_id
+            ORDER BY updated_at DESC NULLS LAST, id DESC
+        ) AS position
+        FROM reactions
+        WHERE confession_id IS NOT NULL AND comment_id IS NULL
+    ) ranked
+    WHERE position > 1
+);
+
+DELETE FROM reactions
+WHERE id IN (
+    SELECT id FROM (
+        SELECT id, row_number() OVER (
+            PARTITION BY user_id, comment_id
+            ORDER BY updated_at DESC NULLS LAST, id DESC
+        ) AS position
+        FROM reactions
+        WHERE comment_id IS NOT NULL
+    ) ranked
+    WHERE position > 1
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reactions_user_confession
+    ON reactions (user_id, confession_id)
+    WHERE confession_id IS NOT NULL AND comment_id IS NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reactions_user_comment
+    ON reactions (user_id, comment_id)
+    WHERE comment_id IS NOT NULL;
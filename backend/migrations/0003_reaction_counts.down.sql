@@ -0,0 +1,2 @@
+ALTER TABLE comments DROP COLUMN IF EXISTS reaction_counts;
+ALTER TABLE confessions DROP COLUMN IF EXISTS reaction_counts;
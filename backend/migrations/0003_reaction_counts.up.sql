@@ -0,0 +1,32 @@
+-- Per-type reaction totals. likes and boos stay as columns for older clients
+-- and are kept in step with the like and boo entries here.
+ALTER TABLE confessions ADD COLUMN IF NOT EXISTS reaction_counts jsonb NOT NULL DEFAULT '{}';
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS reaction_counts jsonb NOT NULL DEFAULT '{}';
+
+UPDATE confessions c
+SET reaction_counts = t.counts
+FROM (
+    SELECT confession_id, jsonb_object_agg(type, total) AS counts
+    FROM (
+        SELECT confession_id, type, count(*) AS total
+        FROM reactions
+        WHERE confession_id IS NOT NULL AND comment_id IS NULL
+        GROUP BY confession_id, type
+    ) per_type
+    GROUP BY confession_id
+) t
+WHERE c.id = t.confession_id;
+
+UPDATE comments m
+SET reaction_counts = t.counts
+FROM (
+    SELECT comment_id, jsonb_object_agg(type, total) AS counts
+    FROM (
+        SELECT comment_id, type, count(*) AS total
+        FROM reactions
+        WHERE comment_id IS NOT NULL
+        GROUP BY comment_id, type
+    ) per_type
+    GROUP BY comment_id
+) t
+WHERE m.id = t.comment_id;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_reactions_user_recent;
+DROP INDEX IF EXISTS idx_reactions_comment_listing;
+DROP INDEX IF EXISTS idx_reactions_confession_listing;
@@ -0,0 +1,12 @@
+-- Keyset pagination for the reaction listings: per target grouped by type,
+-- and per user newest first.
+CREATE INDEX IF NOT EXISTS idx_reactions_confession_listing
+    ON reactions (confession_id, type, updated_at DESC, id DESC)
+    WHERE confession_id IS NOT NULL AND comment_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_reactions_comment_listing
+    ON reactions (comment_id, type, updated_at DESC, id DESC)
+    WHERE comment_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_reactions_user_recent
+    ON reactions (user_id, updated_at DESC, id DESC);
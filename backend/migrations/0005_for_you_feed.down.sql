@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_comments_user_created;
+DROP INDEX IF EXISTS idx_confessions_created_at;
+DROP TABLE IF EXISTS user_mutes;
+ALTER TABLE user_settings DROP COLUMN IF EXISTS muted_categories;
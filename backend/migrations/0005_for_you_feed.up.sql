@@ -0,0 +1,14 @@
+ALTER TABLE user_settings ADD COLUMN IF NOT EXISTS muted_categories jsonb NOT NULL DEFAULT '[]';
+
+CREATE TABLE IF NOT EXISTS user_mutes (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    muted_user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_mutes_pair ON user_mutes (user_id, muted_user_id);
+
+-- Candidate selection takes the newest confessions; category affinity reads a
+-- user's recent comments (recent reactions use idx_reactions_user_recent).
+CREATE INDEX IF NOT EXISTS idx_confessions_created_at ON confessions (created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_comments_user_created ON comments (user_id, created_at DESC);
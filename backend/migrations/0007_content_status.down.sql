@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_comments_pending;
+DROP INDEX IF EXISTS idx_confessions_pending;
+ALTER TABLE comments DROP COLUMN IF EXISTS status;
+ALTER TABLE confessions DROP COLUMN IF EXISTS status;
@@ -0,0 +1,5 @@
+ALTER TABLE confessions ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'published';
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'published';
+-- Held posts are few; keep them cheap to find for review.
+CREATE INDEX IF NOT EXISTS idx_confessions_pending ON confessions (created_at) WHERE status <> 'published';
+CREATE INDEX IF NOT EXISTS idx_comments_pending ON comments (created_at) WHERE status <> 'published';
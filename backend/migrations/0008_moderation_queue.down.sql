@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS reports;
+ALTER TABLE comments
+    DROP COLUMN IF EXISTS moderated_at,
+    DROP COLUMN IF EXISTS moderated_by,
+    DROP COLUMN IF EXISTS moderation_reason;
+ALTER TABLE confessions
+    DROP COLUMN IF EXISTS moderated_at,
+    DROP COLUMN IF EXISTS moderated_by,
+    DROP COLUMN IF EXISTS moderation_reason;
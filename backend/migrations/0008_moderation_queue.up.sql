@@ -0,0 +1,23 @@
+ALTER TABLE confessions
+    ADD COLUMN IF NOT EXISTS moderation_reason text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS moderated_by uuid REFERENCES users (id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS moderated_at timestamptz;
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS moderation_reason text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS moderated_by uuid REFERENCES users (id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS moderated_at timestamptz;
+
+CREATE TABLE IF NOT EXISTS reports (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    reporter_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    confession_id uuid REFERENCES confessions (id) ON DELETE SET NULL,
+    comment_id uuid REFERENCES comments (id) ON DELETE SET NULL,
+    reason text NOT NULL,
+    created_at timestamptz
+);
+-- One report per reporter and post; reports outlive deleted posts so they
+-- still count against the author.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_reporter_confession ON reports (reporter_id, confession_id) WHERE confession_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_reporter_comment ON reports (reporter_id, comment_id) WHERE comment_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_reports_user ON reports (user_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_connection_requests_sender;
+DROP TABLE IF EXISTS user_reputations;
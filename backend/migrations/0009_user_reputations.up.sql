@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS user_reputations (
+    user_id uuid PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    reactions_received int NOT NULL DEFAULT 0,
+    upheld_reports int NOT NULL DEFAULT 0,
+    requests_answered int NOT NULL DEFAULT 0,
+    requests_accepted int NOT NULL DEFAULT 0,
+    updated_at timestamptz
+);
+-- Refreshes count requests per sender.
+CREATE INDEX IF NOT EXISTS idx_connection_requests_sender ON connection_requests (sender_id, status);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS share_clicks;
+DROP TABLE IF EXISTS shares;
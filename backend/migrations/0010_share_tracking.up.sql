@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS shares (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    confession_id uuid NOT NULL REFERENCES confessions (id) ON DELETE CASCADE,
+    user_id uuid REFERENCES users (id) ON DELETE SET NULL,
+    channel text NOT NULL,
+    fingerprint text NOT NULL,
+    code text NOT NULL,
+    clicks int NOT NULL DEFAULT 0,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shares_code ON shares (code);
+-- Looks up a sharer's recent share of a confession on a channel.
+CREATE INDEX IF NOT EXISTS idx_shares_dedup ON shares (confession_id, fingerprint, channel, created_at);
+
+-- One row per person who opened a share link; clicks counts them.
+CREATE TABLE IF NOT EXISTS share_clicks (
+    share_id uuid NOT NULL REFERENCES shares (id) ON DELETE CASCADE,
+    fingerprint text NOT NULL,
+    created_at timestamptz,
+    PRIMARY KEY (share_id, fingerprint)
+);
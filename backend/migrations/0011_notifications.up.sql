@@ -0,0 +1,19 @@
+-- In-app notifications. dedupe_key, when set, makes repeats of the same
+-- notification (e.g. one person reacting again) collapse into the first.
+CREATE TABLE IF NOT EXISTS notifications (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    actor_id uuid REFERENCES users (id) ON DELETE SET NULL,
+    kind text NOT NULL,
+    confession_id uuid REFERENCES confessions (id) ON DELETE CASCADE,
+    comment_id uuid REFERENCES comments (id) ON DELETE CASCADE,
+    connection_request_id uuid REFERENCES connection_requests (id) ON DELETE CASCADE,
+    title text NOT NULL,
+    body text NOT NULL DEFAULT '',
+    dedupe_key text,
+    read_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_created ON notifications (user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications (user_id) WHERE read_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_dedupe ON notifications (user_id, dedupe_key);
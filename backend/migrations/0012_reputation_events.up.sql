@@ -0,0 +1,7 @@
+-- Events the reputation worker already applied, so each replica's copy of an
+-- event moves the signals only once.
+CREATE TABLE IF NOT EXISTS reputation_events (
+    event_id uuid PRIMARY KEY,
+    applied_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_reputation_events_applied_at ON reputation_events (applied_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_shares_dedup;
+CREATE INDEX IF NOT EXISTS idx_shares_dedup ON shares (confession_id, fingerprint, channel, created_at);
@@ -0,0 +1,3 @@
+-- Shares are deduplicated per confession and sharer, whatever the channel.
+DROP INDEX IF EXISTS idx_shares_dedup;
+CREATE INDEX IF NOT EXISTS idx_shares_dedup ON shares (confession_id, fingerprint, created_at);
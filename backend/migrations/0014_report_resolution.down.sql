@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_reports_open_comment;
+DROP INDEX IF EXISTS idx_reports_open_confession;
+ALTER TABLE reports
+    DROP COLUMN IF EXISTS resolved_at,
+    DROP COLUMN IF EXISTS resolved_by;
@@ -0,0 +1,6 @@
+-- Reports stay open until an admin decides on the reported post.
+ALTER TABLE reports
+    ADD COLUMN IF NOT EXISTS resolved_by uuid REFERENCES users (id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS resolved_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_reports_open_confession ON reports (confession_id, created_at) WHERE resolved_at IS NULL AND confession_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_reports_open_comment ON reports (comment_id, created_at) WHERE resolved_at IS NULL AND comment_id IS NOT NULL;
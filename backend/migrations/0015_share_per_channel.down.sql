@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_shares_counted;
+DROP INDEX IF EXISTS idx_shares_sharer_channel;
+CREATE INDEX IF NOT EXISTS idx_shares_dedup ON shares (confession_id, fingerprint, created_at);
+ALTER TABLE shares DROP COLUMN IF EXISTS counted_at;
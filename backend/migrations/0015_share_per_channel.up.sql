@@ -0,0 +1,13 @@
+-- One share row and code per confession, sharer and channel. Repeats on a
+-- channel used to get new rows; the newest stays the sharer's link and older
+-- ones keep their codes and clicks but no longer match the sharer.
+ALTER TABLE shares ADD COLUMN IF NOT EXISTS counted_at timestamptz;
+-- Every existing row incremented the counter when it was created.
+UPDATE shares SET counted_at = created_at WHERE counted_at IS NULL;
+UPDATE shares s SET fingerprint = s.fingerprint || ':' || s.id
+FROM shares newer
+WHERE newer.confession_id = s.confession_id AND newer.fingerprint = s.fingerprint AND newer.channel = s.channel
+    AND (newer.created_at, newer.id) > (s.created_at, s.id);
+DROP INDEX IF EXISTS idx_shares_dedup;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shares_sharer_channel ON shares (confession_id, fingerprint, channel);
+CREATE INDEX IF NOT EXISTS idx_shares_counted ON shares (confession_id, fingerprint, counted_at);
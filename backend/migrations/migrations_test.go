@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestAll_EmbeddedMigrationsParse(t *testing.T) {
+	migrations, err := All()
+	if err != nil {
+		t.Fatalf("embedded migrations are invalid: %v", err)
+	}
+	if len(migrations) == 0 || migrations[0].Version != 1 {
+		t.Fatalf("expected the baseline migration first, got %+v", migrations)
+	}
+}
+
+func TestParse_OrdersByVersion(t *testing.T) {
+	migrations, err := Parse(fstest.MapFS{
+		"0010_add_index.up.sql":   {Data: []byte("CREATE INDEX a ON t (c);")},
+		"0010_add_index.down.sql": {Data: []byte("DROP INDEX a;")},
+		"0002_add_table.up.sql":   {Data: []byte("CREATE TABLE t (c int);")},
+		"0002_add_table.down.sql": {Data: []byte("DROP TABLE t;")},
+		"README.md":               {Data: []byte("ignored")},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].Version != 2 || migrations[1].Name != "add_index" {
+		t.Fatalf("unexpected migrations: %+v", migrations)
+	}
+}
+
+func TestParse_RejectsIncompleteMigrations(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"missing down": {"0001_init.up.sql": {Data: []byte("SELECT 1;")}},
+		"bad version":  {"abc_init.up.sql": {Data: []byte("SELECT 1;")}},
+		"bad name":     {"0001.up.sql": {Data: []byte("SELECT 1;")}},
+	}
+	for name, fsys := range cases {
+		if _, err := Parse(fsys); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
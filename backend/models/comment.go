@@ -13,9 +13,17 @@ type Comment struct {
 	Content      string    `gorm:"type:text;not null" json:"content"`
 	Likes        int       `gorm:"type:int;not null;default:0" json:"likes"`
 	Boos         int       `gorm:"type:int;not null;default:0" json:"boos"`
+	Status       string    `gorm:"type:text;not null;default:'published'" json:"status"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 
+	ModerationReason string     `gorm:"type:text;not null;default:''" json:"moderation_reason,omitempty"`
+	ModeratedBy      *uuid.UUID `gorm:"type:uuid" json:"-"`
+	ModeratedAt      *time.Time `json:"moderated_at,omitempty"`
+
+	Reactions  ReactionCounts `gorm:"column:reaction_counts;type:jsonb;not null;default:'{}'" json:"reactions"`
+	MyReaction string         `gorm:"-" json:"my_reaction,omitempty"`
+
 	Author  User    `gorm:"foreignKey:UserID;references:ID" json:"author"`
 	Replies []Reply `gorm:"foreignKey:CommentID;references:ID" json:"replies,omitempty"`
 }
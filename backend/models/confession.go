@@ -17,5 +17,18 @@ type Confession struct {
 	Comments  int       `gorm:"type:int;not null;default:0" json:"comments"`
 	Category  string    `gorm:"type:text;not null" json:"category"`
 	Trending  bool      `gorm:"type:boolean;not null;default:false" json:"trending"`
+	Status    string    `gorm:"type:text;not null;default:'published'" json:"status"`
 	CreatedAt time.Time `json:"created_at"`
+
+	// ModerationReason says why a post was held or rejected. ModeratedBy
+	// and ModeratedAt record the admin decision.
+	ModerationReason string     `gorm:"type:text;not null;default:''" json:"moderation_reason,omitempty"`
+	ModeratedBy      *uuid.UUID `gorm:"type:uuid" json:"-"`
+	ModeratedAt      *time.Time `json:"moderated_at,omitempty"`
+
+	// Reactions holds per-type totals; Likes and Boos mirror the like and boo
+	// entries for older clients.
+	Reactions ReactionCounts `gorm:"column:reaction_counts;type:jsonb;not null;default:'{}'" json:"reactions"`
+	// MyReaction is the requesting user's reaction type, filled per request.
+	MyReaction string `gorm:"-" json:"my_reaction,omitempty"`
 }
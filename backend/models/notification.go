@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notification kinds.
+const (
+	// NotificationComment: someone commented on your confession.
+	NotificationComment = "comment"
+	// NotificationCommentReply: someone commented on a confession after you.
+	NotificationCommentReply = "comment_reply"
+	// NotificationReaction: someone reacted to your confession or comment.
+	NotificationReaction = "reaction"
+	// NotificationConnectionRequest: someone asked to connect on your post.
+	NotificationConnectionRequest = "connection_request"
+	// NotificationConnectionResponse: your connection request was accepted
+	// or declined.
+	NotificationConnectionResponse = "connection_response"
+	// NotificationModeration: an admin published, rejected or removed your
+	// confession or comment.
+	NotificationModeration = "moderation"
+)
+
+// Notification is an in-app notification for UserID. ActorID is who caused
+// it; it is never exposed because confessions and reactions are anonymous.
+// DedupeKey collapses repeats into the first notification.
+type Notification struct {
+	ID                  uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
+	UserID              uuid.UUID  `gorm:"type:uuid;not null" json:"-"`
+	ActorID             *uuid.UUID `gorm:"type:uuid" json:"-"`
+	Kind                string     `gorm:"type:text;not null" json:"kind"`
+	ConfessionID        *uuid.UUID `gorm:"type:uuid" json:"confession_id,omitempty"`
+	CommentID           *uuid.UUID `gorm:"type:uuid" json:"comment_id,omitempty"`
+	ConnectionRequestID *uuid.UUID `gorm:"type:uuid" json:"connection_request_id,omitempty"`
+	Title               string     `gorm:"type:text;not null" json:"title"`
+	Body                string     `gorm:"type:text;not null;default:''" json:"body"`
+	DedupeKey           *string    `gorm:"type:text" json:"-"`
+	ReadAt              *time.Time `json:"read_at"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
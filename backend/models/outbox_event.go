@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboxEvent is an event envelope waiting to be relayed to Redis. Rows are
+// written in the same transaction as the change they describe.
+type OutboxEvent struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	Channel     string     `gorm:"type:text;not null" json:"channel"`
+	Payload     string     `gorm:"type:text;not null" json:"payload"`
+	Attempts    int        `gorm:"type:int;not null;default:0" json:"attempts"`
+	LastError   string     `gorm:"type:text" json:"last_error,omitempty"`
+	AvailableAt time.Time  `gorm:"not null;index:idx_outbox_pending,priority:2" json:"available_at"`
+	DeliveredAt *time.Time `gorm:"index:idx_outbox_pending,priority:1" json:"delivered_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
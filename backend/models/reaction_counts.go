@@ -0,0 +1,48 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// ReactionCounts maps a reaction type to how many users chose it. It is
+// stored as a jsonb object and only holds types with a non-zero count.
+type ReactionCounts map[string]int
+
+func (r ReactionCounts) Value() (driver.Value, error) {
+	if r == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(map[string]int(r))
+	return string(data), err
+}
+
+func (r *ReactionCounts) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*r = ReactionCounts{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into ReactionCounts", value)
+	}
+	counts := map[string]int{}
+	if err := json.Unmarshal(data, &counts); err != nil {
+		return err
+	}
+	*r = counts
+	return nil
+}
+
+// MarshalJSON renders an empty object rather than null.
+func (r ReactionCounts) MarshalJSON() ([]byte, error) {
+	if r == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(map[string]int(r))
+}
@@ -11,7 +11,7 @@ type Reaction struct {
 	UserID       uuid.UUID  `gorm:"type:uuid;not null" json:"user_id"`
 	ConfessionID *uuid.UUID `gorm:"type:uuid" json:"confession_id,omitempty"`
 	CommentID    *uuid.UUID `gorm:"type:uuid" json:"comment_id,omitempty"`
-	Type         string     `gorm:"type:varchar(10);not null" json:"type"` // "like" or "boo"
+	Type         string     `gorm:"type:varchar(10);not null" json:"type"` // one of config.App.ReactionTypes
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
 }
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Report flags a confession or comment for review. UserID is the reported
+// author. Open reports put a published post in the admin queue until an
+// admin keeps or rejects it, which resolves them; the low-trust check counts
+// the ones admins upheld by rejecting the post.
+type Report struct {
+	ID           uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
+	ReporterID   uuid.UUID  `gorm:"type:uuid;not null" json:"-"`
+	UserID       uuid.UUID  `gorm:"type:uuid;not null" json:"-"`
+	ConfessionID *uuid.UUID `gorm:"type:uuid" json:"confession_id,omitempty"`
+	CommentID    *uuid.UUID `gorm:"type:uuid" json:"comment_id,omitempty"`
+	Reason       string     `gorm:"type:text;not null" json:"reason"`
+	ResolvedBy   *uuid.UUID `gorm:"type:uuid" json:"-"`
+	ResolvedAt   *time.Time `json:"resolved_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Share is one person sharing a confession on a channel; each person gets
+// one row and code per channel. Fingerprint is a keyed hash of who shared it
+// (their user id, or their client when anonymous). Code identifies the link
+// they were given, Clicks counts the distinct people who opened it, and
+// CountedAt is when sharing it last incremented the confession's counter.
+type Share struct {
+	ID           uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
+	ConfessionID uuid.UUID  `gorm:"type:uuid;not null" json:"confession_id"`
+	UserID       *uuid.UUID `gorm:"type:uuid" json:"-"`
+	Channel      string     `gorm:"type:text;not null" json:"channel"`
+	Fingerprint  string     `gorm:"type:text;not null" json:"-"`
+	Code         string     `gorm:"type:text;not null;uniqueIndex" json:"code"`
+	Clicks       int        `gorm:"type:int;not null;default:0" json:"clicks"`
+	CountedAt    *time.Time `json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// ShareClick records that someone opened a share link.
+type ShareClick struct {
+	ShareID     uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Fingerprint string    `gorm:"type:text;primaryKey"`
+	CreatedAt   time.Time
+}
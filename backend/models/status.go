@@ -0,0 +1,17 @@
+package models
+
+import "gorm.io/gorm"
+
+// Publication states of confessions and comments. Pending posts were held by
+// the content filter or pre-moderation and wait for an admin; rejected posts
+// are kept for the record. Both are only visible to their author.
+const (
+	StatusPublished = "published"
+	StatusPending   = "pending"
+	StatusRejected  = "rejected"
+)
+
+// Published limits a query to published rows.
+func Published(db *gorm.DB) *gorm.DB {
+	return db.Where("status = ?", StatusPublished)
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserBlock separates two users in both directions: neither sees the other's
+// comments, confessions or connections, and BlockedUserID cannot send UserID
+// connection requests.
+type UserBlock struct {
+	ID            uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
+	UserID        uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_user_blocks_pair" json:"user_id"`
+	BlockedUserID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_user_blocks_pair" json:"blocked_user_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserMute hides MutedUserID's confessions, comments and connections from
+// UserID. Unlike a block it is one-sided and invisible to the muted user.
+type UserMute struct {
+	ID          uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
+	UserID      uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_user_mutes_pair" json:"user_id"`
+	MutedUserID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_user_mutes_pair" json:"muted_user_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserReputation holds the counted signals behind a user's reputation. The
+// score itself depends on account age and is computed by the reputation
+// package on read.
+type UserReputation struct {
+	UserID            uuid.UUID `gorm:"type:uuid;primaryKey" json:"user_id"`
+	ReactionsReceived int       `gorm:"type:int;not null;default:0" json:"reactions_received"`
+	UpheldReports     int       `gorm:"type:int;not null;default:0" json:"upheld_reports"`
+	RequestsAnswered  int       `gorm:"type:int;not null;default:0" json:"requests_answered"`
+	RequestsAccepted  int       `gorm:"type:int;not null;default:0" json:"requests_accepted"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
@@ -13,6 +13,19 @@ type UserSettings struct {
 	EmailNotifications bool      `gorm:"not null;default:false" json:"email_notifications"`
 	CommentReplies     bool      `gorm:"not null;default:true" json:"comment_replies"`
 	NewFollowers       bool      `gorm:"not null;default:false" json:"new_followers"`
-	CreatedAt          time.Time `json:"created_at"`
-	UpdatedAt          time.Time `json:"updated_at"`
+	// MutedCategories are left out of the personalized feed.
+	MutedCategories StringList `gorm:"type:jsonb;not null;default:'[]'" json:"muted_categories"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// DefaultUserSettings are the settings of a user who never changed them.
+func DefaultUserSettings(userID uuid.UUID) UserSettings {
+	return UserSettings{
+		UserID:             userID,
+		PushNotifications:  true,
+		EmailNotifications: false,
+		CommentReplies:     true,
+		NewFollowers:       false,
+	}
 }
@@ -0,0 +1,88 @@
+// Package notifications stores in-app notifications. They are written in
+// the transaction of the change that caused them, so a notification exists
+// exactly when its cause does, and their live delivery goes through the
+// event outbox to the recipient's websockets only.
+package notifications
+
+import (
+	"github.com/Semkufu95/confessions/Backend/events"
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/Semkufu95/confessions/Backend/relations"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Notify stores n for n.UserID in tx. Nothing is stored when the recipient
+// caused it, switched its kind off in their settings, or muted or blocked
+// the actor (or was blocked by them). With push notifications on, the new
+// notification is also queued for live delivery.
+func Notify(tx *gorm.DB, n models.Notification) error {
+	if n.ActorID != nil && *n.ActorID == n.UserID {
+		return nil
+	}
+	settings, err := settingsOf(tx, n.UserID)
+	if err != nil {
+		return err
+	}
+	if !Enabled(settings, n.Kind) {
+		return nil
+	}
+	if n.ActorID != nil {
+		// Read inside the transaction rather than from the cache, so a
+		// block committed just before is honoured.
+		hidden, err := relations.Hidden(tx.Statement.Context, tx, n.UserID)
+		if err != nil {
+			return err
+		}
+		if hidden[*n.ActorID] {
+			return nil
+		}
+	}
+
+	result := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "dedupe_key"}},
+		DoNothing: true,
+	}).Create(&n)
+	if result.Error != nil || result.RowsAffected == 0 || !settings.PushNotifications {
+		return result.Error
+	}
+
+	unread, err := Unread(tx, n.UserID)
+	if err != nil {
+		return err
+	}
+	// The actor is left out of the envelope like it is left out of the
+	// notification.
+	return events.Enqueue(tx, "", events.NotificationCreated{UserID: n.UserID, Notification: n, Unread: unread})
+}
+
+// Enabled reports whether settings let kind through. Reactions and
+// moderation decisions have no toggle of their own.
+func Enabled(settings models.UserSettings, kind string) bool {
+	switch kind {
+	case models.NotificationComment, models.NotificationCommentReply:
+		return settings.CommentReplies
+	case models.NotificationConnectionRequest, models.NotificationConnectionResponse:
+		return settings.NewFollowers
+	}
+	return true
+}
+
+// Unread counts userID's unread notifications.
+func Unread(db *gorm.DB, userID uuid.UUID) (int64, error) {
+	var unread int64
+	err := db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&unread).Error
+	return unread, err
+}
+
+func settingsOf(db *gorm.DB, userID uuid.UUID) (models.UserSettings, error) {
+	var settings []models.UserSettings
+	if err := db.Where("user_id = ?", userID).Limit(1).Find(&settings).Error; err != nil {
+		return models.UserSettings{}, err
+	}
+	if len(settings) == 0 {
+		return models.DefaultUserSettings(userID), nil
+	}
+	return settings[0], nil
+}
@@ -0,0 +1,36 @@
+package notifications
+
+import (
+	"testing"
+
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/google/uuid"
+)
+
+func TestEnabled_FollowsSettingsToggles(t *testing.T) {
+	settings := models.DefaultUserSettings(uuid.New())
+	for kind, want := range map[string]bool{
+		models.NotificationComment:            true,
+		models.NotificationCommentReply:       true,
+		models.NotificationReaction:           true,
+		models.NotificationConnectionRequest:  false,
+		models.NotificationConnectionResponse: false,
+	} {
+		if got := Enabled(settings, kind); got != want {
+			t.Errorf("default settings: Enabled(%s) = %v, want %v", kind, got, want)
+		}
+	}
+
+	settings.CommentReplies = false
+	settings.NewFollowers = true
+	settings.PushNotifications = false
+	if Enabled(settings, models.NotificationComment) || Enabled(settings, models.NotificationCommentReply) {
+		t.Error("comment notifications should follow CommentReplies")
+	}
+	if !Enabled(settings, models.NotificationConnectionRequest) || !Enabled(settings, models.NotificationConnectionResponse) {
+		t.Error("connection notifications should follow NewFollowers")
+	}
+	if !Enabled(settings, models.NotificationReaction) {
+		t.Error("push notifications only control live delivery, not storage")
+	}
+}
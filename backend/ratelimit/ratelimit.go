@@ -0,0 +1,116 @@
+// Package ratelimit enforces per-action budgets, such as confessions per hour,
+// for each user. Budgets depend on the user's reputation tier and are counted
+// in Redis so every replica shares them.
+package ratelimit
+
+import (
+	"context"
+	"expvar"
+	"math"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/reputation"
+)
+
+// Action is something with its own budget.
+type Action struct {
+	Name   string
+	Window time.Duration
+	// Limits returns the budget of each tier; it is read on every request
+	// so tests and config reloads take effect.
+	Limits func() config.TierLimits
+}
+
+// Budgeted actions.
+var (
+	Confessions = Action{Name: "confessions", Window: time.Hour, Limits: func() config.TierLimits {
+		return config.App.ConfessionRateLimit
+	}}
+	Comments = Action{Name: "comments", Window: time.Minute, Limits: func() config.TierLimits {
+		return config.App.CommentRateLimit
+	}}
+	ConnectionRequests = Action{Name: "connection_requests", Window: 24 * time.Hour, Limits: func() config.TierLimits {
+		return config.App.ConnectionRequestRateLimit
+	}}
+	ContactMessages = Action{Name: "contact_messages", Window: 24 * time.Hour, Limits: func() config.TierLimits {
+		return config.App.ContactRateLimit
+	}}
+	ShareClicks = Action{Name: "share_clicks", Window: time.Hour, Limits: func() config.TierLimits {
+		return config.App.ShareClickRateLimit
+	}}
+)
+
+// Limit returns the action's budget for a reputation tier.
+func (a Action) Limit(tier string) int {
+	limits := a.Limits()
+	switch tier {
+	case reputation.TierTrusted:
+		return limits.Trusted
+	case reputation.TierMember:
+		return limits.Member
+	}
+	return limits.New
+}
+
+// Decision is the outcome of one hit.
+type Decision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the current window ends.
+	Reset time.Duration
+	// RetryAfter is, for refused hits, how long until a hit would be
+	// allowed again.
+	RetryAfter time.Duration
+
+	counted counts
+}
+
+var metrics = expvar.NewMap("rate_limit")
+
+// now is the clock budgets are counted with.
+var now = time.Now
+
+// Allow counts a hit by subject (a user or client key) against the action's
+// budget for tier.
+func Allow(ctx context.Context, action Action, subject, tier string) Decision {
+	limit := action.Limit(tier)
+	result, elapsed := hit(ctx, "ratelimit:"+action.Name+":"+subject, limit, action.Window, now())
+
+	decision := Decision{Allowed: result.allowed, Limit: limit, Reset: action.Window - elapsed, counted: result}
+	if !result.allowed {
+		decision.RetryAfter = retryAfter(limit, result, elapsed, action.Window)
+		metrics.Add("limited:"+action.Name, 1)
+		return decision
+	}
+	used := estimate(result.current, result.previous, elapsed, action.Window)
+	if remaining := math.Ceil(float64(limit) - used); remaining > 0 {
+		decision.Remaining = int(remaining)
+	}
+	return decision
+}
+
+// Refund takes back the hit an allowed decision counted, for requests that
+// turned out to be invalid. Refused hits were never counted.
+func Refund(ctx context.Context, decision Decision) {
+	if !decision.Allowed {
+		return
+	}
+	refund(ctx, decision.counted, now())
+}
+
+// retryAfter is how long a refused client has to wait until the sliding
+// window holds fewer than limit hits: until the previous window's share
+// has faded enough, or, when the current window alone is full, until the
+// current window's share has faded in the next one.
+func retryAfter(limit int, result counts, elapsed, window time.Duration) time.Duration {
+	if result.current < int64(limit) {
+		if result.previous == 0 {
+			return 0
+		}
+		fade := float64(window) * float64(int64(limit)-result.current) / float64(result.previous)
+		return window - elapsed - time.Duration(fade)
+	}
+	return window - elapsed + time.Duration(float64(window)*(1-float64(limit)/float64(result.current)))
+}
@@ -0,0 +1,154 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/config"
+	"github.com/Semkufu95/confessions/Backend/redis"
+	"github.com/Semkufu95/confessions/Backend/reputation"
+	"github.com/alicebob/miniredis/v2"
+)
+
+// withRedis points the redis package at a local stand-in for the test.
+func withRedis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	server := miniredis.RunT(t)
+	redis.ConnectRedis(server.Addr())
+	t.Cleanup(func() {
+		redis.Client.Close()
+		redis.Client = nil
+	})
+	return server
+}
+
+// withClock fixes the clock budgets are counted with.
+func withClock(t *testing.T, at time.Time) func(time.Duration) {
+	t.Helper()
+	current := at
+	now = func() time.Time { return current }
+	t.Cleanup(func() { now = time.Now })
+	return func(d time.Duration) { current = current.Add(d) }
+}
+
+func testAction(name string, limit int) Action {
+	return Action{Name: name, Window: time.Minute, Limits: func() config.TierLimits {
+		return config.TierLimits{New: limit, Member: limit, Trusted: limit}
+	}}
+}
+
+func TestAllow_SlidingWindow(t *testing.T) {
+	for _, backend := range []string{"memory", "redis"} {
+		t.Run(backend, func(t *testing.T) {
+			if backend == "redis" {
+				withRedis(t)
+			}
+			action := testAction("sliding_"+backend, 4)
+			// 30s into a window.
+			advance := withClock(t, time.Unix(0, 0).Add(1000*time.Minute+30*time.Second))
+
+			for i := 1; i <= 4; i++ {
+				decision := Allow(context.Background(), action, "u", reputation.TierNew)
+				if !decision.Allowed || decision.Remaining != 4-i || decision.Reset != 30*time.Second {
+					t.Fatalf("hit %d: unexpected %+v", i, decision)
+				}
+			}
+			// The current window alone is full: wait for its end and for
+			// its weight to fade below the limit, which is immediate at 4/4.
+			if decision := Allow(context.Background(), action, "u", reputation.TierNew); decision.Allowed || decision.RetryAfter != 30*time.Second {
+				t.Fatalf("expected a refusal with retry in 30s, got %+v", decision)
+			}
+
+			// 6s into the next window the previous 4 hits still weigh 3.6.
+			advance(36 * time.Second)
+			if decision := Allow(context.Background(), action, "u", reputation.TierNew); !decision.Allowed || decision.Remaining != 0 {
+				t.Fatalf("expected one more hit, got %+v", decision)
+			}
+			// 4*(54-t)/60 + 1 < 4 once t > 9s.
+			if decision := Allow(context.Background(), action, "u", reputation.TierNew); decision.Allowed || decision.RetryAfter != 9*time.Second {
+				t.Fatalf("expected a refusal with retry in 9s, got %+v", decision)
+			}
+			advance(10 * time.Second)
+			if decision := Allow(context.Background(), action, "u", reputation.TierNew); !decision.Allowed {
+				t.Fatalf("expected the budget to have slid, got %+v", decision)
+			}
+			if decision := Allow(context.Background(), action, "other", reputation.TierNew); !decision.Allowed || decision.Remaining != 3 {
+				t.Fatalf("expected subjects to be counted separately, got %+v", decision)
+			}
+		})
+	}
+}
+
+func TestAllow_SharedThroughRedis(t *testing.T) {
+	server := withRedis(t)
+	action := testAction("shared", 5)
+
+	// Concurrent hits, as if from several replicas, never exceed the limit.
+	var allowed atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if Allow(context.Background(), action, "u", reputation.TierNew).Allowed {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed.Load() != 5 {
+		t.Fatalf("expected 5 hits to be allowed, got %d", allowed.Load())
+	}
+	keys := server.Keys()
+	if len(keys) != 1 || server.TTL(keys[0]) != 2*time.Minute {
+		t.Fatalf("expected one counter expiring after two windows, got %v", keys)
+	}
+
+	// When Redis fails the budget is counted in process instead.
+	server.Close()
+	if decision := Allow(context.Background(), testAction("fallback", 1), "u", reputation.TierNew); !decision.Allowed {
+		t.Fatalf("expected the in-process fallback to allow the first hit, got %+v", decision)
+	}
+	if decision := Allow(context.Background(), testAction("fallback", 1), "u", reputation.TierNew); decision.Allowed {
+		t.Fatalf("expected the in-process fallback to enforce the limit, got %+v", decision)
+	}
+}
+
+func TestRefund(t *testing.T) {
+	for _, backend := range []string{"memory", "redis"} {
+		t.Run(backend, func(t *testing.T) {
+			if backend == "redis" {
+				withRedis(t)
+			}
+			action := testAction("refund_"+backend, 2)
+			withClock(t, time.Unix(0, 0).Add(1000*time.Minute))
+
+			first := Allow(context.Background(), action, "u", reputation.TierNew)
+			Refund(context.Background(), first)
+			Refund(context.Background(), first)
+			Allow(context.Background(), action, "u", reputation.TierNew)
+			if decision := Allow(context.Background(), action, "u", reputation.TierNew); !decision.Allowed || decision.Remaining != 0 {
+				t.Fatalf("expected a refunded hit to be given back once, got %+v", decision)
+			}
+			refused := Allow(context.Background(), action, "u", reputation.TierNew)
+			Refund(context.Background(), refused)
+			if decision := Allow(context.Background(), action, "u", reputation.TierNew); decision.Allowed {
+				t.Fatalf("expected refunding a refused hit to change nothing, got %+v", decision)
+			}
+		})
+	}
+}
+
+func TestAction_LimitByTier(t *testing.T) {
+	action := Action{Name: "tiers", Window: time.Hour, Limits: func() config.TierLimits {
+		return config.TierLimits{New: 1, Member: 2, Trusted: 3}
+	}}
+	for tier, want := range map[string]int{reputation.TierNew: 1, reputation.TierMember: 2, reputation.TierTrusted: 3, "": 1} {
+		if got := action.Limit(tier); got != want {
+			t.Fatalf("tier %q: expected %d, got %d", tier, want, got)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/redis"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Budgets are enforced with a sliding window counter: hits are counted in
+// fixed windows, and a hit is allowed while the current window's count plus
+// the previous window's count, weighted by how much of it still overlaps
+// the sliding window, stays below the limit. Refused hits are not counted,
+// so a client that backs off gets its budget back.
+
+// counts is the state of a key after a hit.
+type counts struct {
+	allowed  bool
+	current  int64
+	previous int64
+	// key is the window an allowed hit was counted in, and inProcess
+	// whether it was counted by the fallback, for refund.
+	key       string
+	inProcess bool
+}
+
+// estimate is the number of hits in the sliding window ending elapsed into
+// the current window.
+func estimate(current, previous int64, elapsed, window time.Duration) float64 {
+	return float64(previous)*float64(window-elapsed)/float64(window) + float64(current)
+}
+
+// hitScript is hit for Redis, so every replica shares the counts. KEYS are
+// the current and previous window; ARGV the window and elapsed time in
+// milliseconds and the limit. It mirrors estimate.
+var hitScript = goredis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+local previous = tonumber(redis.call('GET', KEYS[2]) or '0')
+local window = tonumber(ARGV[1])
+local elapsed = tonumber(ARGV[2])
+if previous * (window - elapsed) / window + current >= tonumber(ARGV[3]) then
+	return {0, current, previous}
+end
+current = redis.call('INCR', KEYS[1])
+if current == 1 then
+	redis.call('PEXPIRE', KEYS[1], 2 * window)
+end
+return {1, current, previous}`)
+
+// windowKeys names the current and previous window of key at now.
+func windowKeys(key string, window time.Duration, now time.Time) (current, previous string, elapsed time.Duration) {
+	index := now.UnixMilli() / window.Milliseconds()
+	elapsed = time.Duration(now.UnixMilli()-index*window.Milliseconds()) * time.Millisecond
+	return key + ":" + strconv.FormatInt(index, 10), key + ":" + strconv.FormatInt(index-1, 10), elapsed
+}
+
+// hit counts a hit on key in Redis. While Redis is unavailable, or when the
+// call fails, it counts in process instead: budgets then apply per replica
+// rather than not at all.
+func hit(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (counts, time.Duration) {
+	currentKey, previousKey, elapsed := windowKeys(key, window, now)
+	if redis.Available() {
+		result, err := hitScript.Run(ctx, redis.Client, []string{currentKey, previousKey},
+			window.Milliseconds(), elapsed.Milliseconds(), limit).Int64Slice()
+		if err == nil && len(result) == 3 {
+			return counts{allowed: result[0] == 1, current: result[1], previous: result[2], key: currentKey}, elapsed
+		}
+		log.Printf("rate limit: counting %s in process: %v", key, err)
+	}
+	metrics.Add("fallback_hits", 1)
+	result := local.hit(currentKey, previousKey, limit, window, elapsed, now)
+	result.key, result.inProcess = currentKey, true
+	return result, elapsed
+}
+
+// refundScript takes one hit back from the window KEYS[1], keeping its
+// expiry, unless it has expired or was never counted.
+var refundScript = goredis.NewScript(`
+if tonumber(redis.call('GET', KEYS[1]) or '0') > 0 then
+	return redis.call('DECR', KEYS[1])
+end
+return 0`)
+
+// refund takes back a hit from the window and store hit counted it in.
+func refund(ctx context.Context, result counts, now time.Time) {
+	if !result.inProcess {
+		if err := refundScript.Run(ctx, redis.Client, []string{result.key}).Err(); err != nil {
+			log.Printf("rate limit: failed to refund %s: %v", result.key, err)
+		}
+		return
+	}
+	local.refund(result.key, now)
+}
+
+type entry struct {
+	count   int64
+	expires time.Time
+}
+
+// memoryCounter is the in-process fallback for hit.
+type memoryCounter struct {
+	mu      sync.Mutex
+	entries map[string]entry
+	swept   time.Time
+}
+
+var local = &memoryCounter{entries: map[string]entry{}}
+
+func (m *memoryCounter) hit(currentKey, previousKey string, limit int, window, elapsed time.Duration, now time.Time) counts {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.Sub(m.swept) > time.Minute {
+		for key, e := range m.entries {
+			if !now.Before(e.expires) {
+				delete(m.entries, key)
+			}
+		}
+		m.swept = now
+	}
+
+	count := func(key string) int64 {
+		if e, ok := m.entries[key]; ok && now.Before(e.expires) {
+			return e.count
+		}
+		return 0
+	}
+	result := counts{current: count(currentKey), previous: count(previousKey)}
+	if estimate(result.current, result.previous, elapsed, window) >= float64(limit) {
+		return result
+	}
+	result.allowed = true
+	result.current++
+	m.entries[currentKey] = entry{count: result.current, expires: now.Add(2*window - elapsed)}
+	return result
+}
+
+func (m *memoryCounter) refund(key string, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.entries[key]; ok && e.count > 0 && now.Before(e.expires) {
+		e.count--
+		m.entries[key] = e
+	}
+}
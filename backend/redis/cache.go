@@ -0,0 +1,227 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"expvar"
+	"log"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+// Cache keys shared by the read handlers and the invalidating subscriber.
+const FeedCacheKey = "confessions:all"
+
+func ConfessionCacheKey(confessionID string) string {
+	return "confessions:" + confessionID + ":with_comments"
+}
+
+func CommentsCacheKey(confessionID string) string {
+	return "comments:" + confessionID
+}
+
+// CardCacheKey is a hash of a confession's rendered image cards, one field
+// per format, so dropping the key drops every card.
+func CardCacheKey(confessionID string) string {
+	return "cards:" + confessionID
+}
+
+// generationTTL bounds how long an invalidated key remembers its
+// generation. It only has to outlast the slowest fill.
+const generationTTL = 24 * time.Hour
+
+// sharedLoadTimeout bounds a load shared by concurrent misses, including its
+// Redis calls. It runs detached from the request that started it, so one
+// caller going away cannot fail the others or skip the fill.
+const sharedLoadTimeout = 30 * time.Second
+
+func generationKey(key string) string {
+	return key + ":generation"
+}
+
+var (
+	cacheMetrics = expvar.NewMap("cache")
+	cacheLoads   singleflight.Group
+
+	// fillScript stores a loaded value only if the key was not invalidated
+	// since the load started.
+	fillScript = goredis.NewScript(`
+if (redis.call("GET", KEYS[2]) or "0") ~= ARGV[1] then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+return 1`)
+
+	// invalidateScript drops each key and bumps its generation. Keys come in
+	// pairs of cached key and generation key.
+	invalidateScript = goredis.NewScript(`
+for i = 1, #KEYS, 2 do
+	redis.call("DEL", KEYS[i])
+	redis.call("INCR", KEYS[i + 1])
+	redis.call("PEXPIRE", KEYS[i + 1], ARGV[1])
+end
+return #KEYS / 2`)
+)
+
+// CacheResult is the outcome of a read-through lookup.
+type CacheResult struct {
+	Body []byte
+	Hit  bool
+}
+
+// Cached returns the value stored under key, or runs load and stores its
+// result for ttl. load gets a context that outlives ctx by up to
+// sharedLoadTimeout. Concurrent misses for the same key share a single load.
+// Redis errors never fail the read, and while Redis is unavailable the cache
+// is bypassed entirely; the loader result is served instead.
+// name labels the hit/miss counters in the "cache" expvar map.
+//
+// A load that started before an Invalidate of key is served but not stored,
+// so a slow fill cannot put back what the invalidation dropped.
+func Cached(ctx context.Context, name, key string, ttl time.Duration, load func(context.Context) ([]byte, error)) (CacheResult, error) {
+	if Available() {
+		body, err := Client.Get(ctx, key).Bytes()
+		if err == nil {
+			cacheMetrics.Add("hits", 1)
+			cacheMetrics.Add("hits:"+name, 1)
+			return CacheResult{Body: body, Hit: true}, nil
+		}
+		if !errors.Is(err, goredis.Nil) {
+			cacheMetrics.Add("errors", 1)
+			log.Printf("cache get %s error: %v", key, err)
+		}
+	}
+
+	cacheMetrics.Add("misses", 1)
+	cacheMetrics.Add("misses:"+name, 1)
+
+	value, err, _ := cacheLoads.Do(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sharedLoadTimeout)
+		defer cancel()
+
+		generation, cacheable := "", false
+		if Available() {
+			stored, err := Client.Get(ctx, generationKey(key)).Result()
+			switch {
+			case err == nil:
+				generation, cacheable = stored, true
+			case errors.Is(err, goredis.Nil):
+				generation, cacheable = "0", true
+			default:
+				cacheMetrics.Add("errors", 1)
+				log.Printf("cache get %s error: %v", generationKey(key), err)
+			}
+		}
+
+		body, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if cacheable && Available() {
+			stored, err := fillScript.Run(ctx, Client, []string{key, generationKey(key)}, generation, body, ttl.Milliseconds()).Int()
+			if err != nil {
+				cacheMetrics.Add("errors", 1)
+				log.Printf("cache set %s error: %v", key, err)
+			} else if stored == 0 {
+				cacheMetrics.Add("stale_fills", 1)
+			}
+		}
+		return body, nil
+	})
+	if err != nil {
+		return CacheResult{}, err
+	}
+	return CacheResult{Body: value.([]byte)}, nil
+}
+
+// CachedField is Cached for one field of a Redis hash that holds a single
+// version of its value. A stored value of another version is a miss and is
+// overwritten, so the hash never grows past one entry per field. Writing a
+// field resets the whole hash's ttl.
+func CachedField(ctx context.Context, name, key, field, version string, ttl time.Duration, load func(context.Context) ([]byte, error)) (CacheResult, error) {
+	if Available() {
+		stored, err := Client.HGet(ctx, key, field).Bytes()
+		if err == nil {
+			if storedVersion, body, ok := bytes.Cut(stored, []byte{0}); ok && string(storedVersion) == version {
+				cacheMetrics.Add("hits", 1)
+				cacheMetrics.Add("hits:"+name, 1)
+				return CacheResult{Body: body, Hit: true}, nil
+			}
+		} else if !errors.Is(err, goredis.Nil) {
+			cacheMetrics.Add("errors", 1)
+			log.Printf("cache hget %s %s error: %v", key, field, err)
+		}
+	}
+
+	cacheMetrics.Add("misses", 1)
+	cacheMetrics.Add("misses:"+name, 1)
+
+	value, err, _ := cacheLoads.Do(key+"\x00"+field+"\x00"+version, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sharedLoadTimeout)
+		defer cancel()
+
+		body, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if Available() {
+			stored := append([]byte(version+"\x00"), body...)
+			pipe := Client.TxPipeline()
+			pipe.HSet(ctx, key, field, stored)
+			pipe.Expire(ctx, key, ttl)
+			if _, err := pipe.Exec(ctx); err != nil {
+				cacheMetrics.Add("errors", 1)
+				log.Printf("cache hset %s %s error: %v", key, field, err)
+			}
+		}
+		return body, nil
+	})
+	if err != nil {
+		return CacheResult{}, err
+	}
+	return CacheResult{Body: value.([]byte)}, nil
+}
+
+// Invalidate drops keys and bumps their generations, so loads already in
+// flight for them are not stored.
+func Invalidate(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	pairs := make([]string, 0, len(keys)*2)
+	for _, key := range keys {
+		pairs = append(pairs, key, generationKey(key))
+	}
+	return invalidateScript.Run(ctx, Client, pairs, generationTTL.Milliseconds()).Err()
+}
+
+// FlushReadCaches deletes every cached feed, confession, comment list and
+// card and returns how many keys were removed. It is meant for maintenance
+// after bulk changes that bypass the event pipeline.
+func FlushReadCaches(ctx context.Context) (int, error) {
+	if !Available() {
+		return 0, errors.New("redis is unavailable")
+	}
+
+	removed := 0
+	for _, pattern := range []string{FeedCacheKey, ConfessionCacheKey("*"), CommentsCacheKey("*"), CardCacheKey("*")} {
+		iter := Client.Scan(ctx, 0, pattern, 100).Iterator()
+		for iter.Next(ctx) {
+			if strings.HasSuffix(iter.Val(), generationKey("")) {
+				continue
+			}
+			if err := Invalidate(ctx, iter.Val()); err != nil {
+				return removed, err
+			}
+			removed++
+		}
+		if err := iter.Err(); err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
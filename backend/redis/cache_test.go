@@ -0,0 +1,203 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestCached_SharesConcurrentLoads(t *testing.T) {
+	var loads int32
+	release := make(chan struct{})
+	load := func(context.Context) ([]byte, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return []byte(`{"ok":true}`), nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]CacheResult, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, err := Cached(context.Background(), "test", "test:shared", time.Minute, load)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			results[i] = result
+		}(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&loads); got != 1 {
+		t.Fatalf("expected a single load, got %d", got)
+	}
+	for _, result := range results {
+		if string(result.Body) != `{"ok":true}` || result.Hit {
+			t.Fatalf("unexpected result: %+v", result)
+		}
+	}
+}
+
+func TestCached_DoesNotCacheErrors(t *testing.T) {
+	failure := errors.New("boom")
+	_, err := Cached(context.Background(), "test", "test:error", time.Minute, func(context.Context) ([]byte, error) {
+		return nil, failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected loader error, got %v", err)
+	}
+
+	result, err := Cached(context.Background(), "test", "test:error", time.Minute, func(context.Context) ([]byte, error) {
+		return []byte("[]"), nil
+	})
+	if err != nil || string(result.Body) != "[]" {
+		t.Fatalf("expected second load to succeed, got %q (%v)", result.Body, err)
+	}
+}
+
+func TestCachedField_KeepsOneVersionPerField(t *testing.T) {
+	server := miniredis.RunT(t)
+	ConnectRedis(server.Addr())
+	t.Cleanup(func() {
+		Client.Close()
+		Client = nil
+	})
+
+	load := func(body string) func(context.Context) ([]byte, error) {
+		return func(context.Context) ([]byte, error) { return []byte(body), nil }
+	}
+	ctx := context.Background()
+	if result, err := CachedField(ctx, "test", "test:fields", "a", "v1", time.Minute, load("one")); err != nil || result.Hit {
+		t.Fatalf("expected a miss, got %+v (%v)", result, err)
+	}
+	if _, err := CachedField(ctx, "test", "test:fields", "b", "v1", time.Minute, load("two")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, err := CachedField(ctx, "test", "test:fields", "a", "v1", time.Minute, load("stale"))
+	if err != nil || !result.Hit || string(result.Body) != "one" {
+		t.Fatalf("expected a hit for field a, got %+v (%v)", result, err)
+	}
+	if ttl := server.TTL("test:fields"); ttl != time.Minute {
+		t.Fatalf("expected the hash to expire in a minute, got %v", ttl)
+	}
+
+	// A new version replaces the field instead of adding one.
+	for _, version := range []string{"v2", "v3", "v4"} {
+		if result, _ := CachedField(ctx, "test", "test:fields", "a", version, time.Minute, load(version)); result.Hit || string(result.Body) != version {
+			t.Fatalf("expected a miss for %s, got %+v", version, result)
+		}
+	}
+	if fields, _ := server.HKeys("test:fields"); len(fields) != 2 {
+		t.Fatalf("expected one field per name, got %v", fields)
+	}
+	if result, _ := CachedField(ctx, "test", "test:fields", "a", "v4", time.Minute, load("stale")); !result.Hit || string(result.Body) != "v4" {
+		t.Fatalf("expected the latest version to hit, got %+v", result)
+	}
+
+	server.Del("test:fields")
+	if result, _ := CachedField(ctx, "test", "test:fields", "b", "v1", time.Minute, load("three")); result.Hit || string(result.Body) != "three" {
+		t.Fatalf("expected dropping the key to drop every field, got %+v", result)
+	}
+}
+
+func TestCached_DropsFillsStartedBeforeInvalidate(t *testing.T) {
+	server := miniredis.RunT(t)
+	ConnectRedis(server.Addr())
+	t.Cleanup(func() {
+		Client.Close()
+		Client = nil
+	})
+
+	ctx := context.Background()
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan CacheResult)
+	go func() {
+		result, _ := Cached(ctx, "test", "test:race", time.Minute, func(context.Context) ([]byte, error) {
+			close(started)
+			<-release
+			return []byte("stale"), nil
+		})
+		done <- result
+	}()
+
+	<-started
+	if err := Invalidate(ctx, "test:race"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	close(release)
+	if result := <-done; string(result.Body) != "stale" {
+		t.Fatalf("expected the in-flight load to be served, got %q", result.Body)
+	}
+	if server.Exists("test:race") {
+		t.Fatal("expected the stale fill not to be stored")
+	}
+
+	result, err := Cached(ctx, "test", "test:race", time.Minute, func(context.Context) ([]byte, error) {
+		return []byte("fresh"), nil
+	})
+	if err != nil || result.Hit || string(result.Body) != "fresh" {
+		t.Fatalf("expected a fresh load, got %+v (%v)", result, err)
+	}
+	got, _ := server.Get("test:race")
+	if got != "fresh" {
+		t.Fatalf("expected the fresh fill to be stored, got %q", got)
+	}
+	if ttl := server.TTL("test:race"); ttl != time.Minute {
+		t.Fatalf("expected the key to expire in a minute, got %v", ttl)
+	}
+}
+
+func TestCached_SharedLoadOutlivesTheFirstCaller(t *testing.T) {
+	server := miniredis.RunT(t)
+	ConnectRedis(server.Addr())
+	t.Cleanup(func() {
+		Client.Close()
+		Client = nil
+	})
+
+	first, cancel := context.WithCancel(context.Background())
+	started, release := make(chan struct{}), make(chan struct{})
+	load := func(ctx context.Context) ([]byte, error) {
+		close(started)
+		<-release
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return []byte("shared"), nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := Cached(first, "test", "test:detached", time.Minute, load)
+		done <- err
+	}()
+	<-started
+	waiter := make(chan CacheResult, 1)
+	go func() {
+		result, _ := Cached(context.Background(), "test", "test:detached", time.Minute, load)
+		waiter <- result
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	close(release)
+
+	if err := <-done; err != nil {
+		t.Fatalf("expected the first caller's load to finish, got %v", err)
+	}
+	if result := <-waiter; string(result.Body) != "shared" {
+		t.Fatalf("expected the waiter to get the shared load, got %q", result.Body)
+	}
+	if got, _ := server.Get("test:detached"); got != "shared" {
+		t.Fatalf("expected the fill to be stored, got %q", got)
+	}
+}
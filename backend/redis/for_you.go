@@ -0,0 +1,71 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/Semkufu95/confessions/Backend/events"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Personalized feeds are cached per user under the current epoch. New or
+// deleted confessions bump the epoch, which retires every cached feed at
+// once; older generations simply expire by TTL.
+const forYouEpochKey = "feed:for_you:epoch"
+
+// ForYouCacheKey is the cached ranking for userID in epoch. Anonymous
+// viewers share the "anonymous" key.
+func ForYouCacheKey(epoch int64, userID string) string {
+	return "feed:for_you:" + strconv.FormatInt(epoch, 10) + ":" + userID
+}
+
+// ForYouEpoch returns the current feed generation, or 0 when Redis is
+// unavailable or the epoch was never bumped.
+func ForYouEpoch(ctx context.Context) int64 {
+	if !Available() {
+		return 0
+	}
+	epoch, err := Client.Get(ctx, forYouEpochKey).Int64()
+	if err != nil && !errors.Is(err, goredis.Nil) {
+		log.Printf("for-you epoch read error: %v", err)
+	}
+	return epoch
+}
+
+// InvalidateForYou drops userID's cached feed so the next request re-ranks
+// with their latest activity and mutes.
+func InvalidateForYou(ctx context.Context, userID string) {
+	if !Available() || userID == "" {
+		return
+	}
+	if err := Client.Del(ctx, ForYouCacheKey(ForYouEpoch(ctx), userID)).Err(); err != nil {
+		log.Printf("for-you invalidate error: %v", err)
+	}
+}
+
+// forYouRefresh decides how an event affects personalized feeds: new and
+// deleted confessions change every feed, while reactions and comments change
+// the actor's category affinity.
+func forYouRefresh(envelope events.Envelope) (bumpEpoch bool, userID string) {
+	switch envelope.Type {
+	case events.ChannelConfessionCreated, events.ChannelConfessionDeleted:
+		return true, ""
+	case events.ChannelReactionUpdated, events.ChannelReactionRemoved,
+		events.ChannelCommentCreated, events.ChannelCommentDeleted:
+		return false, envelope.ActorID
+	default:
+		return false, ""
+	}
+}
+
+func refreshForYou(ctx context.Context, envelope events.Envelope) {
+	bump, userID := forYouRefresh(envelope)
+	if bump {
+		if err := Client.Incr(ctx, forYouEpochKey).Err(); err != nil {
+			log.Printf("for-you epoch bump error: %v", err)
+		}
+	}
+	InvalidateForYou(ctx, userID)
+}
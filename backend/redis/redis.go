@@ -2,8 +2,11 @@ package redis
 
 import (
 	"context"
-	"fmt"
+	"expvar"
 	"log"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -11,14 +14,103 @@ import (
 var Ctx = context.Background()
 var Client *redis.Client
 
+const (
+	healthCheckInterval = 5 * time.Second
+	minReconnectBackoff = time.Second
+	maxReconnectBackoff = 30 * time.Second
+)
+
+var (
+	available     atomic.Bool
+	redisMetrics  = expvar.NewMap("redis")
+	availableFlag = expvar.Func(func() interface{} { return Available() })
+)
+
+func init() {
+	redisMetrics.Set("available", availableFlag)
+}
+
+// ConnectRedis creates the client and checks connectivity once. A failed
+// ping is not fatal: the app starts in degraded mode and the health monitor
+// keeps retrying in the background.
 func ConnectRedis(addr string) {
 	Client = redis.NewClient(&redis.Options{
-		Addr: addr,
+		Addr:         addr,
+		DialTimeout:  2 * time.Second,
+		ReadTimeout:  2 * time.Second,
+		WriteTimeout: 2 * time.Second,
 	})
 
-	_, err := Client.Ping(Ctx).Result()
-	if err != nil {
-		log.Fatal("Huuh! Failed to connect to Redis")
+	if err := Client.Ping(Ctx).Err(); err != nil {
+		available.Store(false)
+		log.Printf("⚠️  Redis unavailable at %s, starting in degraded mode: %v", addr, err)
+		return
+	}
+	available.Store(true)
+	log.Println("Connected to Redis")
+}
+
+// Available reports whether the last health check reached Redis. Callers use
+// it to skip Redis work instead of waiting on dial timeouts during an outage.
+func Available() bool {
+	return Client != nil && available.Load()
+}
+
+// StartHealthMonitor pings Redis until ctx is cancelled and flips the
+// availability flag. While Redis is down it retries with exponential backoff.
+func StartHealthMonitor(ctx context.Context, wg *sync.WaitGroup) {
+	if Client == nil {
+		return
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		backoff := minReconnectBackoff
+		for {
+			wait := healthCheckInterval
+			err := Client.Ping(ctx).Err()
+			switch {
+			case err == nil:
+				if !available.Swap(true) {
+					redisMetrics.Add("reconnects", 1)
+					log.Println("Redis connection restored")
+				}
+				backoff = minReconnectBackoff
+			case ctx.Err() != nil:
+				return
+			default:
+				if available.Swap(false) {
+					log.Printf("⚠️  Redis connection lost, running in degraded mode: %v", err)
+				}
+				wait = backoff
+				backoff = nextBackoff(backoff)
+			}
+
+			if !sleepContext(ctx, wait) {
+				return
+			}
+		}
+	}()
+}
+
+func nextBackoff(current time.Duration) time.Duration {
+	next := current * 2
+	if next > maxReconnectBackoff {
+		return maxReconnectBackoff
+	}
+	return next
+}
+
+// sleepContext waits for d and reports false if ctx was cancelled first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
 	}
-	fmt.Println("Connected to Redis")
 }
@@ -0,0 +1,38 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNextBackoff(t *testing.T) {
+	if got := nextBackoff(time.Second); got != 2*time.Second {
+		t.Fatalf("expected 2s, got %s", got)
+	}
+	if got := nextBackoff(20 * time.Second); got != maxReconnectBackoff {
+		t.Fatalf("expected backoff to be capped at %s, got %s", maxReconnectBackoff, got)
+	}
+}
+
+func TestAvailable_WithoutClient(t *testing.T) {
+	previous := Client
+	Client = nil
+	defer func() { Client = previous }()
+
+	available.Store(true)
+	defer available.Store(false)
+
+	if Available() {
+		t.Fatalf("expected Redis to be unavailable without a client")
+	}
+}
+
+func TestSleepContext_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if sleepContext(ctx, time.Minute) {
+		t.Fatalf("expected sleepContext to stop on cancellation")
+	}
+}
@@ -3,119 +3,280 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
+	"net"
 	"sync"
 	"time"
+
+	"github.com/Semkufu95/confessions/Backend/events"
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
 )
 
-// StartSubscriber listens to Redis pub/sub channels and invalidates cache keys.
+// StartSubscriber listens to Redis pub/sub channels and invalidates cache keys,
+// including personalized feeds.
 func StartSubscriber(ctx context.Context, wg *sync.WaitGroup) {
-	pubsub := Client.Subscribe(ctx,
-		"confessions:confession:created",
-		"confessions:confession:updated",
-		"confessions:confession:deleted",
-		"confessions:confession:starred",
-		"confessions:comment:created",
-		"confessions:comment:updated",
-		"confessions:comment:deleted",
-		"confessions:reaction:updated",
-		"confessions:reaction:removed",
-	)
-	ch := pubsub.Channel()
+	channels := []string{
+		events.ChannelConfessionCreated,
+		events.ChannelConfessionUpdated,
+		events.ChannelConfessionDeleted,
+		events.ChannelConfessionStarred,
+		events.ChannelCommentCreated,
+		events.ChannelCommentUpdated,
+		events.ChannelCommentDeleted,
+		events.ChannelReactionUpdated,
+		events.ChannelReactionRemoved,
+	}
+	StartEventWorker(ctx, wg, "subscriber", channels, func(envelope events.Envelope) {
+		keys, err := invalidationKeys(envelope)
+		if err != nil {
+			log.Printf("redis subscriber: cannot decode %s (%s): %v", envelope.Type, envelope.ID, err)
+			return
+		}
+		if err := Invalidate(Ctx, keys...); err != nil {
+			log.Printf("redis subscriber: cannot invalidate %v: %v", keys, err)
+		}
+		refreshForYou(Ctx, envelope)
+	})
+}
+
+// StartEventWorker decodes every event published on channels and passes it
+// to handle. Every replica runs its own workers and receives every event, so
+// handlers must be idempotent.
+func StartEventWorker(ctx context.Context, wg *sync.WaitGroup, name string, channels []string, handle func(events.Envelope)) {
+	subscribe := func(ctx context.Context) *goredis.PubSub {
+		return Client.Subscribe(ctx, channels...)
+	}
 
+	setSubscribed(name, false)
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		defer func() {
-			if err := pubsub.Close(); err != nil {
-				log.Printf("redis subscriber close error: %v", err)
-			}
-		}()
-		for {
-			select {
-			case <-ctx.Done():
+		runSubscription(ctx, name, subscribe, func(msg *goredis.Message) {
+			envelope, err := events.Decode(msg.Payload)
+			if err != nil {
+				log.Printf("redis %s: dropping malformed event on %s: %v", name, msg.Channel, err)
 				return
-			case msg, ok := <-ch:
-				if !ok {
-					return
-				}
-				switch msg.Channel {
-				case "confessions:confession:created", "confessions:confession:deleted":
-					Client.Del(Ctx, "confessions:all")
-				case "confessions:confession:updated", "confessions:confession:starred":
-					if id := stringValueFromPayload(msg.Payload, "id"); id != "" {
-						Client.Del(Ctx, "confessions:"+id+":with_comments")
-					}
-				case "confessions:comment:created", "confessions:comment:updated", "confessions:comment:deleted":
-					if confessionID := stringValueFromPayload(msg.Payload, "confession_id"); confessionID != "" {
-						Client.Del(Ctx, "confessions:"+confessionID+":with_comments")
-					}
-				case "confessions:reaction:updated", "confessions:reaction:removed":
-					if confessionID := stringValueFromPayload(msg.Payload, "confession_id"); confessionID != "" {
-						Client.Del(Ctx, "confessions:"+confessionID+":with_comments")
-					}
-					if commentID := stringValueFromPayload(msg.Payload, "comment_id"); commentID != "" {
-						Client.Del(Ctx, "comments:"+commentID)
-					}
-				default:
-					log.Printf("Unhandled channel: %s", msg.Channel)
-				}
 			}
-		}
+			handle(envelope)
+		})
 	}()
 }
 
-// StartWebsocketBroadcaster relays Redis events to websocket clients.
-func StartWebsocketBroadcaster(ctx context.Context, wg *sync.WaitGroup, broadcast func(string)) {
-	pubsub := Client.PSubscribe(ctx, "confessions:*", "connections:*")
-	ch := pubsub.Channel()
+// StartWebsocketBroadcaster relays Redis events to websocket clients. The
+// event's actor is passed along so recipients who muted or blocked them can
+// be skipped.
+func StartWebsocketBroadcaster(ctx context.Context, wg *sync.WaitGroup, broadcast func(message, actorID string)) {
+	subscribe := func(ctx context.Context) *goredis.PubSub {
+		return Client.PSubscribe(ctx, "confessions:*", "connections:*")
+	}
 
+	setSubscribed("websocket_broadcaster", false)
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		defer func() {
-			if err := pubsub.Close(); err != nil {
-				log.Printf("redis websocket broadcaster close error: %v", err)
+		runSubscription(ctx, "websocket_broadcaster", subscribe, func(msg *goredis.Message) {
+			data, actorID, err := websocketMessage(msg.Channel, msg.Payload)
+			if err != nil {
+				broadcast(msg.Payload, actorID)
+				return
 			}
-		}()
+			broadcast(string(data), actorID)
+		})
+	}()
+}
 
-		for {
-			select {
-			case <-ctx.Done():
+// StartNotificationDelivery relays new notifications to their recipient's
+// websockets only, in the same frame format as StartWebsocketBroadcaster.
+func StartNotificationDelivery(ctx context.Context, wg *sync.WaitGroup, send func(userID, message string)) {
+	StartEventWorker(ctx, wg, "notification_delivery", []string{events.ChannelNotificationCreated}, func(envelope events.Envelope) {
+		var payload struct {
+			UserID uuid.UUID `json:"user_id"`
+		}
+		if err := envelope.Unmarshal(&payload); err != nil || payload.UserID == uuid.Nil {
+			log.Printf("redis notification_delivery: cannot decode %s: %v", envelope.ID, err)
+			return
+		}
+		raw, err := json.Marshal(envelope)
+		if err != nil {
+			return
+		}
+		data, _, err := websocketMessage(envelope.Type, string(raw))
+		if err != nil {
+			return
+		}
+		send(payload.UserID.String(), string(data))
+	})
+}
+
+// runSubscription keeps a subscription alive until ctx is cancelled. When
+// Redis is unreachable or the connection drops, it resubscribes with
+// exponential backoff; messages published while disconnected are lost,
+// which is acceptable because cache entries also expire by TTL.
+func runSubscription(ctx context.Context, name string, subscribe func(context.Context) *goredis.PubSub, handle func(*goredis.Message)) {
+	defer setSubscribed(name, false)
+
+	backoff := minReconnectBackoff
+	for ctx.Err() == nil {
+		if Client == nil {
+			return
+		}
+
+		pubsub := subscribe(ctx)
+		if _, err := pubsub.Receive(ctx); err != nil {
+			_ = pubsub.Close()
+			if ctx.Err() != nil {
 				return
-			case msg, ok := <-ch:
-				if !ok {
-					return
-				}
+			}
+			log.Printf("redis %s: subscribe failed, retrying in %s: %v", name, backoff, err)
+			if !sleepContext(ctx, backoff) {
+				return
+			}
+			backoff = nextBackoff(backoff)
+			continue
+		}
+		backoff = minReconnectBackoff
+		setSubscribed(name, true)
 
-				event := map[string]interface{}{
-					"channel":     msg.Channel,
-					"received_at": time.Now().UTC().Format(time.RFC3339),
-				}
-				if json.Valid([]byte(msg.Payload)) {
-					event["payload"] = json.RawMessage(msg.Payload)
-				} else {
-					event["payload"] = msg.Payload
-				}
+		err := receiveMessages(ctx, pubsub, handle)
+		setSubscribed(name, false)
+		if closeErr := pubsub.Close(); closeErr != nil && ctx.Err() == nil {
+			log.Printf("redis %s close error: %v", name, closeErr)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		redisMetrics.Add("resubscribes", 1)
+		log.Printf("redis %s: connection lost, resubscribing: %v", name, err)
+	}
+}
+
+var subscriptions sync.Map // worker name -> bool
+
+func setSubscribed(name string, subscribed bool) {
+	subscriptions.Store(name, subscribed)
+}
 
-				data, err := json.Marshal(event)
-				if err != nil {
-					broadcast(msg.Payload)
-					continue
+// Subscriptions reports, per started worker, whether it currently holds a
+// live Redis subscription.
+func Subscriptions() map[string]bool {
+	states := make(map[string]bool)
+	subscriptions.Range(func(key, value interface{}) bool {
+		states[key.(string)] = value.(bool)
+		return true
+	})
+	return states
+}
+
+// receiveMessages delivers messages until the connection fails or ctx is
+// cancelled. Idle connections are pinged so half-open sockets are noticed.
+func receiveMessages(ctx context.Context, pubsub *goredis.PubSub, handle func(*goredis.Message)) error {
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			// Unblocks a pending read on shutdown.
+			_ = pubsub.Close()
+		case <-stop:
+		}
+	}()
+
+	for {
+		received, err := pubsub.ReceiveTimeout(ctx, healthCheckInterval*2)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				if pingErr := pubsub.Ping(ctx); pingErr != nil {
+					return pingErr
 				}
-				broadcast(string(data))
+				continue
 			}
+			return err
 		}
-	}()
+		if msg, ok := received.(*goredis.Message); ok {
+			handle(msg)
+		}
+	}
+}
+
+// invalidationKeys returns the cache keys made stale by an event. The feed
+// shows counters, so almost every event also drops FeedCacheKey.
+func invalidationKeys(envelope events.Envelope) ([]string, error) {
+	switch envelope.Type {
+	case events.ChannelConfessionCreated:
+		return []string{FeedCacheKey}, nil
+	case events.ChannelConfessionDeleted:
+		var payload events.ConfessionDeleted
+		if err := envelope.Unmarshal(&payload); err != nil {
+			return nil, err
+		}
+		return []string{FeedCacheKey, ConfessionCacheKey(payload.ID.String()), CommentsCacheKey(payload.ID.String()), CardCacheKey(payload.ID.String())}, nil
+	case events.ChannelConfessionUpdated:
+		var payload events.ConfessionPayload
+		if err := envelope.Unmarshal(&payload); err != nil {
+			return nil, err
+		}
+		// Cards of the old content are never asked for again.
+		return []string{FeedCacheKey, ConfessionCacheKey(payload.ID.String()), CardCacheKey(payload.ID.String())}, nil
+	case events.ChannelConfessionStarred:
+		var payload events.ConfessionPayload
+		if err := envelope.Unmarshal(&payload); err != nil {
+			return nil, err
+		}
+		return []string{FeedCacheKey, ConfessionCacheKey(payload.ID.String())}, nil
+	case events.ChannelCommentCreated, events.ChannelCommentUpdated, events.ChannelCommentDeleted:
+		var payload struct {
+			ConfessionID uuid.UUID `json:"confession_id"`
+		}
+		if err := envelope.Unmarshal(&payload); err != nil {
+			return nil, err
+		}
+		confessionID := payload.ConfessionID.String()
+		return []string{FeedCacheKey, ConfessionCacheKey(confessionID), CommentsCacheKey(confessionID)}, nil
+	case events.ChannelReactionUpdated, events.ChannelReactionRemoved:
+		var payload struct {
+			ConfessionID *uuid.UUID `json:"confession_id"`
+			CommentID    *uuid.UUID `json:"comment_id"`
+		}
+		if err := envelope.Unmarshal(&payload); err != nil {
+			return nil, err
+		}
+		if payload.ConfessionID == nil {
+			return nil, nil
+		}
+		confessionID := payload.ConfessionID.String()
+		if payload.CommentID != nil {
+			return []string{ConfessionCacheKey(confessionID), CommentsCacheKey(confessionID)}, nil
+		}
+		return []string{FeedCacheKey, ConfessionCacheKey(confessionID)}, nil
+	default:
+		log.Printf("Unhandled channel: %s", envelope.Type)
+		return nil, nil
+	}
 }
 
-func stringValueFromPayload(payload string, key string) string {
-	var data map[string]interface{}
-	if err := json.Unmarshal([]byte(payload), &data); err != nil {
-		return ""
+// websocketMessage converts a Redis payload into the frame sent to browsers.
+// The envelope data becomes "payload" so clients keep reading flat fields;
+// the actor is dropped from the frame because confessions are anonymous and
+// only returned for filtering.
+func websocketMessage(channel string, raw string) ([]byte, string, error) {
+	message := map[string]interface{}{
+		"channel":     channel,
+		"received_at": time.Now().UTC().Format(time.RFC3339),
 	}
-	if value, ok := data[key].(string); ok {
-		return value
+	actorID := ""
+	if envelope, err := events.Decode(raw); err == nil {
+		actorID = envelope.ActorID
+		message["event_id"] = envelope.ID
+		message["version"] = envelope.Version
+		message["payload"] = envelope.Data
+	} else if json.Valid([]byte(raw)) {
+		message["payload"] = json.RawMessage(raw)
+	} else {
+		message["payload"] = raw
 	}
-	return ""
+	data, err := json.Marshal(message)
+	return data, actorID, err
 }
@@ -1,25 +1,187 @@
 package redis
 
-import "testing"
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
 
-func TestStringValueFromPayload(t *testing.T) {
-	payload := `{"id":"abc-123","confession_id":"cid-001"}`
+	"github.com/Semkufu95/confessions/Backend/events"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+)
 
-	if got := stringValueFromPayload(payload, "id"); got != "abc-123" {
-		t.Fatalf("expected id abc-123, got %q", got)
+func mustEnvelope(t *testing.T, event events.Event) events.Envelope {
+	t.Helper()
+	envelope, err := events.NewEnvelope("actor-1", event)
+	if err != nil {
+		t.Fatalf("failed to build envelope: %v", err)
 	}
-	if got := stringValueFromPayload(payload, "confession_id"); got != "cid-001" {
-		t.Fatalf("expected confession_id cid-001, got %q", got)
+	return envelope
+}
+
+func TestInvalidationKeys(t *testing.T) {
+	confessionID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	commentID := uuid.MustParse("00000000-0000-0000-0000-000000000002")
+
+	keys, err := invalidationKeys(mustEnvelope(t, events.ConfessionUpdated{
+		ConfessionPayload: events.ConfessionPayload{ID: confessionID},
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(keys) != 3 || keys[1] != "confessions:"+confessionID.String()+":with_comments" || keys[2] != "cards:"+confessionID.String() {
+		t.Fatalf("unexpected keys for confession update: %v", keys)
+	}
+
+	keys, err = invalidationKeys(mustEnvelope(t, events.ReactionUpdated{
+		ConfessionID: &confessionID,
+		CommentID:    &commentID,
+		Type:         "like",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
-	if got := stringValueFromPayload(payload, "missing_key"); got != "" {
-		t.Fatalf("expected empty value for missing key, got %q", got)
+	if len(keys) != 2 || keys[1] != "comments:"+confessionID.String() {
+		t.Fatalf("unexpected keys for comment reaction: %v", keys)
+	}
+
+	keys, err = invalidationKeys(mustEnvelope(t, events.ConfessionCreated{}))
+	if err != nil || len(keys) != 1 || keys[0] != "confessions:all" {
+		t.Fatalf("expected confessions:all to be invalidated, got %v (%v)", keys, err)
 	}
 }
 
-func TestStringValueFromPayload_InvalidJSON(t *testing.T) {
-	payload := `{"id":`
+func TestForYouRefresh(t *testing.T) {
+	if bump, user := forYouRefresh(mustEnvelope(t, events.ConfessionCreated{})); !bump || user != "" {
+		t.Fatalf("expected a new confession to bump the epoch, got %v %q", bump, user)
+	}
+	if bump, user := forYouRefresh(mustEnvelope(t, events.ReactionUpdated{Type: "heart"})); bump || user != "actor-1" {
+		t.Fatalf("expected a reaction to refresh only the actor's feed, got %v %q", bump, user)
+	}
+	if bump, user := forYouRefresh(mustEnvelope(t, events.ConfessionStarred{})); bump || user != "" {
+		t.Fatalf("expected stars to leave feeds alone, got %v %q", bump, user)
+	}
+}
+
+func TestWebsocketMessage_UnwrapsEnvelope(t *testing.T) {
+	envelope := mustEnvelope(t, events.FriendAdded{FriendRequestPayload: events.FriendRequestPayload{
+		SenderUsername: "alice",
+		Status:         "pending",
+	}})
+	raw, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatalf("failed to marshal envelope: %v", err)
+	}
+
+	data, actorID, err := websocketMessage(envelope.Type, string(raw))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actorID != "actor-1" {
+		t.Fatalf("expected the actor to be returned for filtering, got %q", actorID)
+	}
+
+	var message struct {
+		Channel string                 `json:"channel"`
+		EventID string                 `json:"event_id"`
+		ActorID string                 `json:"actor_id"`
+		Payload map[string]interface{} `json:"payload"`
+	}
+	if err := json.Unmarshal(data, &message); err != nil {
+		t.Fatalf("invalid websocket message: %v", err)
+	}
+	if message.Channel != events.ChannelFriendAdded || message.EventID != envelope.ID {
+		t.Fatalf("unexpected channel or event id: %+v", message)
+	}
+	if message.ActorID != "" {
+		t.Fatalf("actor must not be broadcast, got %q", message.ActorID)
+	}
+	if message.Payload["sender_username"] != "alice" {
+		t.Fatalf("expected flat payload fields, got %v", message.Payload)
+	}
+}
+
+func TestWebsocketMessage_LegacyPayload(t *testing.T) {
+	data, _, err := websocketMessage("confessions:confession:deleted", `{"id":"abc-123"}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var message struct {
+		Payload map[string]interface{} `json:"payload"`
+	}
+	if err := json.Unmarshal(data, &message); err != nil {
+		t.Fatalf("invalid websocket message: %v", err)
+	}
+	if message.Payload["id"] != "abc-123" {
+		t.Fatalf("expected legacy payload to pass through, got %v", message.Payload)
+	}
+}
+
+func TestSubscriptions_TracksWorkerState(t *testing.T) {
+	setSubscribed("test_worker", true)
+	defer subscriptions.Delete("test_worker")
+
+	if !Subscriptions()["test_worker"] {
+		t.Fatalf("expected test_worker to be reported as subscribed")
+	}
+	setSubscribed("test_worker", false)
+	if running, ok := Subscriptions()["test_worker"]; !ok || running {
+		t.Fatalf("expected test_worker to be reported as not subscribed, got %v (%v)", running, ok)
+	}
+}
+
+func TestStartNotificationDelivery_AddressesRecipient(t *testing.T) {
+	server := miniredis.RunT(t)
+	ConnectRedis(server.Addr())
+	t.Cleanup(func() {
+		Client.Close()
+		Client = nil
+	})
+
+	recipient := uuid.New()
+	delivered := make(chan [2]string, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	t.Cleanup(func() {
+		cancel()
+		wg.Wait()
+	})
+	StartNotificationDelivery(ctx, &wg, func(userID, message string) {
+		delivered <- [2]string{userID, message}
+	})
+
+	envelope := mustEnvelope(t, events.NotificationCreated{UserID: recipient, Unread: 3})
+	raw, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for server.PubSubNumSub(events.ChannelNotificationCreated)[events.ChannelNotificationCreated] == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("worker never subscribed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	server.Publish(events.ChannelNotificationCreated, string(raw))
 
-	if got := stringValueFromPayload(payload, "id"); got != "" {
-		t.Fatalf("expected empty value for invalid json, got %q", got)
+	select {
+	case got := <-delivered:
+		var frame struct {
+			Channel string `json:"channel"`
+			Payload struct {
+				Unread int `json:"unread"`
+			} `json:"payload"`
+		}
+		if err := json.Unmarshal([]byte(got[1]), &frame); err != nil {
+			t.Fatalf("bad frame %q: %v", got[1], err)
+		}
+		if got[0] != recipient.String() || frame.Channel != events.ChannelNotificationCreated || frame.Payload.Unread != 3 {
+			t.Fatalf("unexpected delivery to %s: %s", got[0], got[1])
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("notification was not delivered")
 	}
 }
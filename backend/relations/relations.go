@@ -0,0 +1,94 @@
+// Package relations answers who should not see whom. Mutes are one-sided;
+// blocks hide both users from each other.
+package relations
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const hiddenSQL = `
+SELECT muted_user_id AS id FROM user_mutes WHERE user_id = @viewer
+UNION
+SELECT blocked_user_id FROM user_blocks WHERE user_id = @viewer
+UNION
+SELECT user_id FROM user_blocks WHERE blocked_user_id = @viewer`
+
+// Hidden returns the users whose content viewer should not see: everyone they
+// muted or blocked and everyone who blocked them.
+func Hidden(ctx context.Context, db *gorm.DB, viewerID uuid.UUID) (map[uuid.UUID]bool, error) {
+	var ids []uuid.UUID
+	if err := db.WithContext(ctx).Raw(hiddenSQL, map[string]interface{}{"viewer": viewerID}).Scan(&ids).Error; err != nil {
+		return nil, err
+	}
+	hidden := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		hidden[id] = true
+	}
+	return hidden, nil
+}
+
+// Blocked reports whether either user blocked the other.
+func Blocked(ctx context.Context, db *gorm.DB, a, b uuid.UUID) (bool, error) {
+	var count int64
+	err := db.WithContext(ctx).Model(&models.UserBlock{}).
+		Where("(user_id = ? AND blocked_user_id = ?) OR (user_id = ? AND blocked_user_id = ?)", a, b, b, a).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// cacheTTL bounds how long another replica may keep showing content or
+// delivering realtime events after a block or mute; the replica that handled
+// the change invalidates immediately.
+const cacheTTL = 30 * time.Second
+
+type cachedSet struct {
+	hidden  map[uuid.UUID]bool
+	expires time.Time
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = map[uuid.UUID]cachedSet{}
+)
+
+// CachedHidden is Hidden behind a short in-process cache. Every read path
+// uses it, from feeds and comment lists to websocket fan-out, so they all
+// agree on how stale a block or mute may be.
+func CachedHidden(ctx context.Context, db *gorm.DB, viewerID uuid.UUID) (map[uuid.UUID]bool, error) {
+	now := time.Now()
+	cacheMu.Lock()
+	entry, ok := cache[viewerID]
+	cacheMu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.hidden, nil
+	}
+
+	hidden, err := Hidden(ctx, db, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	cacheMu.Lock()
+	cache[viewerID] = cachedSet{hidden: hidden, expires: now.Add(cacheTTL)}
+	for id, entry := range cache {
+		if now.After(entry.expires) {
+			delete(cache, id)
+		}
+	}
+	cacheMu.Unlock()
+	return hidden, nil
+}
+
+// Invalidate drops cached sets after a block, unblock, mute or unmute.
+func Invalidate(userIDs ...uuid.UUID) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	for _, id := range userIDs {
+		delete(cache, id)
+	}
+}
@@ -0,0 +1,56 @@
+package reputation
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// tierTTL bounds how long a tier change takes to affect rate limits and
+// challenges.
+const tierTTL = time.Minute
+
+type cachedTier struct {
+	tier    string
+	expires time.Time
+}
+
+var (
+	tiersMu sync.Mutex
+	tiers   = map[uuid.UUID]cachedTier{}
+)
+
+// CachedTier returns userID's tier, cached in process for a minute, for
+// checks that run on every request. Without a database, or when the lookup
+// fails, it returns TierNew.
+func CachedTier(ctx context.Context, db *gorm.DB, userID uuid.UUID) string {
+	now := time.Now()
+	tiersMu.Lock()
+	entry, ok := tiers[userID]
+	tiersMu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.tier
+	}
+	if db == nil {
+		return TierNew
+	}
+
+	user, err := Get(ctx, db, userID)
+	if err != nil {
+		log.Printf("reputation: failed to load the tier of %s: %v", userID, err)
+		return TierNew
+	}
+	tiersMu.Lock()
+	tiers[userID] = cachedTier{tier: user.Tier, expires: now.Add(tierTTL)}
+	for id, entry := range tiers {
+		if now.After(entry.expires) {
+			delete(tiers, id)
+		}
+	}
+	tiersMu.Unlock()
+	return user.Tier
+}
@@ -0,0 +1,215 @@
+package reputation
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Semkufu95/confessions/Backend/events"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Channels are the events that can change someone's signals. Admin
+// rejections publish nothing, so the moderation handlers refresh the author
+// directly.
+var Channels = []string{
+	events.ChannelReactionUpdated,
+	events.ChannelReactionRemoved,
+	events.ChannelConfessionDeleted,
+	events.ChannelCommentDeleted,
+	events.ChannelFriendAdded,
+	events.ChannelFriendRequestUpdated,
+}
+
+// appliedRetention is how long applied event ids are remembered. Copies of
+// an event reach the replicas within seconds, and the outbox only redelivers
+// events from the last day.
+const appliedRetention = 7 * 24 * time.Hour
+
+var (
+	pruneMu   sync.Mutex
+	lastPrune time.Time
+)
+
+// delta is how one event moves a user's signals. A deleted post takes its
+// reactions and rejection with it without saying how many, so its owner is
+// recounted instead.
+type delta struct {
+	UserID            uuid.UUID
+	ReactionsReceived int
+	RequestsAnswered  int
+	RequestsAccepted  int
+	Recount           bool
+}
+
+// Apply moves the signals envelope changed. Every replica receives every
+// event; the first to record the envelope id in reputation_events applies
+// it and the others skip it, so each event counts once.
+func Apply(ctx context.Context, db *gorm.DB, envelope events.Envelope) error {
+	change, err := eventDelta(ctx, db, envelope)
+	if err != nil || change == nil {
+		return err
+	}
+	eventID, err := uuid.Parse(envelope.ID)
+	if err != nil {
+		return events.ErrInvalidEnvelope
+	}
+
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		claimed := tx.Exec("INSERT INTO reputation_events (event_id) VALUES (?) ON CONFLICT DO NOTHING", eventID)
+		if claimed.Error != nil || claimed.RowsAffected == 0 {
+			return claimed.Error
+		}
+		if change.Recount {
+			return Refresh(ctx, tx, change.UserID)
+		}
+		return applyDelta(ctx, tx, *change)
+	})
+	if err != nil {
+		return err
+	}
+	pruneApplied(ctx, db)
+	return nil
+}
+
+// applyDelta adds change to the stored signals. Users without a row were
+// never counted, so they are recounted, which includes this event.
+func applyDelta(ctx context.Context, tx *gorm.DB, change delta) error {
+	result := tx.Exec(`
+		UPDATE user_reputations SET
+			reactions_received = GREATEST(reactions_received + ?, 0),
+			requests_answered = GREATEST(requests_answered + ?, 0),
+			requests_accepted = GREATEST(requests_accepted + ?, 0),
+			updated_at = now()
+		WHERE user_id = ?`,
+		change.ReactionsReceived, change.RequestsAnswered, change.RequestsAccepted, change.UserID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return Refresh(ctx, tx, change.UserID)
+	}
+	return nil
+}
+
+// pruneApplied forgets applied event ids past appliedRetention, at most
+// once an hour per replica.
+func pruneApplied(ctx context.Context, db *gorm.DB) {
+	pruneMu.Lock()
+	due := time.Since(lastPrune) >= time.Hour
+	if due {
+		lastPrune = time.Now()
+	}
+	pruneMu.Unlock()
+	if !due {
+		return
+	}
+	cutoff := time.Now().Add(-appliedRetention)
+	if err := db.WithContext(ctx).Exec("DELETE FROM reputation_events WHERE applied_at < ?", cutoff).Error; err != nil {
+		log.Printf("reputation: failed to prune applied events: %v", err)
+	}
+}
+
+// eventDelta maps an event to the change it makes: reactions move the
+// author of the reacted-to post, answers to connection requests move the
+// sender, and deleted posts have their owner recounted. It returns nil when
+// the event changes nothing.
+func eventDelta(ctx context.Context, db *gorm.DB, envelope events.Envelope) (*delta, error) {
+	switch envelope.Type {
+	case events.ChannelReactionUpdated, events.ChannelReactionRemoved:
+		var payload struct {
+			ConfessionID *uuid.UUID `json:"confession_id"`
+			CommentID    *uuid.UUID `json:"comment_id"`
+			Type         string     `json:"type"`
+			Previous     string     `json:"previous"`
+		}
+		if err := envelope.Unmarshal(&payload); err != nil {
+			return nil, err
+		}
+		table, id := "confessions", payload.ConfessionID
+		if payload.CommentID != nil {
+			table, id = "comments", payload.CommentID
+		}
+		if id == nil {
+			return nil, nil
+		}
+		var authors []uuid.UUID
+		if err := db.WithContext(ctx).Table(table).Where("id = ?", *id).Pluck("user_id", &authors).Error; err != nil {
+			return nil, err
+		}
+		// A reaction on a deleted post no longer counts either way.
+		if len(authors) == 0 {
+			return nil, nil
+		}
+		if reactor, err := uuid.Parse(envelope.ActorID); err == nil && reactor == authors[0] {
+			return nil, nil
+		}
+
+		change := delta{UserID: authors[0]}
+		if envelope.Type == events.ChannelReactionUpdated {
+			change.ReactionsReceived = reactionDelta(payload.Previous, payload.Type)
+		} else if payload.Type != "" {
+			change.ReactionsReceived = reactionDelta(payload.Type, "")
+		} else {
+			// Removals published before the type was sent.
+			change.Recount = true
+		}
+		if change.ReactionsReceived == 0 && !change.Recount {
+			return nil, nil
+		}
+		return &change, nil
+	case events.ChannelConfessionDeleted, events.ChannelCommentDeleted:
+		actor, err := uuid.Parse(envelope.ActorID)
+		if err != nil {
+			return nil, nil
+		}
+		return &delta{UserID: actor, Recount: true}, nil
+	case events.ChannelFriendAdded, events.ChannelFriendRequestUpdated:
+		var payload events.FriendRequestPayload
+		if err := envelope.Unmarshal(&payload); err != nil {
+			return nil, err
+		}
+		previous := payload.PreviousStatus
+		if previous == "" && envelope.Type == events.ChannelFriendRequestUpdated {
+			previous = "pending"
+		}
+		answered, accepted := requestDelta(previous, payload.Status)
+		if answered == 0 && accepted == 0 {
+			return nil, nil
+		}
+		return &delta{UserID: payload.SenderID, RequestsAnswered: answered, RequestsAccepted: accepted}, nil
+	}
+	return nil, nil
+}
+
+// reactionDelta is how a reaction changing type from previous to next moves
+// the author's received reactions. Empty types mean no reaction; boos do
+// not count.
+func reactionDelta(previous, next string) int {
+	counts := func(reactionType string) int {
+		if reactionType == "" || reactionType == "boo" {
+			return 0
+		}
+		return 1
+	}
+	return counts(next) - counts(previous)
+}
+
+// requestDelta is how a connection request moving from status previous to
+// next changes its sender's answered and accepted requests.
+func requestDelta(previous, next string) (answered, accepted int) {
+	signals := func(status string) (int, int) {
+		switch status {
+		case "accepted":
+			return 1, 1
+		case "declined":
+			return 1, 0
+		}
+		return 0, 0
+	}
+	previousAnswered, previousAccepted := signals(previous)
+	nextAnswered, nextAccepted := signals(next)
+	return nextAnswered - previousAnswered, nextAccepted - previousAccepted
+}
@@ -0,0 +1,42 @@
+package reputation
+
+import "testing"
+
+func TestReactionDelta(t *testing.T) {
+	cases := []struct {
+		previous, next string
+		want           int
+	}{
+		{"", "heart", 1},
+		{"", "boo", 0},
+		{"heart", "like", 0},
+		{"like", "boo", -1},
+		{"boo", "wow", 1},
+		{"heart", "", -1},
+		{"boo", "", 0},
+	}
+	for _, tc := range cases {
+		if got := reactionDelta(tc.previous, tc.next); got != tc.want {
+			t.Errorf("%q -> %q: expected %d, got %d", tc.previous, tc.next, tc.want, got)
+		}
+	}
+}
+
+func TestRequestDelta(t *testing.T) {
+	cases := []struct {
+		previous, next     string
+		answered, accepted int
+	}{
+		{"", "pending", 0, 0},
+		{"pending", "accepted", 1, 1},
+		{"pending", "declined", 1, 0},
+		// A declined request that is sent again is unanswered once more.
+		{"declined", "pending", -1, 0},
+	}
+	for _, tc := range cases {
+		answered, accepted := requestDelta(tc.previous, tc.next)
+		if answered != tc.answered || accepted != tc.accepted {
+			t.Errorf("%q -> %q: expected %d/%d, got %d/%d", tc.previous, tc.next, tc.answered, tc.accepted, answered, accepted)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Package reputation scores how much a user can be trusted, from 0 to 100.
+// The score is derived from signals counted in user_reputations plus the
+// account's age and verified email, so it is computed on read and never goes
+// stale as the account ages.
+package reputation
+
+import (
+	"math"
+	"time"
+)
+
+// Signals are the inputs of the score.
+type Signals struct {
+	AccountAge    time.Duration
+	EmailVerified bool
+	// ReactionsReceived counts reactions other users left on the user's
+	// confessions and comments, boos excluded.
+	ReactionsReceived int
+	// UpheldReports counts the user's posts that admins rejected.
+	UpheldReports int
+	// RequestsAnswered and RequestsAccepted cover connection requests the
+	// user sent that were accepted or declined.
+	RequestsAnswered int
+	RequestsAccepted int
+}
+
+// Score weights. A new, unverified account scores about 12; a verified
+// three-month-old account with some reactions and accepted requests reaches
+// the trusted tier.
+const (
+	verifiedPoints   = 20
+	agePoints        = 25
+	reactionPoints   = 30
+	acceptancePoints = 25
+	upheldPenalty    = 20
+
+	// fullAge earns all age points.
+	fullAge = 90 * 24 * time.Hour
+	// reactionScale is the number of reactions that earns ~63% of the
+	// reaction points; the curve flattens after that so popularity cannot
+	// outweigh everything else.
+	reactionScale = 50.0
+)
+
+// Tiers group scores for callers that only need a coarse level.
+const (
+	TierNew     = "new"
+	TierMember  = "member"
+	TierTrusted = "trusted"
+)
+
+// Score combines the signals into 0-100.
+func Score(s Signals) int {
+	points := 0.0
+	if s.EmailVerified {
+		points += verifiedPoints
+	}
+	points += agePoints * math.Min(math.Max(s.AccountAge.Hours(), 0)/fullAge.Hours(), 1)
+	points += reactionPoints * (1 - math.Exp(-float64(s.ReactionsReceived)/reactionScale))
+	points += acceptancePoints * AcceptanceRate(s)
+	points -= upheldPenalty * float64(s.UpheldReports)
+	return int(math.Round(math.Min(math.Max(points, 0), 100)))
+}
+
+// AcceptanceRate is the share of answered connection requests that were
+// accepted, smoothed towards one half so a single answer does not swing it.
+func AcceptanceRate(s Signals) float64 {
+	return (float64(s.RequestsAccepted) + 1) / (float64(s.RequestsAnswered) + 2)
+}
+
+// TierOf maps a score to its tier.
+func TierOf(score int) string {
+	switch {
+	case score >= 60:
+		return TierTrusted
+	case score >= 30:
+		return TierMember
+	}
+	return TierNew
+}
+
+// Reputation is a user's score with the signals behind it.
+type Reputation struct {
+	Score   int
+	Tier    string
+	Signals Signals
+}
+
+// New scores signals.
+func New(s Signals) Reputation {
+	score := Score(s)
+	return Reputation{Score: score, Tier: TierOf(score), Signals: s}
+}
+
+// Trust maps the score to -1..1, with 0 for an average user, for callers
+// that scale something up or down by reputation.
+func (r Reputation) Trust() float64 {
+	return (float64(r.Score) - 50) / 50
+}
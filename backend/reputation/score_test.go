@@ -0,0 +1,48 @@
+package reputation
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScore(t *testing.T) {
+	day := 24 * time.Hour
+	cases := []struct {
+		name    string
+		signals Signals
+		want    int
+		tier    string
+	}{
+		{"throwaway", Signals{}, 13, TierNew},
+		{"verified newcomer", Signals{EmailVerified: true, AccountAge: day}, 33, TierMember},
+		{"established", Signals{EmailVerified: true, AccountAge: 120 * day, ReactionsReceived: 50, RequestsAnswered: 8, RequestsAccepted: 8}, 86, TierTrusted},
+		{"popular but reported", Signals{EmailVerified: true, AccountAge: 120 * day, ReactionsReceived: 500, UpheldReports: 2}, 47, TierMember},
+		{"repeat offender", Signals{EmailVerified: true, UpheldReports: 5}, 0, TierNew},
+	}
+	for _, tc := range cases {
+		got := New(tc.signals)
+		if got.Score != tc.want || got.Tier != tc.tier {
+			t.Errorf("%s: expected %d (%s), got %d (%s)", tc.name, tc.want, tc.tier, got.Score, got.Tier)
+		}
+	}
+}
+
+func TestAcceptanceRate_IsSmoothed(t *testing.T) {
+	if rate := AcceptanceRate(Signals{}); rate != 0.5 {
+		t.Fatalf("expected no history to rate 0.5, got %v", rate)
+	}
+	one := AcceptanceRate(Signals{RequestsAnswered: 1})
+	many := AcceptanceRate(Signals{RequestsAnswered: 20})
+	if !(many < one && one < 0.5) {
+		t.Fatalf("expected declines to lower the rate gradually, got %v then %v", one, many)
+	}
+}
+
+func TestTrust(t *testing.T) {
+	if trust := (Reputation{Score: 50}).Trust(); trust != 0 {
+		t.Fatalf("expected 50 to be neutral, got %v", trust)
+	}
+	if trust := (Reputation{Score: 100}).Trust(); trust != 1 {
+		t.Fatalf("expected 100 to be full trust, got %v", trust)
+	}
+}
@@ -0,0 +1,105 @@
+package reputation
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// refreshSQL recounts the stored signals of the users matched by the WHERE
+// clause appended to it. Events move the signals by deltas (see Apply);
+// recounts back reindex-reputation, admin rejections and deleted posts.
+const refreshSQL = `
+INSERT INTO user_reputations (user_id, reactions_received, upheld_reports, requests_answered, requests_accepted, updated_at)
+SELECT u.id,
+	(SELECT count(*) FROM reactions r JOIN confessions c ON c.id = r.confession_id
+		WHERE r.comment_id IS NULL AND c.user_id = u.id AND r.user_id <> u.id AND r.type <> 'boo')
+	+ (SELECT count(*) FROM reactions r JOIN comments m ON m.id = r.comment_id
+		WHERE m.user_id = u.id AND r.user_id <> u.id AND r.type <> 'boo'),
+	(SELECT count(*) FROM confessions WHERE user_id = u.id AND status = 'rejected')
+	+ (SELECT count(*) FROM comments WHERE user_id = u.id AND status = 'rejected'),
+	(SELECT count(*) FROM connection_requests WHERE sender_id = u.id AND status IN ('accepted', 'declined')),
+	(SELECT count(*) FROM connection_requests WHERE sender_id = u.id AND status = 'accepted'),
+	now()
+FROM users u
+`
+
+const refreshConflictSQL = `
+ON CONFLICT (user_id) DO UPDATE SET
+	reactions_received = EXCLUDED.reactions_received,
+	upheld_reports = EXCLUDED.upheld_reports,
+	requests_answered = EXCLUDED.requests_answered,
+	requests_accepted = EXCLUDED.requests_accepted,
+	updated_at = EXCLUDED.updated_at`
+
+// Refresh recounts the signals of userIDs.
+func Refresh(ctx context.Context, db *gorm.DB, userIDs ...uuid.UUID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return db.WithContext(ctx).Exec(refreshSQL+"WHERE u.id IN ?"+refreshConflictSQL, userIDs).Error
+}
+
+// RefreshAll recounts every user's signals and returns how many were written.
+func RefreshAll(ctx context.Context, db *gorm.DB) (int64, error) {
+	result := db.WithContext(ctx).Exec(refreshSQL + refreshConflictSQL)
+	return result.RowsAffected, result.Error
+}
+
+// Lookup returns the reputation of every existing user in userIDs. Users
+// whose signals were never counted score on age and email alone.
+func Lookup(ctx context.Context, db *gorm.DB, userIDs []uuid.UUID) (map[uuid.UUID]Reputation, error) {
+	reputations := make(map[uuid.UUID]Reputation, len(userIDs))
+	if len(userIDs) == 0 {
+		return reputations, nil
+	}
+
+	var rows []struct {
+		ID                uuid.UUID
+		EmailVerified     bool
+		CreatedAt         time.Time
+		ReactionsReceived int
+		UpheldReports     int
+		RequestsAnswered  int
+		RequestsAccepted  int
+	}
+	if err := db.WithContext(ctx).Table("users AS u").
+		Select(`u.id, u.email_verified, u.created_at,
+			COALESCE(r.reactions_received, 0) AS reactions_received,
+			COALESCE(r.upheld_reports, 0) AS upheld_reports,
+			COALESCE(r.requests_answered, 0) AS requests_answered,
+			COALESCE(r.requests_accepted, 0) AS requests_accepted`).
+		Joins("LEFT JOIN user_reputations r ON r.user_id = u.id").
+		Where("u.id IN ?", userIDs).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, row := range rows {
+		reputations[row.ID] = New(Signals{
+			AccountAge:        now.Sub(row.CreatedAt),
+			EmailVerified:     row.EmailVerified,
+			ReactionsReceived: row.ReactionsReceived,
+			UpheldReports:     row.UpheldReports,
+			RequestsAnswered:  row.RequestsAnswered,
+			RequestsAccepted:  row.RequestsAccepted,
+		})
+	}
+	return reputations, nil
+}
+
+// Get returns one user's reputation, or gorm.ErrRecordNotFound.
+func Get(ctx context.Context, db *gorm.DB, userID uuid.UUID) (Reputation, error) {
+	reputations, err := Lookup(ctx, db, []uuid.UUID{userID})
+	if err != nil {
+		return Reputation{}, err
+	}
+	reputation, ok := reputations[userID]
+	if !ok {
+		return Reputation{}, gorm.ErrRecordNotFound
+	}
+	return reputation, nil
+}
@@ -1,12 +1,28 @@
 package routes
 
 import (
+	"expvar"
+
+	"github.com/Semkufu95/confessions/Backend/challenge"
 	"github.com/Semkufu95/confessions/Backend/controllers"
 	"github.com/Semkufu95/confessions/Backend/middleware"
+	"github.com/Semkufu95/confessions/Backend/ratelimit"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 )
 
 func SetupRoutes(app *fiber.App) {
+	// ===== PROBES (Public, root only) =====
+	app.Get("/healthz", controllers.Healthz)
+	app.Get("/readyz", controllers.Readyz)
+
+	// ===== METRICS (Admins, root only) =====
+	app.Get("/debug/vars", middleware.RequireAuth, middleware.RequireAdmin, adaptor.HTTPHandler(expvar.Handler()))
+
+	// ===== SHORT LINKS (Public, root only) =====
+	app.Get("/s/:code", middleware.OptionalAuth, controllers.FollowShortLink)
+	app.Get("/s/:code/image.png", controllers.GetShortLinkImage)
+
 	registerRoutes(app.Group("/"))
 	registerRoutes(app.Group("/api"))
 }
@@ -14,47 +30,83 @@ func SetupRoutes(app *fiber.App) {
 func registerRoutes(api fiber.Router) {
 	// ===== AUTH (Public) =====
 	api.Post("/login", controllers.Login)
-	api.Post("/register", controllers.Register)
+	api.Post("/register", middleware.RequireChallenge(challenge.ScopeRegister), controllers.Register)
 	api.Get("/verify-email", controllers.VerifyEmail)
 	api.Post("/verify-email/resend", controllers.ResendVerificationEmail)
 	api.Get("/stats", controllers.GetRealtimeStats)
-	api.Post("/contact", controllers.SendContactMessage)
-	api.Get("/confessions", controllers.GetAllConfessions)
-	api.Post("/confessions/:id/share", controllers.ShareConfession)
-	api.Get("/connections", controllers.GetAllConnections)
-	api.Get("/connections/:id/profile", controllers.GetConnectionProfile)
-	api.Get("/confessions/:id/comments", controllers.GetConfessionWithComments)
-	api.Get("/comments/:id", controllers.GetCommentsByConfession)
+	api.Get("/challenge", controllers.GetChallenge)
+	api.Post("/contact", middleware.OptionalAuth, middleware.RequireChallenge(challenge.ScopeContact), middleware.RateLimit(ratelimit.ContactMessages), controllers.SendContactMessage)
+	api.Get("/reactions/types", controllers.GetReactionTypes)
+	api.Get("/confessions", middleware.OptionalAuth, controllers.GetAllConfessions)
+	api.Get("/feed/for-you", middleware.OptionalAuth, controllers.GetForYouFeed)
+	api.Post("/confessions/:id/share", middleware.OptionalAuth, middleware.RequireChallenge(challenge.ScopeShare), controllers.ShareConfession)
+	api.Get("/shares/:code", middleware.OptionalAuth, middleware.RateLimit(ratelimit.ShareClicks), controllers.ResolveShare)
+	api.Get("/confessions/:id/card.png", controllers.GetConfessionCard)
+	api.Get("/feeds/confessions.atom", controllers.GetAtomFeed)
+	api.Get("/feeds/confessions.rss", controllers.GetRSSFeed)
+	api.Get("/feeds/confessions.json", controllers.GetJSONFeed)
+	api.Get("/connections", middleware.OptionalAuth, controllers.GetAllConnections)
+	api.Get("/connections/:id/profile", middleware.OptionalAuth, controllers.GetConnectionProfile)
+	api.Get("/confessions/:id/comments", middleware.OptionalAuth, controllers.GetConfessionWithComments)
+	api.Get("/comments/:id", middleware.OptionalAuth, controllers.GetCommentsByConfession)
+	api.Get("/confessions/:id/reactions", middleware.OptionalAuth, controllers.GetConfessionReactions)
+	api.Get("/comments/:id/reactions", middleware.OptionalAuth, controllers.GetCommentReactions)
 
 	// ===== PROTECTED ROUTES =====
 	protected := api.Group("/", middleware.RequireAuth)
 	protected.Post("/logout", controllers.Logout)
+	protected.Post("/ws-ticket", controllers.CreateWebsocketTicket)
 	protected.Get("/me/settings", controllers.GetMySettings)
 	protected.Put("/me/settings", controllers.UpdateMySettings)
 	protected.Get("/me/friends", controllers.GetMyFriends)
+	protected.Get("/me/reactions", controllers.GetMyReactions)
+	protected.Get("/me/blocks", controllers.GetMyBlocks)
+	protected.Post("/me/blocks", controllers.BlockUser)
+	protected.Delete("/me/blocks/:userId", controllers.UnblockUser)
+	protected.Get("/me/mutes", controllers.GetMyMutes)
+	protected.Post("/me/mutes", controllers.MuteUser)
+	protected.Delete("/me/mutes/:userId", controllers.UnmuteUser)
+	protected.Get("/me/moderation", controllers.GetMyModeration)
+	protected.Get("/me/reputation", controllers.GetMyReputation)
+	protected.Get("/me/notifications", controllers.GetMyNotifications)
+	protected.Post("/me/notifications/read-all", controllers.MarkAllNotificationsRead)
+	protected.Post("/me/notifications/:id/read", controllers.MarkNotificationRead)
 
 	// ===== CONFESSIONS =====
 	confessions := protected.Group("/confessions")
-	confessions.Post("/", controllers.CreateConfession)           // Create a confession
-	confessions.Put("/:id", controllers.UpdateConfession)         // Update a confession
-	confessions.Delete("/:id", controllers.DeleteConfession)      // Delete a confession
-	confessions.Post("/:id/star", controllers.StarConfession)     // Star a confession
-	confessions.Post("/:id/react", controllers.ReactToConfession) // Like/Boo a confession
+	confessions.Post("/", middleware.RateLimit(ratelimit.Confessions), controllers.CreateConfession) // Create a confession
+	confessions.Put("/:id", controllers.UpdateConfession)                                            // Update a confession
+	confessions.Delete("/:id", controllers.DeleteConfession)                                         // Delete a confession
+	confessions.Post("/:id/star", controllers.StarConfession)                                        // Star a confession
+	confessions.Post("/:id/react", controllers.ReactToConfession)                                    // React to a confession
+	confessions.Delete("/:id/react", controllers.RemoveConfessionReaction)                           // Remove my reaction
+	confessions.Post("/:id/report", controllers.ReportConfession)                                    // Report to moderators
+	confessions.Get("/:id/shares", controllers.GetConfessionShares)                                  // Share breakdown for the author
 
 	// ===== COMMENTS =====
 	comments := protected.Group("/comments")
-	comments.Post("/:id", controllers.PostComment)
+	comments.Post("/:id", middleware.RateLimit(ratelimit.Comments), controllers.PostComment)
 	comments.Put("/:id", controllers.UpdateComment)
 	comments.Delete("/:id", controllers.DeleteComment)
 	comments.Post("/:id/react", controllers.ReactToComment)
+	comments.Delete("/:id/react", controllers.RemoveCommentReaction)
+	comments.Post("/:id/report", controllers.ReportComment)
 
 	// ===== CONNECTIONS =====
 	connections := protected.Group("/connections")
 	connections.Post("/", controllers.CreateConnection)
-	connections.Post("/:id/connect", controllers.ConnectToConnection)
+	connections.Post("/:id/connect", middleware.RateLimit(ratelimit.ConnectionRequests), controllers.ConnectToConnection)
 	protected.Post("/me/friends/requests/:id/respond", controllers.RespondToFriendRequest)
 
 	// ===== REACTIONS =====
 	reactions := protected.Group("/reactions")
 	reactions.Delete("/:id/remove", controllers.RemoveReaction)
+
+	// ===== MODERATION (Admins) =====
+	moderation := protected.Group("/admin/moderation", middleware.RequireAdmin)
+	moderation.Get("/", controllers.GetModerationQueue)
+	moderation.Post("/confessions/:id/approve", controllers.ApproveConfession)
+	moderation.Post("/confessions/:id/reject", controllers.RejectConfession)
+	moderation.Post("/comments/:id/approve", controllers.ApproveComment)
+	moderation.Post("/comments/:id/reject", controllers.RejectComment)
 }
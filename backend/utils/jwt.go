@@ -2,14 +2,14 @@ package utils
 
 import (
 	"errors"
-	"os"
 	"time"
 
+	"github.com/Semkufu95/confessions/Backend/config"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 func GenerateJWT(userID string, sessionID string) (string, error) {
-	secret := os.Getenv("JWT_SECRET")
+	secret := config.App.JWTSecret
 	if secret == "" {
 		return "", errors.New("JWT_SECRET is not set")
 	}
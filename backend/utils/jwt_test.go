@@ -1,15 +1,15 @@
 package utils
 
 import (
-	"os"
 	"testing"
 
+	"github.com/Semkufu95/confessions/Backend/config"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 func TestGenerateJWT(t *testing.T) {
 	secret := "unit-test-secret"
-	os.Setenv("JWT_SECRET", secret)
+	config.App.JWTSecret = secret
 
 	userID := "user-456"
 	sessionID := "session-123"
@@ -5,8 +5,9 @@ import (
 	"fmt"
 	"net"
 	"net/smtp"
-	"os"
 	"strings"
+
+	"github.com/Semkufu95/confessions/Backend/config"
 )
 
 var ErrEmailDeliveryNotConfigured = errors.New("email delivery is not configured")
@@ -37,22 +38,28 @@ func SendContactMessage(toEmail, senderName, senderEmail, subject, message strin
 	return sendSMTPMail(toEmail, "[Confessions] "+cleanSubject, body)
 }
 
-func sendSMTPMail(toEmail, subject, body string) error {
-	host := os.Getenv("SMTP_HOST")
-	port := os.Getenv("SMTP_PORT")
-	username := os.Getenv("SMTP_USERNAME")
-	password := os.Getenv("SMTP_PASSWORD")
-	from := os.Getenv("SMTP_FROM")
+// SendModerationDecision tells an author whether their held post was
+// published or rejected.
+func SendModerationDecision(toEmail, kind string, approved bool, reason string) error {
+	if approved {
+		body := fmt.Sprintf("Hi,\r\n\r\nYour %s has been reviewed and is now published.\r\n", kind)
+		return sendSMTPMail(toEmail, "Your "+kind+" is published", body)
+	}
+	body := fmt.Sprintf("Hi,\r\n\r\nYour %s has been reviewed and was not published.\r\n\r\nReason: %s\r\n", kind, reason)
+	return sendSMTPMail(toEmail, "Your "+kind+" was not published", body)
+}
 
-	if host == "" || port == "" || username == "" || password == "" || from == "" {
+func sendSMTPMail(toEmail, subject, body string) error {
+	settings := config.App.SMTP
+	if !settings.Configured() {
 		return ErrEmailDeliveryNotConfigured
 	}
 
-	auth := smtp.PlainAuth("", username, password, host)
-	address := net.JoinHostPort(host, port)
+	auth := smtp.PlainAuth("", settings.Username, settings.Password, settings.Host)
+	address := net.JoinHostPort(settings.Host, settings.Port)
 
 	subjectLine := "Subject: " + subject + "\r\n"
 	headers := "MIME-Version: 1.0\r\nContent-Type: text/plain; charset=\"UTF-8\"\r\n"
 	message := []byte(subjectLine + headers + "\r\n" + body)
-	return smtp.SendMail(address, auth, from, []string{toEmail}, message)
+	return smtp.SendMail(address, auth, settings.From, []string{toEmail}, message)
 }
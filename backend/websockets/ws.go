@@ -6,19 +6,44 @@ import (
 	"github.com/gofiber/websocket/v2"
 )
 
-var Clients = make(map[*websocket.Conn]bool)
+// Clients maps each connection to its user id, or "" for anonymous sockets.
+var Clients = make(map[*websocket.Conn]string)
 var Mu sync.Mutex
 
+// HideFrom reports whether events caused by actorID must not reach
+// recipientID. main wires it to the block and mute lists; when nil every
+// client receives every event.
+var HideFrom func(recipientID, actorID string) bool
+
+func Register(c *websocket.Conn, userID string) {
+	Mu.Lock()
+	defer Mu.Unlock()
+	Clients[c] = userID
+}
+
+func Unregister(c *websocket.Conn) {
+	Mu.Lock()
+	defer Mu.Unlock()
+	delete(Clients, c)
+}
+
 func ClientCount() int {
 	Mu.Lock()
 	defer Mu.Unlock()
 	return len(Clients)
 }
 
-func Broadcast(message string) {
+// Broadcast sends message to every client that is allowed to see events from
+// actorID.
+func Broadcast(message string, actorID string) {
+	skip := hiddenRecipients(actorID)
+
 	Mu.Lock()
 	defer Mu.Unlock()
-	for c := range Clients {
+	for c, userID := range Clients {
+		if userID != "" && skip[userID] {
+			continue
+		}
 		if err := c.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
 			err := c.Close()
 			if err != nil {
@@ -30,6 +55,49 @@ func Broadcast(message string) {
 	}
 }
 
+// SendTo sends message to every connection of userID.
+func SendTo(userID string, message string) {
+	if userID == "" {
+		return
+	}
+	Mu.Lock()
+	defer Mu.Unlock()
+	for c, owner := range Clients {
+		if owner != userID {
+			continue
+		}
+		if err := c.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+			_ = c.Close()
+			delete(Clients, c)
+		}
+	}
+}
+
+// hiddenRecipients evaluates HideFrom once per signed-in user, outside the
+// lock because it may query the database.
+func hiddenRecipients(actorID string) map[string]bool {
+	skip := map[string]bool{}
+	if HideFrom == nil || actorID == "" {
+		return skip
+	}
+
+	Mu.Lock()
+	users := make(map[string]bool)
+	for _, userID := range Clients {
+		if userID != "" && userID != actorID {
+			users[userID] = true
+		}
+	}
+	Mu.Unlock()
+
+	for userID := range users {
+		if HideFrom(userID, actorID) {
+			skip[userID] = true
+		}
+	}
+	return skip
+}
+
 func Shutdown() {
 	Mu.Lock()
 	defer Mu.Unlock()
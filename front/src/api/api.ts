@@ -1,4 +1,5 @@
 import axios from "axios";
+import { isSolvableChallenge, solveChallenge } from "./challenge";
 
 const apiBaseURL = import.meta.env.VITE_API_URL || "http://localhost:5000/api";
 
@@ -20,7 +21,7 @@ api.interceptors.request.use((request) => {
 
 api.interceptors.response.use(
     (response) => response,
-    (error) => {
+    async (error) => {
         if (error?.response?.status === 401) {
             localStorage.removeItem("token");
             localStorage.removeItem("user");
@@ -28,6 +29,22 @@ api.interceptors.response.use(
                 window.dispatchEvent(new Event("auth:logout"));
             }
         }
+
+        // Solve the challenge and repeat the request once; a second 428
+        // goes back to the caller.
+        const challenge = error?.response?.data?.challenge;
+        const request = error?.config;
+        if (
+            error?.response?.status === 428 &&
+            isSolvableChallenge(challenge) &&
+            request &&
+            !request.headers?.has("X-Challenge-Solution")
+        ) {
+            const solution = await solveChallenge(challenge);
+            request.headers.set("X-Challenge-Token", challenge.token);
+            request.headers.set("X-Challenge-Solution", solution);
+            return api.request(request);
+        }
         return Promise.reject(error);
     }
 );
@@ -0,0 +1,48 @@
+// Proof-of-work challenges the backend asks for (428 Precondition Required)
+// before public endpoints like register, contact and share once a network
+// made too many calls. See "Challenges" in backend/README.md.
+
+export type Challenge = {
+    type: string;
+    scope: string;
+    token: string;
+    difficulty: number;
+    expires_at: string;
+};
+
+// Nonces hashed per batch. Each batch yields to the event loop, so the page
+// stays responsive while a challenge is solved.
+const BATCH_SIZE = 512;
+
+const encoder = new TextEncoder();
+
+function leadingZeroBits(digest: ArrayBuffer): number {
+    let zeros = 0;
+    for (const byte of new Uint8Array(digest)) {
+        if (byte !== 0) {
+            return zeros + Math.clz32(byte) - 24;
+        }
+        zeros += 8;
+    }
+    return zeros;
+}
+
+export function isSolvableChallenge(value: unknown): value is Challenge {
+    const challenge = value as Challenge | undefined;
+    return challenge?.type === "pow" && typeof challenge.token === "string" && typeof challenge.difficulty === "number";
+}
+
+// solveChallenge finds a decimal nonce such that
+// SHA-256(token + ":" + nonce) starts with `difficulty` zero bits.
+export async function solveChallenge(challenge: Challenge): Promise<string> {
+    for (let start = 0; ; start += BATCH_SIZE) {
+        const nonces = Array.from({ length: BATCH_SIZE }, (_, i) => String(start + i));
+        const digests = await Promise.all(
+            nonces.map((nonce) => crypto.subtle.digest("SHA-256", encoder.encode(`${challenge.token}:${nonce}`)))
+        );
+        const found = digests.findIndex((digest) => leadingZeroBits(digest) >= challenge.difficulty);
+        if (found >= 0) {
+            return nonces[found];
+        }
+    }
+}
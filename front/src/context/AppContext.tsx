@@ -7,6 +7,7 @@ import type {
     FriendFollower,
     FriendRequestInboxItem,
 } from "../types";
+import { api } from "../api/api";
 import { ConfessionService } from "../services/ConfessionService";
 import { ConnectionService } from "../services/ConnectionService";
 import { useAuth } from "./AuthContext";
@@ -389,9 +390,29 @@ export function AppProvider({ children }: AppProviderProps) {
         } catch {
             wsURL = "ws://localhost:5000/ws";
         }
+        let socket: WebSocket | null = null;
+        let cancelled = false;
+
+        const connect = async () => {
+            // Signed-in sockets skip events from muted and blocked users. The
+            // token is traded for a single-use ticket so it stays out of URLs.
+            if (localStorage.getItem("token")) {
+                try {
+                    const { data } = await api.post<{ ticket: string }>("/ws-ticket");
+                    wsURL += `?ticket=${encodeURIComponent(data.ticket)}`;
+                } catch {
+                    // Connect anonymously; events are still delivered.
+                }
+            }
+            if (cancelled) return;
+            socket = new WebSocket(wsURL);
+            socket.onmessage = handleMessage;
+            socket.onerror = () => {
+                // Keep UI functional even when websocket is unavailable.
+            };
+        };
 
-        const socket = new WebSocket(wsURL);
-        socket.onmessage = (event) => {
+        const handleMessage = (event: MessageEvent) => {
             const parsed = parseRealtimeEvent(typeof event.data === "string" ? event.data : "");
             if (!parsed) return;
 
@@ -427,12 +448,12 @@ export function AppProvider({ children }: AppProviderProps) {
                 pushNotification(notification);
             }
         };
-        socket.onerror = () => {
-            // Keep UI functional even when websocket is unavailable.
-        };
+
+        void connect();
 
         return () => {
-            socket.close();
+            cancelled = true;
+            socket?.close();
         };
         // eslint-disable-next-line react-hooks/exhaustive-deps
     }, [enabledNotificationChannels, user?.id]);